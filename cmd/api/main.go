@@ -7,6 +7,8 @@ import (
 	"go-rest-chi/internal/config"
 	appdb "go-rest-chi/internal/db"
 	"go-rest-chi/internal/httpserver"
+	"go-rest-chi/internal/probe"
+	"go-rest-chi/internal/realtime"
 	"go-rest-chi/internal/repositories"
 	"go-rest-chi/internal/router"
 	"go-rest-chi/internal/services"
@@ -34,7 +36,22 @@ func openDB(cfg *config.Config) (*appdb.SQL, error) {
 	return sqlc, nil
 }
 
-func initRouter(cfg *config.Config, sqlDB *appdb.SQL) *chi.Mux {
+// storageQuotas converts the configured quotas, in MB, to bytes.
+func storageQuotas(mc config.MediaConfig) services.StorageQuotas {
+	quota := func(mb, files int) services.StorageQuota {
+		return services.StorageQuota{MaxBytes: int64(mb) << 20, MaxFilesPerDay: files}
+	}
+	q := services.StorageQuotas{
+		Default: quota(mc.QuotaMB, mc.QuotaFilesPerDay),
+		Roles:   make(map[string]services.StorageQuota, len(mc.QuotaRoles)),
+	}
+	for role, rq := range mc.QuotaRoles {
+		q.Roles[role] = quota(rq.MB, rq.FilesPerDay)
+	}
+	return q
+}
+
+func initRouter(cfg *config.Config, sqlDB *appdb.SQL, broker realtime.Broker) (*chi.Mux, services.MediaPipeline, services.MediaGC) {
 
 	st, err := storage.NewFromConfig(cfg.Storage)
 	if err != nil {
@@ -44,20 +61,98 @@ func initRouter(cfg *config.Config, sqlDB *appdb.SQL) *chi.Mux {
 	userRepo := repositories.NewUserRepository(sqlDB)
 	postRepo := repositories.NewPostRepository(sqlDB)
 	mediaRepo := repositories.NewMediaRepository(sqlDB)
+	notifRepo := repositories.NewNotificationRepository(sqlDB)
+	convRepo := repositories.NewConversationRepository(sqlDB)
+	blockRepo := repositories.NewBlockRepository(sqlDB)
+	followRepo := repositories.NewFollowRepository(sqlDB)
+	searchRepo := repositories.NewSearchRepository(sqlDB, cfg.Search.Language)
+	if n, err := searchRepo.SyncLanguage(context.Background()); err != nil {
+		panic(fmt.Errorf("search init: %w", err))
+	} else if n > 0 {
+		log.Printf("search: reindexed %d posts in %s", n, cfg.Search.Language)
+	}
+	uploadRepo := repositories.NewResumableUploadRepository(sqlDB)
+	gcRepo := repositories.NewMediaGCRepository(sqlDB)
 
 	jwtSvc := auth.NewService(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL)
 
 	userSvc := services.NewUserService(userRepo)
-	postSvc := services.NewPostService(postRepo, st)
-	mediaSvc := services.NewMediaService(mediaRepo, st, cfg.Storage.PresignTTL)
+	blockSvc := services.NewBlockService(blockRepo, userRepo, followRepo)
+	notifSvc := services.NewNotificationService(notifRepo, blockRepo, broker)
+	followSvc := services.NewFollowService(followRepo, userRepo, blockRepo, notifSvc)
+	postMediaLimits := services.PostMediaLimits{
+		Images: cfg.Media.PostMaxImages,
+		Videos: cfg.Media.PostMaxVideos,
+		Audio:  cfg.Media.PostMaxAudio,
+		Mixed:  cfg.Media.PostMixKinds,
+	}
+	postSvc := services.NewPostService(postRepo, mediaRepo, postMediaLimits, userRepo, followRepo, notifSvc, broker, st)
+	prober := probe.Prober(probe.Container{})
+	if ff, ok := probe.NewFFmpeg(cfg.Media.FFprobePath, cfg.Media.FFmpegPath); ok {
+		prober = ff
+	} else {
+		log.Printf("ffprobe not found, reading MP4/WebM metadata only")
+	}
+
+	// Media processors, run in order on every stored upload.
+	processors := []services.MediaProcessor{
+		services.NewProbeProcessor(prober, st),
+		services.NewVariantProcessor(mediaRepo, st, cfg.Media.ImageVariants),
+		services.NewPlaceholderProcessor(),
+	}
+	pipeline := services.NewMediaPipeline(mediaRepo, st, broker, cfg.Storage.PresignTTL, services.PipelineOptions{
+		Workers:       cfg.Media.Workers,
+		QueueSize:     cfg.Media.QueueSize,
+		Timeout:       cfg.Media.ProcessTimeout,
+		SweepInterval: cfg.Media.SweepInterval,
+	}, processors...)
+	mediaSvc := services.NewMediaService(mediaRepo, st, cfg.Storage.PresignTTL, services.MediaLimits{
+		Image: int64(cfg.Media.MaxImageMB) << 20,
+		Video: int64(cfg.Media.MaxVideoMB) << 20,
+		Audio: int64(cfg.Media.MaxAudioMB) << 20,
+		Types: map[string][]string{
+			"image": cfg.Media.ImageTypes,
+			"video": cfg.Media.VideoTypes,
+			"audio": cfg.Media.AudioTypes,
+		},
+		Post: postMediaLimits,
+		AltText: services.AltTextPolicy{
+			Mode:     cfg.Media.AltTextPolicy,
+			MaxChars: cfg.Media.AltTextMax,
+		},
+		Quota: storageQuotas(cfg.Media),
+	}, pipeline)
+	gc := services.NewMediaGC(gcRepo, st, services.MediaGCOptions{
+		Interval:        cfg.Media.GCInterval,
+		UnattachedAfter: cfg.Media.GCUnattachedAfter,
+		Retention:       cfg.Media.GCRetention,
+		BatchSize:       cfg.Media.GCBatchSize,
+		DryRun:          cfg.Media.GCDryRun,
+	})
+	if cfg.Media.GCDryRun {
+		log.Printf("media gc: dry run, nothing will be removed")
+	}
+	uploadSvc := services.NewResumableUploadService(uploadRepo, mediaSvc, st, int64(cfg.Storage.TusMaxSizeMB)<<20, cfg.Storage.TusExpiry)
+	searchSvc := services.NewSearchService(searchRepo)
+	msgSvc := services.NewMessageService(convRepo, userRepo, blockRepo, followRepo, mediaRepo, broker, st, cfg.Storage.PresignTTL)
+
+	// The local driver has no bucket to PUT into, so the API serves uploads.
+	localFS, _ := st.(*storage.LocalFS)
 
 	r := router.New(router.Deps{
-		DB: sqlDB,
+		DB:       sqlDB,
+		Realtime: broker,
 		Services: router.Services{
-			Users: userSvc,
-			Posts: postSvc,
-			Media: mediaSvc,
-			JWT:   jwtSvc,
+			Users:         userSvc,
+			Posts:         postSvc,
+			Media:         mediaSvc,
+			Uploads:       uploadSvc,
+			Notifications: notifSvc,
+			Messages:      msgSvc,
+			Blocks:        blockSvc,
+			Follows:       followSvc,
+			Search:        searchSvc,
+			JWT:           jwtSvc,
 		},
 	}, router.Options{
 		CORS: router.CORSOpts{
@@ -68,11 +163,11 @@ func initRouter(cfg *config.Config, sqlDB *appdb.SQL) *chi.Mux {
 			AllowCredentials: cfg.CORS.AllowCredentials,
 			MaxAge:           cfg.CORS.MaxAge,
 		},
-		StorageDriver: cfg.Storage.Driver,
-		LocalDir:      cfg.Storage.LocalDir,
+		LocalFS:         localFS,
+		StreamHeartbeat: cfg.Realtime.Heartbeat,
 	})
 
-	return r
+	return r, pipeline, gc
 }
 
 func main() {
@@ -89,7 +184,12 @@ func main() {
 	}
 	defer sqlDB.Close()
 
-	r := initRouter(cfg, sqlDB)
+	broker, err := realtime.NewFromConfig(cfg.Realtime, sqlDB, cfg.DB.DSN)
+	if err != nil {
+		log.Fatalf("realtime init: %v", err)
+	}
+
+	r, pipeline, gc := initRouter(cfg, sqlDB, broker)
 
 	srv, err := httpserver.New(httpserver.Options{
 		Addr:         addr,
@@ -111,12 +211,19 @@ func main() {
 	<-quit
 
 	log.Println("⏳ shutting down...")
+	// Closing the broker ends open event streams so Shutdown does not wait on them.
+	_ = broker.Close()
+
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("shutdown error: %v", err)
 	}
+	// No request can enqueue media any more; unfinished work resumes on the
+	// next start.
+	_ = pipeline.Close()
+	_ = gc.Close()
 	log.Println("✅ bye")
 
 }
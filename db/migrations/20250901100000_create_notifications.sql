@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists notifications (
+    id bigint generated always as identity primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    actor_id bigint not null references users(id) on delete cascade,
+    type varchar(30) not null,
+    entity_id bigint null,
+    group_key text not null,
+    actor_count int not null default 1,
+    read_at timestamptz null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create unique index if not exists ux_notifications_unread_group on notifications(user_id, group_key) where read_at is null;
+create index if not exists idx_notifications_user on notifications(user_id, updated_at desc, id desc);
+
+create trigger trg_notification_update_at
+before update on notifications
+for each row
+execute function set_updated_at();
+
+create table if not exists notification_preferences (
+    user_id bigint not null references users(id) on delete cascade,
+    type varchar(30) not null,
+    enabled boolean not null default true,
+    primary key (user_id, type)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists notification_preferences;
+drop trigger if exists trg_notification_update_at on notifications;
+drop table if exists notifications;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists realtime_events (
+    id bigint generated always as identity primary key,
+    topic text not null,
+    type varchar(50) not null,
+    payload jsonb not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists idx_realtime_events_topic on realtime_events(topic, id);
+create index if not exists idx_realtime_events_created on realtime_events(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists realtime_events;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists conversations (
+    id bigint generated always as identity primary key,
+    kind varchar(10) not null check (kind in ('direct', 'group')),
+    title varchar(255) null,
+    created_by bigint not null references users(id) on delete cascade,
+    last_activity_at timestamptz not null default now(),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create trigger trg_conversation_update_at
+before update on conversations
+for each row
+execute function set_updated_at();
+
+create table if not exists conversation_participants (
+    conversation_id bigint not null references conversations(id) on delete cascade,
+    user_id bigint not null references users(id) on delete cascade,
+    last_read_message_id bigint null,
+    joined_at timestamptz not null default now(),
+    primary key (conversation_id, user_id)
+);
+
+create index if not exists idx_conversation_participants_user on conversation_participants(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists conversation_participants;
+drop trigger if exists trg_conversation_update_at on conversations;
+drop table if exists conversations;
+-- +goose StatementEnd
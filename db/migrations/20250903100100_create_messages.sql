@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists messages (
+    id bigint generated always as identity primary key,
+    conversation_id bigint not null references conversations(id) on delete cascade,
+    sender_id bigint not null references users(id) on delete cascade,
+    body text not null default '',
+    created_at timestamptz not null default now(),
+    deleted_at timestamptz null
+);
+
+create index if not exists idx_messages_conversation on messages(conversation_id, id desc);
+
+create table if not exists message_media (
+    message_id bigint not null references messages(id) on delete cascade,
+    media_id bigint not null references media(id) on delete cascade,
+    position int not null default 0,
+    primary key (message_id, media_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists message_media;
+drop table if exists messages;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists user_blocks (
+    blocker_id bigint not null references users(id) on delete cascade,
+    blocked_id bigint not null references users(id) on delete cascade,
+    created_at timestamptz not null default now(),
+    primary key (blocker_id, blocked_id),
+    check (blocker_id <> blocked_id)
+);
+
+create index if not exists idx_user_blocks_blocked on user_blocks(blocked_id);
+
+create table if not exists user_mutes (
+    muter_id bigint not null references users(id) on delete cascade,
+    muted_id bigint not null references users(id) on delete cascade,
+    created_at timestamptz not null default now(),
+    primary key (muter_id, muted_id),
+    check (muter_id <> muted_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists user_mutes;
+drop table if exists user_blocks;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users add column if not exists is_private boolean not null default false;
+
+create table if not exists follows (
+    follower_id bigint not null references users(id) on delete cascade,
+    followee_id bigint not null references users(id) on delete cascade,
+    status varchar(10) not null default 'accepted' check (status in ('pending', 'accepted')),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    primary key (follower_id, followee_id),
+    check (follower_id <> followee_id)
+);
+
+create index if not exists idx_follows_followee on follows(followee_id, status);
+
+create trigger trg_follow_update_at
+before update on follows
+for each row
+execute function set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger if exists trg_follow_update_at on follows;
+drop table if exists follows;
+alter table users drop column if exists is_private;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table posts add column if not exists visibility varchar(20) not null default 'public'
+    check (visibility in ('public', 'followers', 'mentioned', 'unlisted'));
+
+create table if not exists post_mentions (
+    post_id bigint not null references posts(id) on delete cascade,
+    user_id bigint not null references users(id) on delete cascade,
+    primary key (post_id, user_id)
+);
+
+create index if not exists idx_post_mentions_user on post_mentions(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists post_mentions;
+alter table posts drop column if exists visibility;
+-- +goose StatementEnd
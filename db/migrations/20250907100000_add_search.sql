@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+create extension if not exists pg_trgm;
+
+alter table users add column if not exists display_name varchar(100) null;
+
+create index if not exists idx_posts_search on posts using gin (
+    (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B'))
+);
+
+create index if not exists idx_users_username_trgm on users using gin ((username::text) gin_trgm_ops);
+create index if not exists idx_users_display_name_trgm on users using gin (display_name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_users_display_name_trgm;
+drop index if exists idx_users_username_trgm;
+drop index if exists idx_posts_search;
+alter table users drop column if exists display_name;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists media_uploads (
+    id bigint generated always as identity primary key,
+    owner_id bigint not null references users(id) on delete cascade,
+    kind varchar(20) not null,
+    storage_key text not null,
+    mime_type varchar(100) not null,
+    size_bytes bigint not null,
+    media_id bigint null references media(id) on delete set null,
+    expires_at timestamptz not null,
+    completed_at timestamptz null,
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists ux_media_uploads_storage_key on media_uploads(storage_key);
+create index if not exists idx_media_uploads_pending on media_uploads(expires_at) where completed_at is null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists media_uploads;
+-- +goose StatementEnd
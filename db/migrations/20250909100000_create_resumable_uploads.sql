@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists resumable_uploads (
+    id varchar(26) primary key,
+    owner_id bigint not null references users(id) on delete cascade,
+    filename text not null,
+    mime_type varchar(100) not null,
+    upload_length bigint not null check (upload_length > 0),
+    upload_offset bigint not null default 0,
+    metadata text not null default '',
+    media_id bigint null references media(id) on delete set null,
+    expires_at timestamptz not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    check (upload_offset between 0 and upload_length)
+);
+
+create index if not exists idx_resumable_uploads_pending on resumable_uploads(expires_at) where media_id is null;
+
+-- Each PATCH is stored as its own object; the parts are stitched together
+-- in offset order once the upload is complete.
+create table if not exists resumable_upload_parts (
+    upload_id varchar(26) not null references resumable_uploads(id) on delete cascade,
+    part_offset bigint not null,
+    storage_key text not null,
+    size_bytes bigint not null,
+    created_at timestamptz not null default now(),
+    primary key (upload_id, part_offset)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists resumable_upload_parts;
+drop table if exists resumable_uploads;
+-- +goose StatementEnd
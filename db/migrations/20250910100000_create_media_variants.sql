@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists media_variants (
+    media_id bigint not null references media(id) on delete cascade,
+    width int not null,
+    height int not null,
+    storage_key text not null,
+    mime_type varchar(100) not null,
+    size_bytes bigint not null,
+    created_at timestamptz not null default now(),
+    primary key (media_id, width)
+);
+
+create unique index if not exists ux_media_variants_storage_key on media_variants(storage_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists media_variants;
+-- +goose StatementEnd
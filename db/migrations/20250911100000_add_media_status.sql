@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Existing media was processed inline, so it starts out ready.
+alter table media add column if not exists status varchar(20) not null default 'ready'
+    check (status in ('processing', 'ready', 'failed'));
+-- A worker holds a media row until locked_until; a crashed worker's rows
+-- are picked up again once it passes.
+alter table media add column if not exists locked_until timestamptz null;
+
+create index if not exists idx_media_processing on media(id) where status = 'processing';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_media_processing;
+alter table media drop column if exists locked_until;
+alter table media drop column if exists status;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table media add column if not exists video_codec varchar(50) null;
+alter table media add column if not exists audio_codec varchar(50) null;
+-- poster_key is a still frame of a video, stored next to it.
+alter table media add column if not exists poster_key text null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table media drop column if exists poster_key;
+alter table media drop column if exists audio_codec;
+alter table media drop column if exists video_codec;
+-- +goose StatementEnd
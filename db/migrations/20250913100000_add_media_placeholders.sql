@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Placeholders shown while an image loads: a BlurHash and a #rrggbb color.
+alter table media add column if not exists blurhash varchar(64) null;
+alter table media add column if not exists dominant_color varchar(7) null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table media drop column if exists dominant_color;
+alter table media drop column if exists blurhash;
+-- +goose StatementEnd
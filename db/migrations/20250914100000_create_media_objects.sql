@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per stored original. Media rows of the same owner with identical
+-- content share it, and ref_count counts them; the object is deleted with
+-- the last one.
+create table if not exists media_objects (
+    storage_key text primary key,
+    owner_id bigint not null references users(id) on delete cascade,
+    content_hash char(64) not null,
+    size_bytes bigint not null,
+    ref_count int not null default 1 check (ref_count >= 0),
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists ux_media_objects_owner_hash on media_objects(owner_id, content_hash);
+
+-- SHA-256 of the stored bytes, hex encoded.
+alter table media add column if not exists content_hash char(64) null;
+create index if not exists idx_media_owner_hash on media(owner_id, content_hash);
+
+-- Deduplicated media share their original and, through it, their variants.
+drop index if exists ux_media_storage_key;
+create index if not exists idx_media_storage_key on media(storage_key);
+drop index if exists ux_media_variants_storage_key;
+create index if not exists idx_media_variants_storage_key on media_variants(storage_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_media_variants_storage_key;
+create unique index if not exists ux_media_variants_storage_key on media_variants(storage_key);
+drop index if exists idx_media_storage_key;
+create unique index if not exists ux_media_storage_key on media(storage_key);
+drop index if exists idx_media_owner_hash;
+alter table media drop column if exists content_hash;
+drop table if exists media_objects;
+-- +goose StatementEnd
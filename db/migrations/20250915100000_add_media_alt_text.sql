@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Alt text describing media for screen readers. Uploads that create media
+-- later carry it until then.
+alter table media add column if not exists alt_text text null;
+alter table media_uploads add column if not exists alt_text text null;
+alter table resumable_uploads add column if not exists alt_text text null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table resumable_uploads drop column if exists alt_text;
+alter table media_uploads drop column if exists alt_text;
+alter table media drop column if exists alt_text;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Garbage collection looks media up from their attachments and stored
+-- objects up by key.
+create index if not exists idx_post_media_media on post_media(media_id);
+create index if not exists idx_message_media_media on message_media(media_id);
+create index if not exists idx_media_deleted on media(deleted_at) where deleted_at is not null;
+create index if not exists idx_media_poster_key on media(poster_key) where poster_key is not null;
+create index if not exists idx_resumable_upload_parts_storage_key on resumable_upload_parts(storage_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_resumable_upload_parts_storage_key;
+drop index if exists idx_media_poster_key;
+drop index if exists idx_media_deleted;
+drop index if exists idx_message_media_media;
+drop index if exists idx_post_media_media;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Quota checks count a user's media uploaded since the start of the day.
+create index if not exists idx_media_owner_created on media(owner_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_media_owner_created;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Distinct actors of a grouped notification; actor_count is their number.
+create table if not exists notification_actors (
+    notification_id bigint not null references notifications(id) on delete cascade,
+    actor_id bigint not null references users(id) on delete cascade,
+    primary key (notification_id, actor_id)
+);
+
+insert into notification_actors (notification_id, actor_id)
+select id, actor_id from notifications
+on conflict do nothing;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists notification_actors;
+-- +goose StatementEnd
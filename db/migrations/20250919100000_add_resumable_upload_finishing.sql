@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Set while one request stitches a complete upload into media, so a retry
+-- racing it does not build the media twice.
+alter table resumable_uploads add column if not exists finishing_at timestamptz null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table resumable_uploads drop column if exists finishing_at;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The pair of users of each direct conversation. The unique index keeps two
+-- racing requests from opening a second conversation between the same two.
+create table if not exists direct_conversations (
+    conversation_id bigint primary key references conversations(id) on delete cascade,
+    user_a bigint not null references users(id) on delete cascade,
+    user_b bigint not null references users(id) on delete cascade
+);
+
+create unique index if not exists idx_direct_conversations_pair
+on direct_conversations ((least(user_a, user_b)), (greatest(user_a, user_b)));
+
+-- Where a pair already has several, the oldest is the one found from now on.
+insert into direct_conversations (conversation_id, user_a, user_b)
+select c.id, min(p.user_id), max(p.user_id)
+from conversations c
+join conversation_participants p
+on p.conversation_id = c.id
+where c.kind = 'direct'
+group by c.id
+having count(*) = 2
+order by c.id
+on conflict do nothing;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists direct_conversations;
+-- +goose StatementEnd
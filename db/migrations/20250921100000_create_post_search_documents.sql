@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The text search configuration posts are indexed in. The app sets it to
+-- SEARCH_LANGUAGE on start, rebuilding the documents when it changes, so
+-- queries in that language always match the index.
+create table if not exists search_settings (
+    id boolean primary key default true check (id),
+    config regconfig not null
+);
+
+insert into search_settings (config) values ('english')
+on conflict do nothing;
+
+-- The weighted document of each post, kept by a trigger so the index does
+-- not depend on a configuration constant.
+create table if not exists post_search_documents (
+    post_id bigint primary key references posts(id) on delete cascade,
+    document tsvector not null
+);
+
+create index if not exists idx_post_search_documents_document
+on post_search_documents using gin (document);
+
+create or replace function set_post_search_document()
+returns trigger as $$
+declare
+    cfg regconfig;
+begin
+    select config into cfg from search_settings;
+    insert into post_search_documents (post_id, document)
+    values (
+        new.id,
+        setweight(to_tsvector(cfg, new.title), 'A') || setweight(to_tsvector(cfg, new.description), 'B')
+    )
+    on conflict (post_id) do update set document = excluded.document;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger trg_post_search_document
+after insert or update of title, description on posts
+for each row
+execute function set_post_search_document();
+
+insert into post_search_documents (post_id, document)
+select id, setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B')
+from posts
+on conflict do nothing;
+
+drop index if exists idx_posts_search;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+create index if not exists idx_posts_search on posts using gin (
+    (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B'))
+);
+
+drop trigger if exists trg_post_search_document on posts;
+drop function if exists set_post_search_document();
+drop table if exists post_search_documents;
+drop table if exists search_settings;
+-- +goose StatementEnd
@@ -0,0 +1,67 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Whether viewer_id (null for an anonymous caller) may see a post by
+-- author_id. Blocks either way hide it; otherwise the author always sees it,
+-- others by its visibility and the author's privacy, and mentioned users the
+-- posts they are mentioned in. Unlisted posts only show where
+-- include_unlisted is set: on the post itself and on the author's profile.
+-- A plain SQL function, so the planner inlines it into each query.
+create or replace function post_visible_to(
+    target_post_id bigint,
+    author_id bigint,
+    post_visibility text,
+    viewer_id bigint,
+    include_unlisted boolean
+)
+returns boolean as $$
+    select (
+        viewer_id is null
+        or not exists (
+            select 1 from user_blocks b
+            where (b.blocker_id = viewer_id and b.blocked_id = author_id)
+            or (b.blocked_id = viewer_id and b.blocker_id = author_id)
+        )
+    )
+    and (
+        author_id = viewer_id
+        or (
+            (
+                post_visibility = 'public'
+                or (post_visibility = 'unlisted' and include_unlisted)
+                or (post_visibility = 'followers' and exists (
+                    select 1 from follows f
+                    where f.follower_id = viewer_id
+                    and f.followee_id = author_id
+                    and f.status = 'accepted'
+                ))
+            )
+            and (
+                not exists (
+                    select 1 from users au
+                    where au.id = author_id
+                    and au.is_private
+                )
+                or exists (
+                    select 1 from follows f
+                    where f.follower_id = viewer_id
+                    and f.followee_id = author_id
+                    and f.status = 'accepted'
+                )
+            )
+        )
+        or (
+            post_visibility = 'mentioned'
+            and exists (
+                select 1 from post_mentions pmn
+                where pmn.post_id = target_post_id
+                and pmn.user_id = viewer_id
+            )
+        )
+    )
+$$ language sql stable;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop function if exists post_visible_to(bigint, bigint, text, bigint, boolean);
+-- +goose StatementEnd
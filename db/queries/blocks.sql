@@ -0,0 +1,55 @@
+-- name: CreateBlock :exec
+insert into user_blocks (blocker_id, blocked_id)
+values ($1, $2)
+on conflict (blocker_id, blocked_id) do nothing;
+
+-- name: DeleteBlock :execrows
+delete from user_blocks
+where blocker_id = $1
+and blocked_id = $2;
+
+-- name: ListBlocks :many
+select b.blocked_id AS user_id,
+  u.username,
+  b.created_at
+from user_blocks b
+join users u
+on u.id = b.blocked_id
+where b.blocker_id = $1
+order by b.created_at desc, b.blocked_id desc
+limit $2 offset $3;
+
+-- name: IsBlockedEither :one
+SELECT EXISTS(
+  SELECT 1 FROM user_blocks
+  WHERE (blocker_id = sqlc.arg('user_a') AND blocked_id = sqlc.arg('user_b'))
+  OR (blocker_id = sqlc.arg('user_b') AND blocked_id = sqlc.arg('user_a'))
+);
+
+-- name: CreateMute :exec
+insert into user_mutes (muter_id, muted_id)
+values ($1, $2)
+on conflict (muter_id, muted_id) do nothing;
+
+-- name: DeleteMute :execrows
+delete from user_mutes
+where muter_id = $1
+and muted_id = $2;
+
+-- name: ListMutes :many
+select m.muted_id AS user_id,
+  u.username,
+  m.created_at
+from user_mutes m
+join users u
+on u.id = m.muted_id
+where m.muter_id = $1
+order by m.created_at desc, m.muted_id desc
+limit $2 offset $3;
+
+-- name: ListHiddenUserIDs :many
+select blocked_id AS user_id from user_blocks where blocker_id = sqlc.arg('user_id')
+union
+select blocker_id AS user_id from user_blocks where blocked_id = sqlc.arg('user_id')
+union
+select muted_id AS user_id from user_mutes where muter_id = sqlc.arg('user_id');
@@ -0,0 +1,79 @@
+-- name: CreateConversation :one
+insert into conversations (kind, title, created_by)
+values ($1, $2, $3)
+returning conversations.*;
+
+-- name: AddConversationParticipant :exec
+insert into conversation_participants (conversation_id, user_id)
+values ($1, $2)
+on conflict (conversation_id, user_id) do nothing;
+
+-- name: AddDirectConversation :execrows
+insert into direct_conversations (conversation_id, user_a, user_b)
+values ($1, $2, $3)
+on conflict ((least(user_a, user_b)), (greatest(user_a, user_b))) do nothing;
+
+-- name: FindDirectConversation :one
+select c.*
+from conversations c
+join direct_conversations d
+on d.conversation_id = c.id
+where least(d.user_a, d.user_b) = least(sqlc.arg('user_a')::bigint, sqlc.arg('user_b')::bigint)
+and greatest(d.user_a, d.user_b) = greatest(sqlc.arg('user_a')::bigint, sqlc.arg('user_b')::bigint)
+limit 1;
+
+-- name: GetConversationForUser :one
+select c.*
+from conversations c
+join conversation_participants p
+on p.conversation_id = c.id
+where c.id = $1
+and p.user_id = $2
+limit 1;
+
+-- name: ListConversationsByUser :many
+select c.*,
+  p.last_read_message_id,
+  (
+    select count(*)
+    from messages m
+    where m.conversation_id = c.id
+    and m.deleted_at is null
+    and m.sender_id <> p.user_id
+    and m.id > coalesce(p.last_read_message_id, 0)
+  )::bigint AS unread_count
+from conversations c
+join conversation_participants p
+on p.conversation_id = c.id
+where p.user_id = sqlc.arg('user_id')
+and (
+  sqlc.narg('before_at')::timestamptz IS NULL
+  OR (c.last_activity_at, c.id) < (sqlc.narg('before_at')::timestamptz, sqlc.narg('before_id')::bigint)
+)
+order by c.last_activity_at desc, c.id desc
+limit sqlc.arg('limit');
+
+-- name: ListConversationParticipants :many
+select p.*,
+  u.username AS username
+from conversation_participants p
+join users u
+on u.id = p.user_id
+where p.conversation_id = any(sqlc.arg('conversation_ids')::bigint[])
+order by p.conversation_id, p.joined_at, p.user_id;
+
+-- name: TouchConversation :exec
+update conversations
+set last_activity_at = now()
+where id = $1;
+
+-- name: MarkConversationRead :execrows
+update conversation_participants
+set last_read_message_id = greatest(coalesce(last_read_message_id, 0), sqlc.arg('message_id')::bigint)
+where conversation_id = sqlc.arg('conversation_id')
+and user_id = sqlc.arg('user_id')
+and exists (
+  select 1 from messages m
+  where m.id = sqlc.arg('message_id')::bigint
+  and m.conversation_id = sqlc.arg('conversation_id')
+);
@@ -0,0 +1,60 @@
+-- name: UpsertFollow :one
+insert into follows (follower_id, followee_id, status)
+values ($1, $2, $3)
+on conflict (follower_id, followee_id) do update
+set status = follows.status
+returning status, (xmax = 0)::boolean AS inserted;
+
+-- name: DeleteFollow :execrows
+delete from follows
+where follower_id = $1
+and followee_id = $2;
+
+-- name: DeletePendingFollow :execrows
+delete from follows
+where follower_id = $1
+and followee_id = $2
+and status = 'pending';
+
+-- name: DeleteFollowsBetween :exec
+delete from follows
+where (follower_id = sqlc.arg('user_a') and followee_id = sqlc.arg('user_b'))
+or (follower_id = sqlc.arg('user_b') and followee_id = sqlc.arg('user_a'));
+
+-- name: AcceptFollow :execrows
+update follows
+set status = 'accepted'
+where follower_id = $1
+and followee_id = $2
+and status = 'pending';
+
+-- name: AcceptAllPendingFollows :exec
+update follows
+set status = 'accepted'
+where followee_id = $1
+and status = 'pending';
+
+-- name: ListFollowRequests :many
+select f.follower_id AS user_id,
+  u.username,
+  f.created_at
+from follows f
+join users u
+on u.id = f.follower_id
+where f.followee_id = $1
+and f.status = 'pending'
+and u.deleted_at is null
+order by f.created_at desc, f.follower_id desc
+limit $2 offset $3;
+
+-- name: ListAcceptedFollowerIDs :many
+select follower_id
+from follows
+where followee_id = $1
+and status = 'accepted';
+
+-- name: IsFollowing :one
+SELECT EXISTS(
+  SELECT 1 FROM follows
+  WHERE follower_id = $1 AND followee_id = $2 AND status = 'accepted'
+);
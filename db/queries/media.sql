@@ -1,13 +1,116 @@
 -- name: CreateMedia :one
 INSERT INTO media (
-  owner_id, kind, storage_key, mime_type, size_bytes, width, height, duration_ms
+  owner_id, kind, storage_key, mime_type, size_bytes, width, height, duration_ms, status, content_hash, alt_text
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 )
 RETURNING media.*;
 
--- name: AttachMediaToPost :exec
-INSERT INTO post_media (post_id, media_id, position)
-VALUES ($1, $2, $3)
-ON CONFLICT (post_id, media_id)
-DO UPDATE SET position = EXCLUDED.position;
\ No newline at end of file
+-- name: AttachMediaToPost :execrows
+insert into post_media (post_id, media_id, position)
+select p.id, sqlc.arg('media_id'), coalesce(max(pm.position) + 1, 0)
+from posts p
+left join post_media pm
+on pm.post_id = p.id
+where p.id = sqlc.arg('post_id')
+and p.user_id = sqlc.arg('user_id')
+and p.deleted_at is null
+group by p.id
+on conflict (post_id, media_id)
+do update set position = post_media.position;
+
+-- name: ListOwnedMediaByIDs :many
+select media.*
+from media
+where id = any(sqlc.arg('ids')::bigint[])
+and owner_id = sqlc.arg('owner_id')
+and deleted_at is null;
+
+-- name: ClaimMediaForProcessing :one
+update media
+set locked_until = sqlc.arg('locked_until')
+where id = sqlc.arg('id')
+and status = 'processing'
+and deleted_at is null
+and (locked_until is null or locked_until < now())
+returning media.*;
+
+-- name: FinishMediaProcessing :exec
+update media
+set status = sqlc.arg('status'),
+  width = sqlc.narg('width'),
+  height = sqlc.narg('height'),
+  duration_ms = sqlc.narg('duration_ms'),
+  video_codec = sqlc.narg('video_codec'),
+  audio_codec = sqlc.narg('audio_codec'),
+  poster_key = sqlc.narg('poster_key'),
+  blurhash = sqlc.narg('blurhash'),
+  dominant_color = sqlc.narg('dominant_color'),
+  locked_until = null
+where id = sqlc.arg('id');
+
+-- name: GetMediaUsage :one
+with live_objects as (
+  select distinct m.storage_key, coalesce(o.size_bytes, m.size_bytes) as size_bytes
+  from media m
+  left join media_objects o on o.storage_key = m.storage_key
+  where m.owner_id = sqlc.arg('owner_id')
+  and m.deleted_at is null
+)
+select coalesce((select sum(size_bytes) from live_objects), 0)::bigint as total_bytes,
+  (
+    select count(*) from media
+    where owner_id = sqlc.arg('owner_id')
+    and created_at >= sqlc.arg('since')
+  )::bigint as files_since;
+
+-- name: GetServedObject :one
+select mime_type, content_hash
+from media
+where storage_key = sqlc.arg('storage_key')
+and deleted_at is null
+union all
+select v.mime_type, null::text
+from media_variants v
+join media m on m.id = v.media_id
+where v.storage_key = sqlc.arg('storage_key')
+and m.deleted_at is null
+union all
+select 'image/jpeg', null::text
+from media
+where poster_key = sqlc.arg('storage_key')
+and deleted_at is null
+limit 1;
+
+-- name: ListPendingMediaIDs :many
+select id
+from media
+where status = 'processing'
+and deleted_at is null
+and (locked_until is null or locked_until < now())
+order by id
+limit $1;
+
+-- name: ListMediaByOwner :many
+select media.*
+from media
+where owner_id = sqlc.arg('owner_id')
+and deleted_at is null
+and (sqlc.narg('kind')::text is null or kind = sqlc.narg('kind'))
+order by created_at desc, id desc
+limit sqlc.arg('limit') offset sqlc.arg('offset');
+
+-- name: SoftDeleteMedia :execrows
+update media
+set deleted_at = now()
+where id = $1
+and owner_id = $2
+and deleted_at is null;
+
+-- name: UpdateMediaAltText :one
+update media
+set alt_text = sqlc.narg('alt_text')
+where id = sqlc.arg('id')
+and owner_id = sqlc.arg('owner_id')
+and deleted_at is null
+returning media.*;
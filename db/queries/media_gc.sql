@@ -0,0 +1,94 @@
+-- name: ListUnattachedMedia :many
+select media.*
+from media
+where deleted_at is null
+and created_at < sqlc.arg('created_before')
+and (locked_until is null or locked_until < now())
+and not exists (
+  select 1
+  from post_media pm
+  join posts p on p.id = pm.post_id
+  where pm.media_id = media.id
+  and (p.deleted_at is null or p.deleted_at >= sqlc.arg('deleted_before'))
+)
+and not exists (
+  select 1
+  from message_media mm
+  join messages msg on msg.id = mm.message_id
+  where mm.media_id = media.id
+  and (msg.deleted_at is null or msg.deleted_at >= sqlc.arg('deleted_before'))
+)
+order by id
+limit sqlc.arg('limit');
+
+-- name: ListDeletedMedia :many
+select media.*
+from media
+where deleted_at < sqlc.arg('deleted_before')
+order by id
+limit sqlc.arg('limit');
+
+-- name: PurgeMedia :one
+delete from media
+where id = sqlc.arg('id')
+and (
+  deleted_at < sqlc.arg('deleted_before')
+  or (
+    deleted_at is null
+    and created_at < sqlc.arg('created_before')
+    and (locked_until is null or locked_until < now())
+    and not exists (
+      select 1
+      from post_media pm
+      join posts p on p.id = pm.post_id
+      where pm.media_id = media.id
+      and (p.deleted_at is null or p.deleted_at >= sqlc.arg('deleted_before'))
+    )
+    and not exists (
+      select 1
+      from message_media mm
+      join messages msg on msg.id = mm.message_id
+      where mm.media_id = media.id
+      and (msg.deleted_at is null or msg.deleted_at >= sqlc.arg('deleted_before'))
+    )
+  )
+)
+returning storage_key;
+
+-- name: ListExpiredMediaUploads :many
+select media_uploads.*
+from media_uploads
+where completed_at is null
+and expires_at < now()
+order by expires_at
+limit $1;
+
+-- name: DeleteExpiredMediaUpload :execrows
+delete from media_uploads
+where id = $1
+and completed_at is null
+and expires_at < now();
+
+-- name: ListExpiredResumableUploadIDs :many
+select id
+from resumable_uploads
+where media_id is null
+and expires_at < now()
+order by expires_at
+limit $1;
+
+-- name: DeleteExpiredResumableUpload :execrows
+delete from resumable_uploads
+where id = $1
+and media_id is null
+and expires_at < now();
+
+-- name: ListKnownStorageKeys :many
+select k::text
+from unnest(sqlc.arg('keys')::text[]) as k
+where exists (select 1 from media where storage_key = k)
+or exists (select 1 from media where poster_key = k)
+or exists (select 1 from media_variants where storage_key = k)
+or exists (select 1 from media_objects where storage_key = k)
+or exists (select 1 from media_uploads where storage_key = k and completed_at is null)
+or exists (select 1 from resumable_upload_parts where storage_key = k);
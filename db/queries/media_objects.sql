@@ -0,0 +1,17 @@
+-- name: AcquireMediaObject :one
+insert into media_objects (storage_key, owner_id, content_hash, size_bytes)
+values ($1, $2, $3, $4)
+on conflict (owner_id, content_hash)
+do update set ref_count = media_objects.ref_count + 1
+returning storage_key;
+
+-- name: ReleaseMediaObject :one
+update media_objects
+set ref_count = ref_count - 1
+where storage_key = $1
+returning ref_count;
+
+-- name: DeleteMediaObject :exec
+delete from media_objects
+where storage_key = $1
+and ref_count = 0;
@@ -0,0 +1,24 @@
+-- name: CreateMediaUpload :one
+insert into media_uploads (owner_id, kind, storage_key, mime_type, size_bytes, expires_at, alt_text)
+values ($1, $2, $3, $4, $5, $6, $7)
+returning media_uploads.*;
+
+-- name: GetMediaUpload :one
+select media_uploads.*
+from media_uploads
+where id = $1
+and owner_id = $2
+limit 1;
+
+-- name: GetMediaUploadByKey :one
+select media_uploads.*
+from media_uploads
+where storage_key = $1
+limit 1;
+
+-- name: CompleteMediaUpload :execrows
+update media_uploads
+set completed_at = now(),
+media_id = $2
+where id = $1
+and completed_at is null;
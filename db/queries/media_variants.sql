@@ -0,0 +1,15 @@
+-- name: CreateMediaVariant :one
+insert into media_variants (media_id, width, height, storage_key, mime_type, size_bytes)
+values ($1, $2, $3, $4, $5, $6)
+on conflict (media_id, width)
+do update set height = excluded.height,
+  storage_key = excluded.storage_key,
+  mime_type = excluded.mime_type,
+  size_bytes = excluded.size_bytes
+returning media_variants.*;
+
+-- name: ListMediaVariants :many
+select media_variants.*
+from media_variants
+where media_id = any(sqlc.arg('media_ids')::bigint[])
+order by media_id, width;
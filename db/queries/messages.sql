@@ -0,0 +1,30 @@
+-- name: CreateMessage :one
+insert into messages (conversation_id, sender_id, body)
+values ($1, $2, $3)
+returning messages.*;
+
+-- name: ListMessagesPaginated :many
+select messages.*
+from messages
+where conversation_id = sqlc.arg('conversation_id')
+and deleted_at is null
+and (sqlc.narg('before_id')::bigint IS NULL OR id < sqlc.narg('before_id')::bigint)
+order by id desc
+limit sqlc.arg('limit');
+
+-- name: AttachMediaToMessage :exec
+insert into message_media (message_id, media_id, position)
+values ($1, $2, $3)
+on conflict (message_id, media_id)
+do update set position = EXCLUDED.position;
+
+-- name: ListMessageMedia :many
+select mm.message_id,
+  mm.position,
+  m.*
+from message_media mm
+join media m
+on m.id = mm.media_id
+and m.deleted_at is null
+where mm.message_id = any(sqlc.arg('message_ids')::bigint[])
+order by mm.message_id, mm.position asc, m.id asc;
@@ -0,0 +1,83 @@
+-- name: UpsertNotification :one
+insert into notifications (user_id, actor_id, type, entity_id, group_key)
+values ($1, $2, $3, $4, $5)
+on conflict (user_id, group_key) where read_at is null
+do update set actor_id = excluded.actor_id
+returning notifications.id;
+
+-- name: AddNotificationActor :exec
+insert into notification_actors (notification_id, actor_id)
+values ($1, $2)
+on conflict do nothing;
+
+-- name: RefreshNotificationActorCount :one
+with n as (
+  update notifications
+  set actor_count = (
+    select count(*) from notification_actors na
+    where na.notification_id = notifications.id
+  )
+  where notifications.id = $1
+  returning notifications.*
+)
+select n.*,
+  u.username AS actor_username
+from n
+join users u
+on u.id = n.actor_id;
+
+-- name: ListNotificationsByUser :many
+select n.*,
+  u.username AS actor_username
+from notifications n
+join users u
+on u.id = n.actor_id
+where n.user_id = $1
+and not exists (
+  select 1 from user_blocks b
+  where (b.blocker_id = n.user_id and b.blocked_id = n.actor_id)
+  or (b.blocker_id = n.actor_id and b.blocked_id = n.user_id)
+)
+order by n.updated_at desc, n.id desc
+limit $2 offset $3;
+
+-- name: CountUnreadNotifications :one
+select count(*)
+from notifications n
+where n.user_id = $1
+and n.read_at is null
+and not exists (
+  select 1 from user_blocks b
+  where (b.blocker_id = n.user_id and b.blocked_id = n.actor_id)
+  or (b.blocker_id = n.actor_id and b.blocked_id = n.user_id)
+);
+
+-- name: MarkNotificationRead :execrows
+update notifications
+set read_at = now()
+where id = $1
+and user_id = $2
+and read_at is null;
+
+-- name: MarkAllNotificationsRead :exec
+update notifications
+set read_at = now()
+where user_id = $1
+and read_at is null;
+
+-- name: IsNotificationTypeEnabled :one
+SELECT NOT EXISTS(
+  SELECT 1 FROM notification_preferences
+  WHERE user_id = $1 AND type = $2 AND enabled = false
+);
+
+-- name: ListNotificationPreferences :many
+select notification_preferences.*
+from notification_preferences
+where user_id = $1;
+
+-- name: UpsertNotificationPreference :exec
+insert into notification_preferences (user_id, type, enabled)
+values ($1, $2, $3)
+on conflict (user_id, type)
+do update set enabled = excluded.enabled;
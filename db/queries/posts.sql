@@ -1,35 +1,52 @@
 -- name: CreatePost :one
-insert into posts (title, description, user_id)
-values ($1, $2, $3)
+insert into posts (title, description, user_id, visibility)
+values ($1, $2, $3, $4)
 returning posts.*;
 
--- name: GetAllPostsByUser :many
-select posts.*
-from posts
-where user_id = $1
-and deleted_at is null
-order by created_at desc, id desc;
+-- name: GetPostWithMedia :many
+select p.*,
+  m.id          AS media_id,
+  m.kind        AS media_kind,
+  m.mime_type   AS media_mime_type,
+  m.storage_key AS media_storage_key,
+  m.width       AS media_width,
+  m.height      AS media_height,
+  m.duration_ms AS media_duration_ms,
+  m.status      AS media_status,
+  m.video_codec AS media_video_codec,
+  m.audio_codec AS media_audio_codec,
+  m.poster_key  AS media_poster_key,
+  m.blurhash    AS media_blurhash,
+  m.dominant_color AS media_dominant_color,
+  m.content_hash AS media_content_hash,
+  m.alt_text    AS media_alt_text,
+  pm.position   AS media_position
+from posts p 
+left join post_media pm
+on pm.post_id = p.id
+left join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where p.id = sqlc.arg('id')
+and p.deleted_at is null
+and post_visible_to(p.id, p.user_id, p.visibility, sqlc.narg('viewer_id')::bigint, true)
+order by pm.position asc, m.created_at asc, m.id asc;
 
--- name: SoftDeletePost :exec
+-- name: SoftDeletePost :execrows
 update posts
 set deleted_at = now()
-where id = $1 and deleted_at is null;
+where id = $1 and user_id = $2 and deleted_at is null;
 
 -- name: UpdatePostPartial :one
 update posts
 set title = coalesce(sqlc.narg(title), title),
-description = coalesce (sqlc.narg(description), description)
+description = coalesce (sqlc.narg(description), description),
+visibility = coalesce(sqlc.narg(visibility), visibility)
 where id = sqlc.arg(id)
+and user_id = sqlc.arg(user_id)
 and deleted_at is null
 returning posts.*;
 
--- name: ListPostsPaginated :many
-select posts.*
-from posts
-where deleted_at is null
-order by created_at desc, id desc
-limit $1 offset $2;
-
 -- name: ListPostsWithMediaPaginated :many
 select p.*,
   m.id          AS media_id,
@@ -39,6 +56,14 @@ select p.*,
   m.width       AS media_width,
   m.height      AS media_height,
   m.duration_ms AS media_duration_ms,
+  m.status      AS media_status,
+  m.video_codec AS media_video_codec,
+  m.audio_codec AS media_audio_codec,
+  m.poster_key  AS media_poster_key,
+  m.blurhash    AS media_blurhash,
+  m.dominant_color AS media_dominant_color,
+  m.content_hash AS media_content_hash,
+  m.alt_text    AS media_alt_text,
   pm.position   AS media_position
 from posts p 
 left join post_media pm
@@ -48,9 +73,73 @@ on m.id = pm.media_id
 and m.deleted_at is null
 where p.deleted_at is null
 and  (sqlc.narg('user_id')::bigint IS NULL OR p.user_id = sqlc.narg('user_id')::bigint)
+-- Unlisted posts only show on their author's profile.
+and post_visible_to(p.id, p.user_id, p.visibility, sqlc.narg('viewer_id')::bigint, sqlc.narg('user_id')::bigint IS NOT NULL)
+and (
+  sqlc.narg('viewer_id')::bigint IS NULL
+  OR sqlc.narg('user_id')::bigint IS NOT NULL
+  OR NOT EXISTS (
+    select 1 from user_mutes mu
+    where mu.muter_id = sqlc.narg('viewer_id')::bigint
+    and mu.muted_id = p.user_id
+  )
+)
 order by p.created_at desc, p.id desc,
 pm.position asc, m.created_at asc, m.id asc
 limit sqlc.arg('limit') 
 offset sqlc.arg('offset');
 
+-- name: DeletePostMentions :exec
+delete from post_mentions
+where post_id = $1;
+
+-- name: CreatePostMentions :exec
+insert into post_mentions (post_id, user_id)
+select sqlc.arg('post_id'), unnest(sqlc.arg('user_ids')::bigint[])
+on conflict (post_id, user_id) do nothing;
+
+-- name: ListPostMentionIDs :many
+select user_id
+from post_mentions
+where post_id = $1;
+
+-- name: LockOwnedPost :one
+select id
+from posts
+where id = $1
+and user_id = $2
+and deleted_at is null
+for update;
+
+-- name: ListPostMediaIDs :many
+select pm.media_id
+from post_media pm
+join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where pm.post_id = $1
+order by pm.position asc, m.created_at asc, m.id asc;
+
+-- name: ListPostMediaKinds :many
+select m.kind
+from post_media pm
+join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where pm.post_id = $1;
+
+-- name: DetachPostMedia :execrows
+delete from post_media pm
+using posts p
+where p.id = pm.post_id
+and pm.post_id = sqlc.arg('post_id')
+and pm.media_id = sqlc.arg('media_id')
+and p.user_id = sqlc.arg('user_id')
+and p.deleted_at is null;
 
+-- name: SetPostMediaPositions :exec
+update post_media pm
+set position = o.ord - 1
+from unnest(sqlc.arg('media_ids')::bigint[]) with ordinality as o(media_id, ord)
+where pm.post_id = sqlc.arg('post_id')
+and pm.media_id = o.media_id;
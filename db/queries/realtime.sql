@@ -0,0 +1,28 @@
+-- name: CreateRealtimeEvent :one
+insert into realtime_events (topic, type, payload)
+values ($1, $2, $3)
+returning realtime_events.*;
+
+-- name: GetRealtimeEvent :one
+select realtime_events.*
+from realtime_events
+where id = $1
+limit 1;
+
+-- name: ListRealtimeEventsSince :many
+select realtime_events.*
+from realtime_events
+where id > sqlc.arg('after_id')
+and topic = any(sqlc.arg('topics')::text[])
+order by id asc
+limit sqlc.arg('limit');
+
+-- name: DeleteRealtimeEventsBefore :exec
+delete from realtime_events
+where created_at < $1;
+
+-- name: LockRealtimePublish :exec
+select pg_advisory_xact_lock(hashtext('realtime_events'));
+
+-- name: NotifyRealtimeEvent :exec
+select pg_notify('realtime_events', sqlc.arg('id')::bigint::text);
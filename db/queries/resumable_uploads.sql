@@ -0,0 +1,58 @@
+-- name: CreateResumableUpload :one
+insert into resumable_uploads (id, owner_id, filename, mime_type, upload_length, metadata, expires_at, alt_text)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning resumable_uploads.*;
+
+-- name: GetResumableUpload :one
+select resumable_uploads.*
+from resumable_uploads
+where id = $1
+and owner_id = $2
+limit 1;
+
+-- name: AdvanceResumableUpload :execrows
+update resumable_uploads
+set upload_offset = sqlc.arg('new_offset'),
+updated_at = now()
+where id = sqlc.arg('id')
+and upload_offset = sqlc.arg('old_offset')
+and media_id is null;
+
+-- name: CreateResumableUploadPart :exec
+insert into resumable_upload_parts (upload_id, part_offset, storage_key, size_bytes)
+values ($1, $2, $3, $4);
+
+-- name: ListResumableUploadParts :many
+select resumable_upload_parts.*
+from resumable_upload_parts
+where upload_id = $1
+order by part_offset;
+
+-- name: ClaimResumableUpload :execrows
+update resumable_uploads
+set finishing_at = now()
+where id = sqlc.arg('id')
+and media_id is null
+and (finishing_at is null or finishing_at < sqlc.arg('stale_before'));
+
+-- name: ReleaseResumableUpload :exec
+update resumable_uploads
+set finishing_at = null
+where id = $1
+and media_id is null;
+
+-- name: SetResumableUploadMedia :execrows
+update resumable_uploads
+set media_id = $2,
+updated_at = now()
+where id = $1
+and media_id is null;
+
+-- name: DeleteResumableUploadParts :exec
+delete from resumable_upload_parts
+where upload_id = $1;
+
+-- name: DeleteResumableUpload :exec
+delete from resumable_uploads
+where id = $1
+and owner_id = $2;
@@ -0,0 +1,66 @@
+-- name: SearchPosts :many
+select r.id, r.title, r.description, r.user_id, r.username, r.visibility, r.created_at, r.rank,
+  ts_headline(
+    sqlc.arg('lang')::regconfig,
+    replace(replace(replace(r.title || ' ' || r.description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+    websearch_to_tsquery(sqlc.arg('lang')::regconfig, sqlc.arg('query')),
+    'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2'
+  ) AS snippet
+from (
+  select p.id, p.title, p.description, p.user_id, u.username, p.visibility, p.created_at,
+    ts_rank(d.document, tsq)::float8 AS rank
+  from post_search_documents d
+  join posts p
+  on p.id = d.post_id
+  join users u
+  on u.id = p.user_id
+  and u.deleted_at is null
+  cross join websearch_to_tsquery(sqlc.arg('lang')::regconfig, sqlc.arg('query')) tsq
+  where d.document @@ tsq
+  and p.deleted_at is null
+  and post_visible_to(p.id, p.user_id, p.visibility, sqlc.narg('viewer_id')::bigint, false)
+) r
+where (
+  sqlc.narg('before_rank')::float8 IS NULL
+  OR (r.rank, r.id) < (sqlc.narg('before_rank')::float8, sqlc.narg('before_id')::bigint)
+)
+order by r.rank desc, r.id desc
+limit sqlc.arg('limit');
+
+-- name: SearchUsers :many
+select r.id, r.username, r.display_name, r.rank
+from (
+  select u.id, u.username, u.display_name,
+    greatest(
+      similarity(u.username::text, sqlc.arg('query')),
+      similarity(coalesce(u.display_name, ''), sqlc.arg('query'))
+    )::float8 AS rank
+  from users u
+  where u.deleted_at is null
+  and (u.username::text % sqlc.arg('query') OR u.display_name % sqlc.arg('query'))
+  and (
+    sqlc.narg('viewer_id')::bigint IS NULL
+    OR NOT EXISTS (
+      select 1 from user_blocks b
+      where (b.blocker_id = sqlc.narg('viewer_id')::bigint and b.blocked_id = u.id)
+      or (b.blocked_id = sqlc.narg('viewer_id')::bigint and b.blocker_id = u.id)
+    )
+  )
+) r
+where (
+  sqlc.narg('before_rank')::float8 IS NULL
+  OR (r.rank, r.id) < (sqlc.narg('before_rank')::float8, sqlc.narg('before_id')::bigint)
+)
+order by r.rank desc, r.id desc
+limit sqlc.arg('limit');
+
+-- name: SetSearchConfig :execrows
+update search_settings
+set config = sqlc.arg('lang')::regconfig
+where config <> sqlc.arg('lang')::regconfig;
+
+-- name: RebuildPostSearchDocuments :execrows
+update post_search_documents d
+set document = setweight(to_tsvector(sqlc.arg('lang')::regconfig, p.title), 'A') || setweight(to_tsvector(sqlc.arg('lang')::regconfig, p.description), 'B')
+from posts p
+where p.id = d.post_id;
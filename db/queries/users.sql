@@ -25,4 +25,16 @@ limit 1;
 SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND deleted_at IS NULL);
 
 -- name: ExistsUserByUsername :one
-SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 AND deleted_at IS NULL);
\ No newline at end of file
+SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 AND deleted_at IS NULL);
+
+-- name: UpdateUserPrivacy :one
+update users
+set is_private = $2
+where id = $1 and deleted_at is null
+returning users.*;
+
+-- name: UpdateUserDisplayName :one
+update users
+set display_name = $2
+where id = $1 and deleted_at is null
+returning users.*;
@@ -11,6 +11,31 @@ const CtxUserID ctxKey = "uid"
 const CtxRole ctxKey = "role"
 
 func Middleware(s *Service) func(http.Handler) http.Handler {
+	return middleware(s, false)
+}
+
+// OptionalMiddleware identifies the caller when a bearer token is sent and
+// lets anonymous requests through. An invalid token is still rejected.
+func OptionalMiddleware(s *Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		strict := Middleware(s)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			strict.ServeHTTP(w, r)
+		})
+	}
+}
+
+// StreamMiddleware also accepts the token as the access_token query
+// parameter, because browser EventSource cannot send an Authorization header.
+func StreamMiddleware(s *Service) func(http.Handler) http.Handler {
+	return middleware(s, true)
+}
+
+func middleware(s *Service, allowQuery bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == http.MethodOptions {
@@ -18,11 +43,16 @@ func Middleware(s *Service) func(http.Handler) http.Handler {
 				return
 			}
 			h := r.Header.Get("Authorization")
-			if !strings.HasPrefix(h, "Bearer ") {
+			var token string
+			switch {
+			case strings.HasPrefix(h, "Bearer "):
+				token = strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+			case allowQuery && r.URL.Query().Get("access_token") != "":
+				token = r.URL.Query().Get("access_token")
+			default:
 				http.Error(w, "missing bearer", http.StatusUnauthorized)
 				return
 			}
-			token := strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
 			claims, err := s.Verify(token)
 			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"go-rest-chi/internal/helpers"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -49,22 +51,127 @@ type StorageConfig struct {
 	LocalDir      string
 	PublicBaseURL string
 	PresignTTL    time.Duration
-	S3Bucket      string
-	S3Region      string
-	S3Endpoint    string
-	S3AccessKey   string
-	S3SecretKey   string
-	S3UsePath     bool
+	SigningSecret string
+	// LocalPublic serves local media to anyone at permanent URLs instead of
+	// signed ones that expire.
+	LocalPublic bool
+	S3Bucket    string
+	S3Region    string
+	S3Endpoint  string
+	S3AccessKey string
+	S3SecretKey string
+	S3UsePath   bool
+	// S3PublicBaseURL serves objects from a public bucket or CDN; empty
+	// means presigned URLs.
+	S3PublicBaseURL string
+	S3PartSizeMB    int
+	// Resumable (tus) uploads: largest accepted file and how long an
+	// unfinished upload can be resumed.
+	TusMaxSizeMB int
+	TusExpiry    time.Duration
+}
+
+// MediaConfig caps upload sizes per media kind and lists the exact MIME
+// types accepted for each kind.
+type MediaConfig struct {
+	MaxImageMB int
+	MaxVideoMB int
+	MaxAudioMB int
+	ImageTypes []string
+	VideoTypes []string
+	AudioTypes []string
+	// ImageVariants are the widths, in pixels, of the resized copies kept
+	// for every image.
+	ImageVariants []int
+	// Background processing of stored media.
+	Workers        int
+	QueueSize      int
+	ProcessTimeout time.Duration
+	SweepInterval  time.Duration
+	// FFprobePath and FFmpegPath name the binaries used to probe videos and
+	// audio and to extract poster frames; when ffprobe is missing only
+	// MP4 and WebM metadata is read.
+	FFprobePath string
+	FFmpegPath  string
+	// Media one post may hold, per kind. Unless PostMixKinds is set a post
+	// holds a single kind, so the defaults allow four images or one video.
+	PostMaxImages int
+	PostMaxVideos int
+	PostMaxAudio  int
+	PostMixKinds  bool
+	// AltTextMax caps alt text, in characters. AltTextPolicy is off, warn
+	// (flag images sent without alt text) or require (reject them).
+	AltTextMax    int
+	AltTextPolicy string
+	// Garbage collection of unreachable media and objects every GCInterval
+	// (0 disables it). Unattached media and objects without a row go after
+	// GCUnattachedAfter, deleted media after GCRetention. GCDryRun only logs.
+	GCInterval        time.Duration
+	GCUnattachedAfter time.Duration
+	GCRetention       time.Duration
+	GCBatchSize       int
+	GCDryRun          bool
+	// Per-user quota: MB of live media kept and files uploaded per UTC day,
+	// 0 for no limit. QuotaRoles overrides both for users of a role.
+	QuotaMB          int
+	QuotaFilesPerDay int
+	QuotaRoles       map[string]MediaQuota
+}
+
+// MediaQuota is the quota of one role; 0 is unlimited.
+type MediaQuota struct {
+	MB          int
+	FilesPerDay int
+}
+
+// parseQuotaRoles parses comma separated role:mb:files entries. Malformed
+// entries come back with negative limits for Validate to reject.
+func parseQuotaRoles(s string) map[string]MediaQuota {
+	out := map[string]MediaQuota{}
+	for _, entry := range helpers.Csv(s) {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || strings.TrimSpace(parts[0]) == "" {
+			out[entry] = MediaQuota{MB: -1, FilesPerDay: -1}
+			continue
+		}
+		out[strings.TrimSpace(parts[0])] = MediaQuota{
+			MB:          helpers.MustInt(strings.TrimSpace(parts[1]), -1),
+			FilesPerDay: helpers.MustInt(strings.TrimSpace(parts[2]), -1),
+		}
+	}
+	return out
+}
+
+type RealtimeConfig struct {
+	Driver      string
+	Heartbeat   time.Duration
+	ReplayLimit int
+	Retention   time.Duration
+}
+
+// searchLanguage matches the names of text search configurations, such as
+// "english" or "simple".
+var searchLanguage = regexp.MustCompile(`^[a-z_]+$`)
+
+type SearchConfig struct {
+	// Language is the Postgres text search configuration, e.g. "english".
+	Language string
 }
 
 type Config struct {
-	App     AppConfig
-	DB      DBConfig
-	CORS    CORSConfig
-	JWT     JWTConfig
-	Storage StorageConfig
+	App      AppConfig
+	DB       DBConfig
+	CORS     CORSConfig
+	JWT      JWTConfig
+	Storage  StorageConfig
+	Media    MediaConfig
+	Realtime RealtimeConfig
+	Search   SearchConfig
 }
 
+// exampleSigningSecret is the STORAGE_SIGNING_SECRET of .env.example.
+const exampleSigningSecret = "dev_signing_secret_change_me"
+
 func (c *Config) Validate() error {
 	if c.App.Env == "prod" {
 		if len(c.CORS.AllowedOrigins) == 1 && c.CORS.AllowedOrigins[0] == "*" {
@@ -86,6 +193,88 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unsupported DB_DRIVER %v", c.DB.Driver)
 	}
 
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.SigningSecret == "" {
+			return errors.New("STORAGE_SIGNING_SECRET is required with STORAGE_DRIVER=local")
+		}
+		// Anyone who knows the example secret can forge media URLs.
+		if c.App.Env != "dev" && c.Storage.SigningSecret == exampleSigningSecret {
+			return errors.New("STORAGE_SIGNING_SECRET must be changed from the .env.example value outside dev")
+		}
+	case "s3":
+		if c.Storage.S3Bucket == "" {
+			return errors.New("S3_BUCKET is required with STORAGE_DRIVER=s3")
+		}
+		// S3 rejects multipart parts below 5 MiB.
+		if c.Storage.S3PartSizeMB < 5 {
+			return errors.New("S3_PART_SIZE_MB must be at least 5")
+		}
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER %v", c.Storage.Driver)
+	}
+
+	if c.Storage.TusMaxSizeMB <= 0 {
+		return errors.New("TUS_MAX_SIZE_MB must be positive")
+	}
+
+	if c.Media.MaxImageMB <= 0 || c.Media.MaxVideoMB <= 0 || c.Media.MaxAudioMB <= 0 {
+		return errors.New("UPLOAD_MAX_*_MB must be positive")
+	}
+
+	for _, w := range c.Media.ImageVariants {
+		if w <= 0 {
+			return errors.New("UPLOAD_IMAGE_VARIANTS must be positive pixel widths")
+		}
+	}
+
+	if c.Media.Workers <= 0 || c.Media.QueueSize <= 0 {
+		return errors.New("MEDIA_WORKERS and MEDIA_QUEUE_SIZE must be positive")
+	}
+
+	if c.Media.PostMaxImages < 0 || c.Media.PostMaxVideos < 0 || c.Media.PostMaxAudio < 0 {
+		return errors.New("POST_MAX_* must not be negative")
+	}
+
+	if c.Media.AltTextMax <= 0 {
+		return errors.New("MEDIA_ALT_TEXT_MAX must be positive")
+	}
+	switch c.Media.AltTextPolicy {
+	case "off", "warn", "require":
+	default:
+		return fmt.Errorf("unsupported MEDIA_ALT_TEXT_POLICY %v", c.Media.AltTextPolicy)
+	}
+
+	if c.Media.GCInterval < 0 {
+		return errors.New("MEDIA_GC_INTERVAL must not be negative")
+	}
+	if c.Media.GCInterval > 0 {
+		// Uploads are stored before their rows exist and may be attached
+		// some time after; neither can be mistaken for garbage.
+		if c.Media.GCUnattachedAfter < time.Hour || c.Media.GCRetention < 0 || c.Media.GCBatchSize <= 0 {
+			return errors.New("MEDIA_GC_UNATTACHED_AFTER must be at least 1h, MEDIA_GC_RETENTION not negative and MEDIA_GC_BATCH_SIZE positive")
+		}
+	}
+
+	if c.Media.QuotaMB < 0 || c.Media.QuotaFilesPerDay < 0 {
+		return errors.New("MEDIA_QUOTA_MB and MEDIA_QUOTA_FILES_PER_DAY must not be negative")
+	}
+	for role, q := range c.Media.QuotaRoles {
+		if q.MB < 0 || q.FilesPerDay < 0 {
+			return fmt.Errorf("MEDIA_QUOTA_ROLES entry %q must be role:mb:files with non-negative limits", role)
+		}
+	}
+
+	switch c.Realtime.Driver {
+	case "memory", "postgres":
+	default:
+		return fmt.Errorf("unsupported REALTIME_DRIVER %v", c.Realtime.Driver)
+	}
+
+	if !searchLanguage.MatchString(c.Search.Language) {
+		return fmt.Errorf("invalid SEARCH_LANGUAGE %q", c.Search.Language)
+	}
+
 	return nil
 }
 
@@ -120,9 +309,9 @@ func Load() (*Config, error) {
 		},
 		CORS: CORSConfig{
 			AllowedOrigins:   helpers.Csv(helpers.GetEnv("CORS_ALLOWED_ORIGINS", "*")),
-			AllowedMethods:   helpers.Csv(helpers.GetEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
-			AllowedHeaders:   helpers.Csv(helpers.GetEnv("CORS_ALLOWED_HEADERS", "Accept,Authorization,Content-Type,X-CSRF-Token")),
-			ExposedHeaders:   helpers.Csv(helpers.GetEnv("CORS_EXPOSE_HEADERS", "")),
+			AllowedMethods:   helpers.Csv(helpers.GetEnv("CORS_ALLOWED_METHODS", "GET,HEAD,POST,PUT,PATCH,DELETE,OPTIONS")),
+			AllowedHeaders:   helpers.Csv(helpers.GetEnv("CORS_ALLOWED_HEADERS", "Accept,Authorization,Content-Type,X-CSRF-Token,Tus-Resumable,Upload-Length,Upload-Metadata,Upload-Offset,Upload-Checksum")),
+			ExposedHeaders:   helpers.Csv(helpers.GetEnv("CORS_EXPOSE_HEADERS", "Location,Tus-Resumable,Tus-Version,Tus-Extension,Tus-Max-Size,Tus-Checksum-Algorithm,Upload-Offset,Upload-Length,Upload-Metadata,Upload-Expires,Media-Id")),
 			AllowCredentials: helpers.MustBool(helpers.GetEnv("CORS_ALLOW_CREDENTIALS", "true"), true),
 			MaxAge:           helpers.MustInt(helpers.GetEnv("CORS_MAX_AGE", "300"), 300),
 		},
@@ -137,6 +326,8 @@ func Load() (*Config, error) {
 			LocalDir:      helpers.GetEnv("STORAGE_LOCAL_DIR", "./var/media"),
 			PublicBaseURL: helpers.GetEnv("STORAGE_PUBLIC_BASE_URL", "http://localhost:8080"),
 			PresignTTL:    helpers.MustDur(helpers.GetEnv("STORAGE_PRESIGN_TTL", "10m"), 10*time.Minute),
+			SigningSecret: helpers.GetEnv("STORAGE_SIGNING_SECRET", ""),
+			LocalPublic:   helpers.MustBool(helpers.GetEnv("STORAGE_LOCAL_PUBLIC", "false"), false),
 
 			S3Bucket:    helpers.GetEnv("S3_BUCKET", ""),
 			S3Region:    helpers.GetEnv("S3_REGION", ""),
@@ -144,6 +335,57 @@ func Load() (*Config, error) {
 			S3AccessKey: helpers.GetEnv("S3_ACCESS_KEY", ""),
 			S3SecretKey: helpers.GetEnv("S3_SECRET_KEY", ""),
 			S3UsePath:   helpers.MustBool(helpers.GetEnv("S3_USE_PATH_STYLE", "true"), true),
+
+			S3PublicBaseURL: helpers.GetEnv("S3_PUBLIC_BASE_URL", ""),
+			S3PartSizeMB:    helpers.MustInt(helpers.GetEnv("S3_PART_SIZE_MB", "16"), 16),
+
+			TusMaxSizeMB: helpers.MustInt(helpers.GetEnv("TUS_MAX_SIZE_MB", "2048"), 2048),
+			TusExpiry:    helpers.MustDur(helpers.GetEnv("TUS_EXPIRY", "24h"), 24*time.Hour),
+		},
+		Media: MediaConfig{
+			MaxImageMB: helpers.MustInt(helpers.GetEnv("UPLOAD_MAX_IMAGE_MB", "20"), 20),
+			MaxVideoMB: helpers.MustInt(helpers.GetEnv("UPLOAD_MAX_VIDEO_MB", "500"), 500),
+			MaxAudioMB: helpers.MustInt(helpers.GetEnv("UPLOAD_MAX_AUDIO_MB", "100"), 100),
+			ImageTypes: helpers.Csv(helpers.GetEnv("UPLOAD_IMAGE_TYPES", "image/jpeg,image/png,image/gif,image/webp")),
+			VideoTypes: helpers.Csv(helpers.GetEnv("UPLOAD_VIDEO_TYPES", "video/mp4,video/webm,video/quicktime")),
+			AudioTypes: helpers.Csv(helpers.GetEnv("UPLOAD_AUDIO_TYPES", "audio/mpeg,audio/ogg,audio/wave,audio/mp4")),
+
+			ImageVariants: helpers.CsvInts(helpers.GetEnv("UPLOAD_IMAGE_VARIANTS", "160,480,1080")),
+
+			Workers:        helpers.MustInt(helpers.GetEnv("MEDIA_WORKERS", "2"), 2),
+			QueueSize:      helpers.MustInt(helpers.GetEnv("MEDIA_QUEUE_SIZE", "256"), 256),
+			ProcessTimeout: helpers.MustDur(helpers.GetEnv("MEDIA_PROCESS_TIMEOUT", "5m"), 5*time.Minute),
+			SweepInterval:  helpers.MustDur(helpers.GetEnv("MEDIA_SWEEP_INTERVAL", "1m"), time.Minute),
+
+			FFprobePath: helpers.GetEnv("MEDIA_FFPROBE_PATH", "ffprobe"),
+			FFmpegPath:  helpers.GetEnv("MEDIA_FFMPEG_PATH", "ffmpeg"),
+
+			PostMaxImages: helpers.MustInt(helpers.GetEnv("POST_MAX_IMAGES", "4"), 4),
+			PostMaxVideos: helpers.MustInt(helpers.GetEnv("POST_MAX_VIDEOS", "1"), 1),
+			PostMaxAudio:  helpers.MustInt(helpers.GetEnv("POST_MAX_AUDIO", "1"), 1),
+			PostMixKinds:  helpers.MustBool(helpers.GetEnv("POST_MIX_MEDIA_KINDS", "false"), false),
+
+			AltTextMax:    helpers.MustInt(helpers.GetEnv("MEDIA_ALT_TEXT_MAX", "1500"), 1500),
+			AltTextPolicy: helpers.GetEnv("MEDIA_ALT_TEXT_POLICY", "off"),
+
+			GCInterval:        helpers.MustDur(helpers.GetEnv("MEDIA_GC_INTERVAL", "1h"), time.Hour),
+			GCUnattachedAfter: helpers.MustDur(helpers.GetEnv("MEDIA_GC_UNATTACHED_AFTER", "24h"), 24*time.Hour),
+			GCRetention:       helpers.MustDur(helpers.GetEnv("MEDIA_GC_RETENTION", "720h"), 720*time.Hour),
+			GCBatchSize:       helpers.MustInt(helpers.GetEnv("MEDIA_GC_BATCH_SIZE", "500"), 500),
+			GCDryRun:          helpers.MustBool(helpers.GetEnv("MEDIA_GC_DRY_RUN", "false"), false),
+
+			QuotaMB:          helpers.MustInt(helpers.GetEnv("MEDIA_QUOTA_MB", "5120"), 5120),
+			QuotaFilesPerDay: helpers.MustInt(helpers.GetEnv("MEDIA_QUOTA_FILES_PER_DAY", "200"), 200),
+			QuotaRoles:       parseQuotaRoles(helpers.GetEnv("MEDIA_QUOTA_ROLES", "admin:0:0")),
+		},
+		Realtime: RealtimeConfig{
+			Driver:      helpers.GetEnv("REALTIME_DRIVER", "memory"),
+			Heartbeat:   helpers.MustDur(helpers.GetEnv("REALTIME_HEARTBEAT", "25s"), 25*time.Second),
+			ReplayLimit: helpers.MustInt(helpers.GetEnv("REALTIME_REPLAY_LIMIT", "1000"), 1000),
+			Retention:   helpers.MustDur(helpers.GetEnv("REALTIME_RETENTION", "24h"), 24*time.Hour),
+		},
+		Search: SearchConfig{
+			Language: helpers.GetEnv("SEARCH_LANGUAGE", "english"),
 		},
 	}
 
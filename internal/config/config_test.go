@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuotaRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want map[string]MediaQuota
+	}{
+		{"empty", "", map[string]MediaQuota{}},
+		{"one role", "admin:0:0", map[string]MediaQuota{"admin": {}}},
+		{
+			"several roles with spaces",
+			"admin:0:0, pro : 20480 : 1000",
+			map[string]MediaQuota{"admin": {}, "pro": {MB: 20480, FilesPerDay: 1000}},
+		},
+		{"missing field", "pro:100", map[string]MediaQuota{"pro:100": {MB: -1, FilesPerDay: -1}}},
+		{"missing role", ":1:2", map[string]MediaQuota{":1:2": {MB: -1, FilesPerDay: -1}}},
+		{"not a number", "pro:lots:10", map[string]MediaQuota{"pro": {MB: -1, FilesPerDay: 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQuotaRoles(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQuotaRoles(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// loadEnv runs Load on env alone, with an empty .env.
+func loadEnv(t *testing.T, env map[string]string) error {
+	t.Helper()
+
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(".env", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_DSN", "postgres://localhost/test")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.test")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	_, err := Load()
+	return err
+}
+
+func TestLoadSigningSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"unset", map[string]string{"APP_ENV": "dev", "STORAGE_SIGNING_SECRET": ""}, true},
+		{"example in dev", map[string]string{"APP_ENV": "dev", "STORAGE_SIGNING_SECRET": exampleSigningSecret}, false},
+		{"example in prod", map[string]string{"APP_ENV": "prod", "STORAGE_SIGNING_SECRET": exampleSigningSecret}, true},
+		{"own secret in prod", map[string]string{"APP_ENV": "prod", "STORAGE_SIGNING_SECRET": "0f3c9a7e51"}, false},
+		{"s3 needs none", map[string]string{"APP_ENV": "prod", "STORAGE_DRIVER": "s3", "S3_BUCKET": "media", "STORAGE_SIGNING_SECRET": ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadEnv(t, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -85,6 +85,25 @@ func (s *SQL) Version(ctx context.Context) (string, error) {
 	return v, nil
 }
 
+// InTx runs fn with queries bound to a single transaction, committing when fn
+// returns nil and rolling back otherwise.
+func (s *SQL) InTx(ctx context.Context, fn func(q *dbgen.Queries) error) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db begin: %w", err)
+	}
+
+	if err := fn(s.Q.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db commit: %w", err)
+	}
+	return nil
+}
+
 func (s *SQL) Close() error {
 	return s.DB.Close()
 }
@@ -8,44 +8,101 @@ package dbgen
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
-const attachMediaToPost = `-- name: AttachMediaToPost :exec
-INSERT INTO post_media (post_id, media_id, position)
-VALUES ($1, $2, $3)
-ON CONFLICT (post_id, media_id)
-DO UPDATE SET position = EXCLUDED.position
+const attachMediaToPost = `-- name: AttachMediaToPost :execrows
+insert into post_media (post_id, media_id, position)
+select p.id, $1, coalesce(max(pm.position) + 1, 0)
+from posts p
+left join post_media pm
+on pm.post_id = p.id
+where p.id = $2
+and p.user_id = $3
+and p.deleted_at is null
+group by p.id
+on conflict (post_id, media_id)
+do update set position = post_media.position
 `
 
 type AttachMediaToPostParams struct {
-	PostID   int64
-	MediaID  int64
-	Position int32
+	MediaID int64
+	PostID  int64
+	UserID  int64
 }
 
-func (q *Queries) AttachMediaToPost(ctx context.Context, arg AttachMediaToPostParams) error {
-	_, err := q.db.ExecContext(ctx, attachMediaToPost, arg.PostID, arg.MediaID, arg.Position)
-	return err
+func (q *Queries) AttachMediaToPost(ctx context.Context, arg AttachMediaToPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, attachMediaToPost, arg.MediaID, arg.PostID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimMediaForProcessing = `-- name: ClaimMediaForProcessing :one
+update media
+set locked_until = $1
+where id = $2
+and status = 'processing'
+and deleted_at is null
+and (locked_until is null or locked_until < now())
+returning media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at, media.status, media.locked_until, media.video_codec, media.audio_codec, media.poster_key, media.blurhash, media.dominant_color, media.content_hash, media.alt_text
+`
+
+type ClaimMediaForProcessingParams struct {
+	LockedUntil *time.Time
+	ID          int64
+}
+
+func (q *Queries) ClaimMediaForProcessing(ctx context.Context, arg ClaimMediaForProcessingParams) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, claimMediaForProcessing, arg.LockedUntil, arg.ID)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Kind,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.DurationMs,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Status,
+		&i.LockedUntil,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.PosterKey,
+		&i.Blurhash,
+		&i.DominantColor,
+		&i.ContentHash,
+		&i.AltText,
+	)
+	return i, err
 }
 
 const createMedia = `-- name: CreateMedia :one
 INSERT INTO media (
-  owner_id, kind, storage_key, mime_type, size_bytes, width, height, duration_ms
+  owner_id, kind, storage_key, mime_type, size_bytes, width, height, duration_ms, status, content_hash, alt_text
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 )
-RETURNING media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at
+RETURNING media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at, media.status, media.locked_until, media.video_codec, media.audio_codec, media.poster_key, media.blurhash, media.dominant_color, media.content_hash, media.alt_text
 `
 
 type CreateMediaParams struct {
-	OwnerID    int64
-	Kind       string
-	StorageKey string
-	MimeType   string
-	SizeBytes  int64
-	Width      sql.NullInt32
-	Height     sql.NullInt32
-	DurationMs sql.NullInt32
+	OwnerID     int64
+	Kind        string
+	StorageKey  string
+	MimeType    string
+	SizeBytes   int64
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	DurationMs  sql.NullInt32
+	Status      string
+	ContentHash sql.NullString
+	AltText     sql.NullString
 }
 
 func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error) {
@@ -58,7 +115,338 @@ func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Mediu
 		arg.Width,
 		arg.Height,
 		arg.DurationMs,
+		arg.Status,
+		arg.ContentHash,
+		arg.AltText,
+	)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Kind,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.DurationMs,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Status,
+		&i.LockedUntil,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.PosterKey,
+		&i.Blurhash,
+		&i.DominantColor,
+		&i.ContentHash,
+		&i.AltText,
+	)
+	return i, err
+}
+
+const finishMediaProcessing = `-- name: FinishMediaProcessing :exec
+update media
+set status = $1,
+  width = $2,
+  height = $3,
+  duration_ms = $4,
+  video_codec = $5,
+  audio_codec = $6,
+  poster_key = $7,
+  blurhash = $8,
+  dominant_color = $9,
+  locked_until = null
+where id = $10
+`
+
+type FinishMediaProcessingParams struct {
+	Status        string
+	Width         sql.NullInt32
+	Height        sql.NullInt32
+	DurationMs    sql.NullInt32
+	VideoCodec    sql.NullString
+	AudioCodec    sql.NullString
+	PosterKey     sql.NullString
+	Blurhash      sql.NullString
+	DominantColor sql.NullString
+	ID            int64
+}
+
+func (q *Queries) FinishMediaProcessing(ctx context.Context, arg FinishMediaProcessingParams) error {
+	_, err := q.db.ExecContext(ctx, finishMediaProcessing,
+		arg.Status,
+		arg.Width,
+		arg.Height,
+		arg.DurationMs,
+		arg.VideoCodec,
+		arg.AudioCodec,
+		arg.PosterKey,
+		arg.Blurhash,
+		arg.DominantColor,
+		arg.ID,
+	)
+	return err
+}
+
+const getMediaUsage = `-- name: GetMediaUsage :one
+with live_objects as (
+  select distinct m.storage_key, coalesce(o.size_bytes, m.size_bytes) as size_bytes
+  from media m
+  left join media_objects o on o.storage_key = m.storage_key
+  where m.owner_id = $1
+  and m.deleted_at is null
+)
+select coalesce((select sum(size_bytes) from live_objects), 0)::bigint as total_bytes,
+  (
+    select count(*) from media
+    where owner_id = $1
+    and created_at >= $2
+  )::bigint as files_since
+`
+
+type GetMediaUsageParams struct {
+	OwnerID int64
+	Since   time.Time
+}
+
+type GetMediaUsageRow struct {
+	TotalBytes int64
+	FilesSince int64
+}
+
+func (q *Queries) GetMediaUsage(ctx context.Context, arg GetMediaUsageParams) (GetMediaUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getMediaUsage, arg.OwnerID, arg.Since)
+	var i GetMediaUsageRow
+	err := row.Scan(&i.TotalBytes, &i.FilesSince)
+	return i, err
+}
+
+const getServedObject = `-- name: GetServedObject :one
+select mime_type, content_hash
+from media
+where storage_key = $1
+and deleted_at is null
+union all
+select v.mime_type, null::text
+from media_variants v
+join media m on m.id = v.media_id
+where v.storage_key = $1
+and m.deleted_at is null
+union all
+select 'image/jpeg', null::text
+from media
+where poster_key = $1
+and deleted_at is null
+limit 1
+`
+
+type GetServedObjectRow struct {
+	MimeType    string
+	ContentHash sql.NullString
+}
+
+func (q *Queries) GetServedObject(ctx context.Context, storageKey string) (GetServedObjectRow, error) {
+	row := q.db.QueryRowContext(ctx, getServedObject, storageKey)
+	var i GetServedObjectRow
+	err := row.Scan(&i.MimeType, &i.ContentHash)
+	return i, err
+}
+
+const listMediaByOwner = `-- name: ListMediaByOwner :many
+select media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at, media.status, media.locked_until, media.video_codec, media.audio_codec, media.poster_key, media.blurhash, media.dominant_color, media.content_hash, media.alt_text
+from media
+where owner_id = $1
+and deleted_at is null
+and ($2::text is null or kind = $2)
+order by created_at desc, id desc
+limit $3 offset $4
+`
+
+type ListMediaByOwnerParams struct {
+	OwnerID int64
+	Kind    sql.NullString
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) ListMediaByOwner(ctx context.Context, arg ListMediaByOwnerParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaByOwner,
+		arg.OwnerID,
+		arg.Kind,
+		arg.Limit,
+		arg.Offset,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Kind,
+			&i.StorageKey,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.DurationMs,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Status,
+			&i.LockedUntil,
+			&i.VideoCodec,
+			&i.AudioCodec,
+			&i.PosterKey,
+			&i.Blurhash,
+			&i.DominantColor,
+			&i.ContentHash,
+			&i.AltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnedMediaByIDs = `-- name: ListOwnedMediaByIDs :many
+select media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at, media.status, media.locked_until, media.video_codec, media.audio_codec, media.poster_key, media.blurhash, media.dominant_color, media.content_hash, media.alt_text
+from media
+where id = any($1::bigint[])
+and owner_id = $2
+and deleted_at is null
+`
+
+type ListOwnedMediaByIDsParams struct {
+	Ids     []int64
+	OwnerID int64
+}
+
+func (q *Queries) ListOwnedMediaByIDs(ctx context.Context, arg ListOwnedMediaByIDsParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, listOwnedMediaByIDs, arg.Ids, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Kind,
+			&i.StorageKey,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.DurationMs,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Status,
+			&i.LockedUntil,
+			&i.VideoCodec,
+			&i.AudioCodec,
+			&i.PosterKey,
+			&i.Blurhash,
+			&i.DominantColor,
+			&i.ContentHash,
+			&i.AltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingMediaIDs = `-- name: ListPendingMediaIDs :many
+select id
+from media
+where status = 'processing'
+and deleted_at is null
+and (locked_until is null or locked_until < now())
+order by id
+limit $1
+`
+
+func (q *Queries) ListPendingMediaIDs(ctx context.Context, limit int32) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingMediaIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteMedia = `-- name: SoftDeleteMedia :execrows
+update media
+set deleted_at = now()
+where id = $1
+and owner_id = $2
+and deleted_at is null
+`
+
+type SoftDeleteMediaParams struct {
+	ID      int64
+	OwnerID int64
+}
+
+func (q *Queries) SoftDeleteMedia(ctx context.Context, arg SoftDeleteMediaParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteMedia, arg.ID, arg.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateMediaAltText = `-- name: UpdateMediaAltText :one
+update media
+set alt_text = $1
+where id = $2
+and owner_id = $3
+and deleted_at is null
+returning media.id, media.owner_id, media.kind, media.storage_key, media.mime_type, media.size_bytes, media.width, media.height, media.duration_ms, media.created_at, media.deleted_at, media.status, media.locked_until, media.video_codec, media.audio_codec, media.poster_key, media.blurhash, media.dominant_color, media.content_hash, media.alt_text
+`
+
+type UpdateMediaAltTextParams struct {
+	AltText sql.NullString
+	ID      int64
+	OwnerID int64
+}
+
+func (q *Queries) UpdateMediaAltText(ctx context.Context, arg UpdateMediaAltTextParams) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, updateMediaAltText, arg.AltText, arg.ID, arg.OwnerID)
 	var i Medium
 	err := row.Scan(
 		&i.ID,
@@ -72,6 +460,15 @@ func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Mediu
 		&i.DurationMs,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Status,
+		&i.LockedUntil,
+		&i.VideoCodec,
+		&i.AudioCodec,
+		&i.PosterKey,
+		&i.Blurhash,
+		&i.DominantColor,
+		&i.ContentHash,
+		&i.AltText,
 	)
 	return i, err
 }
@@ -6,21 +6,134 @@ package dbgen
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
-type Medium struct {
-	ID         int64
-	OwnerID    int64
-	Kind       string
+type Conversation struct {
+	ID             int64
+	Kind           string
+	Title          sql.NullString
+	CreatedBy      int64
+	LastActivityAt time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ConversationParticipant struct {
+	ConversationID    int64
+	UserID            int64
+	LastReadMessageID sql.NullInt64
+	JoinedAt          time.Time
+}
+
+type DirectConversation struct {
+	ConversationID int64
+	UserA          int64
+	UserB          int64
+}
+
+type Follow struct {
+	FollowerID int64
+	FolloweeID int64
+	Status     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type MediaObject struct {
+	StorageKey  string
+	OwnerID     int64
+	ContentHash string
+	SizeBytes   int64
+	RefCount    int32
+	CreatedAt   time.Time
+}
+
+type MediaUpload struct {
+	ID          int64
+	OwnerID     int64
+	Kind        string
+	StorageKey  string
+	MimeType    string
+	SizeBytes   int64
+	MediaID     sql.NullInt64
+	ExpiresAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	AltText     sql.NullString
+}
+
+type MediaVariant struct {
+	MediaID    int64
+	Width      int32
+	Height     int32
 	StorageKey string
 	MimeType   string
 	SizeBytes  int64
-	Width      sql.NullInt32
-	Height     sql.NullInt32
-	DurationMs sql.NullInt32
 	CreatedAt  time.Time
-	DeletedAt  *time.Time
+}
+
+type Medium struct {
+	ID            int64
+	OwnerID       int64
+	Kind          string
+	StorageKey    string
+	MimeType      string
+	SizeBytes     int64
+	Width         sql.NullInt32
+	Height        sql.NullInt32
+	DurationMs    sql.NullInt32
+	CreatedAt     time.Time
+	DeletedAt     *time.Time
+	Status        string
+	LockedUntil   *time.Time
+	VideoCodec    sql.NullString
+	AudioCodec    sql.NullString
+	PosterKey     sql.NullString
+	Blurhash      sql.NullString
+	DominantColor sql.NullString
+	ContentHash   sql.NullString
+	AltText       sql.NullString
+}
+
+type Message struct {
+	ID             int64
+	ConversationID int64
+	SenderID       int64
+	Body           string
+	CreatedAt      time.Time
+	DeletedAt      *time.Time
+}
+
+type MessageMedium struct {
+	MessageID int64
+	MediaID   int64
+	Position  int32
+}
+
+type Notification struct {
+	ID         int64
+	UserID     int64
+	ActorID    int64
+	Type       string
+	EntityID   sql.NullInt64
+	GroupKey   string
+	ActorCount int32
+	ReadAt     *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type NotificationActor struct {
+	NotificationID int64
+	ActorID        int64
+}
+
+type NotificationPreference struct {
+	UserID  int64
+	Type    string
+	Enabled bool
 }
 
 type Post struct {
@@ -31,6 +144,7 @@ type Post struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   *time.Time
+	Visibility  string
 }
 
 type PostMedium struct {
@@ -39,6 +153,53 @@ type PostMedium struct {
 	Position int32
 }
 
+type PostMention struct {
+	PostID int64
+	UserID int64
+}
+
+type PostSearchDocument struct {
+	PostID   int64
+	Document interface{}
+}
+
+type RealtimeEvent struct {
+	ID        int64
+	Topic     string
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+type ResumableUpload struct {
+	ID           string
+	OwnerID      int64
+	Filename     string
+	MimeType     string
+	UploadLength int64
+	UploadOffset int64
+	Metadata     string
+	MediaID      sql.NullInt64
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	AltText      sql.NullString
+	FinishingAt  *time.Time
+}
+
+type ResumableUploadPart struct {
+	UploadID   string
+	PartOffset int64
+	StorageKey string
+	SizeBytes  int64
+	CreatedAt  time.Time
+}
+
+type SearchSetting struct {
+	ID     bool
+	Config interface{}
+}
+
 type User struct {
 	ID           int64
 	Username     string
@@ -47,4 +208,18 @@ type User struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	DeletedAt    *time.Time
+	IsPrivate    bool
+	DisplayName  sql.NullString
+}
+
+type UserBlock struct {
+	BlockerID int64
+	BlockedID int64
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   int64
+	MutedID   int64
+	CreatedAt time.Time
 }
@@ -12,19 +12,20 @@ import (
 )
 
 const createPost = `-- name: CreatePost :one
-insert into posts (title, description, user_id)
-values ($1, $2, $3)
-returning posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at
+insert into posts (title, description, user_id, visibility)
+values ($1, $2, $3, $4)
+returning posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at, posts.visibility
 `
 
 type CreatePostParams struct {
 	Title       string
 	Description string
 	UserID      int64
+	Visibility  string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost, arg.Title, arg.Description, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createPost, arg.Title, arg.Description, arg.UserID, arg.Visibility)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -34,27 +35,132 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
 	)
 	return i, err
 }
 
-const getAllPostsByUser = `-- name: GetAllPostsByUser :many
-select posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at
-from posts
-where user_id = $1
-and deleted_at is null
-order by created_at desc, id desc
+const createPostMentions = `-- name: CreatePostMentions :exec
+insert into post_mentions (post_id, user_id)
+select $1, unnest($2::bigint[])
+on conflict (post_id, user_id) do nothing
+`
+
+type CreatePostMentionsParams struct {
+	PostID  int64
+	UserIds []int64
+}
+
+func (q *Queries) CreatePostMentions(ctx context.Context, arg CreatePostMentionsParams) error {
+	_, err := q.db.ExecContext(ctx, createPostMentions, arg.PostID, arg.UserIds)
+	return err
+}
+
+const deletePostMentions = `-- name: DeletePostMentions :exec
+delete from post_mentions
+where post_id = $1
+`
+
+func (q *Queries) DeletePostMentions(ctx context.Context, postID int64) error {
+	_, err := q.db.ExecContext(ctx, deletePostMentions, postID)
+	return err
+}
+
+const detachPostMedia = `-- name: DetachPostMedia :execrows
+delete from post_media pm
+using posts p
+where p.id = pm.post_id
+and pm.post_id = $1
+and pm.media_id = $2
+and p.user_id = $3
+and p.deleted_at is null
+`
+
+type DetachPostMediaParams struct {
+	PostID  int64
+	MediaID int64
+	UserID  int64
+}
+
+func (q *Queries) DetachPostMedia(ctx context.Context, arg DetachPostMediaParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, detachPostMedia, arg.PostID, arg.MediaID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostWithMedia = `-- name: GetPostWithMedia :many
+select p.id, p.title, p.description, p.user_id, p.created_at, p.updated_at, p.deleted_at, p.visibility,
+  m.id          AS media_id,
+  m.kind        AS media_kind,
+  m.mime_type   AS media_mime_type,
+  m.storage_key AS media_storage_key,
+  m.width       AS media_width,
+  m.height      AS media_height,
+  m.duration_ms AS media_duration_ms,
+  m.status      AS media_status,
+  m.video_codec AS media_video_codec,
+  m.audio_codec AS media_audio_codec,
+  m.poster_key  AS media_poster_key,
+  m.blurhash    AS media_blurhash,
+  m.dominant_color AS media_dominant_color,
+  m.content_hash AS media_content_hash,
+  m.alt_text    AS media_alt_text,
+  pm.position   AS media_position
+from posts p 
+left join post_media pm
+on pm.post_id = p.id
+left join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where p.id = $1
+and p.deleted_at is null
+and post_visible_to(p.id, p.user_id, p.visibility, $2::bigint, true)
+order by pm.position asc, m.created_at asc, m.id asc
 `
 
-func (q *Queries) GetAllPostsByUser(ctx context.Context, userID int64) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getAllPostsByUser, userID)
+type GetPostWithMediaParams struct {
+	ID       int64
+	ViewerID sql.NullInt64
+}
+
+type GetPostWithMediaRow struct {
+	ID                 int64
+	Title              string
+	Description        string
+	UserID             int64
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          *time.Time
+	Visibility         string
+	MediaID            sql.NullInt64
+	MediaKind          sql.NullString
+	MediaMimeType      sql.NullString
+	MediaStorageKey    sql.NullString
+	MediaWidth         sql.NullInt32
+	MediaHeight        sql.NullInt32
+	MediaDurationMs    sql.NullInt32
+	MediaStatus        sql.NullString
+	MediaVideoCodec    sql.NullString
+	MediaAudioCodec    sql.NullString
+	MediaPosterKey     sql.NullString
+	MediaBlurhash      sql.NullString
+	MediaDominantColor sql.NullString
+	MediaContentHash   sql.NullString
+	MediaAltText       sql.NullString
+	MediaPosition      sql.NullInt32
+}
+
+func (q *Queries) GetPostWithMedia(ctx context.Context, arg GetPostWithMediaParams) ([]GetPostWithMediaRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostWithMedia, arg.ID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostWithMediaRow
 	for rows.Next() {
-		var i Post
+		var i GetPostWithMediaRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
@@ -63,6 +169,23 @@ func (q *Queries) GetAllPostsByUser(ctx context.Context, userID int64) ([]Post,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.Visibility,
+			&i.MediaID,
+			&i.MediaKind,
+			&i.MediaMimeType,
+			&i.MediaStorageKey,
+			&i.MediaWidth,
+			&i.MediaHeight,
+			&i.MediaDurationMs,
+			&i.MediaStatus,
+			&i.MediaVideoCodec,
+			&i.MediaAudioCodec,
+			&i.MediaPosterKey,
+			&i.MediaBlurhash,
+			&i.MediaDominantColor,
+			&i.MediaContentHash,
+			&i.MediaAltText,
+			&i.MediaPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -77,40 +200,90 @@ func (q *Queries) GetAllPostsByUser(ctx context.Context, userID int64) ([]Post,
 	return items, nil
 }
 
-const listPostsPaginated = `-- name: ListPostsPaginated :many
-select posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at
-from posts
-where deleted_at is null
-order by created_at desc, id desc
-limit $1 offset $2
+const listPostMediaIDs = `-- name: ListPostMediaIDs :many
+select pm.media_id
+from post_media pm
+join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where pm.post_id = $1
+order by pm.position asc, m.created_at asc, m.id asc
 `
 
-type ListPostsPaginatedParams struct {
-	Limit  int32
-	Offset int32
+func (q *Queries) ListPostMediaIDs(ctx context.Context, postID int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listPostMediaIDs, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var media_id int64
+		if err := rows.Scan(&media_id); err != nil {
+			return nil, err
+		}
+		items = append(items, media_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListPostsPaginated(ctx context.Context, arg ListPostsPaginatedParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, listPostsPaginated, arg.Limit, arg.Offset)
+const listPostMediaKinds = `-- name: ListPostMediaKinds :many
+select m.kind
+from post_media pm
+join media m
+on m.id = pm.media_id
+and m.deleted_at is null
+where pm.post_id = $1
+`
+
+func (q *Queries) ListPostMediaKinds(ctx context.Context, postID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listPostMediaKinds, postID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []string
 	for rows.Next() {
-		var i Post
-		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Description,
-			&i.UserID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DeletedAt,
-		); err != nil {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, kind)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostMentionIDs = `-- name: ListPostMentionIDs :many
+select user_id
+from post_mentions
+where post_id = $1
+`
+
+func (q *Queries) ListPostMentionIDs(ctx context.Context, postID int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listPostMentionIDs, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -122,7 +295,7 @@ func (q *Queries) ListPostsPaginated(ctx context.Context, arg ListPostsPaginated
 }
 
 const listPostsWithMediaPaginated = `-- name: ListPostsWithMediaPaginated :many
-select p.id, p.title, p.description, p.user_id, p.created_at, p.updated_at, p.deleted_at,
+select p.id, p.title, p.description, p.user_id, p.created_at, p.updated_at, p.deleted_at, p.visibility,
   m.id          AS media_id,
   m.kind        AS media_kind,
   m.mime_type   AS media_mime_type,
@@ -130,6 +303,14 @@ select p.id, p.title, p.description, p.user_id, p.created_at, p.updated_at, p.de
   m.width       AS media_width,
   m.height      AS media_height,
   m.duration_ms AS media_duration_ms,
+  m.status      AS media_status,
+  m.video_codec AS media_video_codec,
+  m.audio_codec AS media_audio_codec,
+  m.poster_key  AS media_poster_key,
+  m.blurhash    AS media_blurhash,
+  m.dominant_color AS media_dominant_color,
+  m.content_hash AS media_content_hash,
+  m.alt_text    AS media_alt_text,
   pm.position   AS media_position
 from posts p 
 left join post_media pm
@@ -139,38 +320,64 @@ on m.id = pm.media_id
 and m.deleted_at is null
 where p.deleted_at is null
 and  ($1::bigint IS NULL OR p.user_id = $1::bigint)
+-- Unlisted posts only show on their author's profile.
+and post_visible_to(p.id, p.user_id, p.visibility, $2::bigint, $1::bigint IS NOT NULL)
+and (
+  $2::bigint IS NULL
+  OR $1::bigint IS NOT NULL
+  OR NOT EXISTS (
+    select 1 from user_mutes mu
+    where mu.muter_id = $2::bigint
+    and mu.muted_id = p.user_id
+  )
+)
 order by p.created_at desc, p.id desc,
 pm.position asc, m.created_at asc, m.id asc
 limit $3 
-offset $2
+offset $4
 `
 
 type ListPostsWithMediaPaginatedParams struct {
-	UserID sql.NullInt64
-	Offset int32
-	Limit  int32
+	UserID   sql.NullInt64
+	Offset   int32
+	Limit    int32
+	ViewerID sql.NullInt64
 }
 
 type ListPostsWithMediaPaginatedRow struct {
-	ID              int64
-	Title           string
-	Description     string
-	UserID          int64
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
-	DeletedAt       *time.Time
-	MediaID         sql.NullInt64
-	MediaKind       sql.NullString
-	MediaMimeType   sql.NullString
-	MediaStorageKey sql.NullString
-	MediaWidth      sql.NullInt32
-	MediaHeight     sql.NullInt32
-	MediaDurationMs sql.NullInt32
-	MediaPosition   sql.NullInt32
+	ID                 int64
+	Title              string
+	Description        string
+	UserID             int64
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          *time.Time
+	Visibility         string
+	MediaID            sql.NullInt64
+	MediaKind          sql.NullString
+	MediaMimeType      sql.NullString
+	MediaStorageKey    sql.NullString
+	MediaWidth         sql.NullInt32
+	MediaHeight        sql.NullInt32
+	MediaDurationMs    sql.NullInt32
+	MediaStatus        sql.NullString
+	MediaVideoCodec    sql.NullString
+	MediaAudioCodec    sql.NullString
+	MediaPosterKey     sql.NullString
+	MediaBlurhash      sql.NullString
+	MediaDominantColor sql.NullString
+	MediaContentHash   sql.NullString
+	MediaAltText       sql.NullString
+	MediaPosition      sql.NullInt32
 }
 
 func (q *Queries) ListPostsWithMediaPaginated(ctx context.Context, arg ListPostsWithMediaPaginatedParams) ([]ListPostsWithMediaPaginatedRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPostsWithMediaPaginated, arg.UserID, arg.Offset, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, listPostsWithMediaPaginated,
+		arg.UserID,
+		arg.Offset,
+		arg.Limit,
+		arg.ViewerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -186,6 +393,7 @@ func (q *Queries) ListPostsWithMediaPaginated(ctx context.Context, arg ListPosts
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.Visibility,
 			&i.MediaID,
 			&i.MediaKind,
 			&i.MediaMimeType,
@@ -193,6 +401,14 @@ func (q *Queries) ListPostsWithMediaPaginated(ctx context.Context, arg ListPosts
 			&i.MediaWidth,
 			&i.MediaHeight,
 			&i.MediaDurationMs,
+			&i.MediaStatus,
+			&i.MediaVideoCodec,
+			&i.MediaAudioCodec,
+			&i.MediaPosterKey,
+			&i.MediaBlurhash,
+			&i.MediaDominantColor,
+			&i.MediaContentHash,
+			&i.MediaAltText,
 			&i.MediaPosition,
 		); err != nil {
 			return nil, err
@@ -208,34 +424,91 @@ func (q *Queries) ListPostsWithMediaPaginated(ctx context.Context, arg ListPosts
 	return items, nil
 }
 
-const softDeletePost = `-- name: SoftDeletePost :exec
+const lockOwnedPost = `-- name: LockOwnedPost :one
+select id
+from posts
+where id = $1
+and user_id = $2
+and deleted_at is null
+for update
+`
+
+type LockOwnedPostParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) LockOwnedPost(ctx context.Context, arg LockOwnedPostParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, lockOwnedPost, arg.ID, arg.UserID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const setPostMediaPositions = `-- name: SetPostMediaPositions :exec
+update post_media pm
+set position = o.ord - 1
+from unnest($1::bigint[]) with ordinality as o(media_id, ord)
+where pm.post_id = $2
+and pm.media_id = o.media_id
+`
+
+type SetPostMediaPositionsParams struct {
+	MediaIds []int64
+	PostID   int64
+}
+
+func (q *Queries) SetPostMediaPositions(ctx context.Context, arg SetPostMediaPositionsParams) error {
+	_, err := q.db.ExecContext(ctx, setPostMediaPositions, arg.MediaIds, arg.PostID)
+	return err
+}
+
+const softDeletePost = `-- name: SoftDeletePost :execrows
 update posts
 set deleted_at = now()
-where id = $1 and deleted_at is null
+where id = $1 and user_id = $2 and deleted_at is null
 `
 
-func (q *Queries) SoftDeletePost(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, softDeletePost, id)
-	return err
+type SoftDeletePostParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) SoftDeletePost(ctx context.Context, arg SoftDeletePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeletePost, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updatePostPartial = `-- name: UpdatePostPartial :one
 update posts
 set title = coalesce($1, title),
-description = coalesce ($2, description)
-where id = $3
+description = coalesce ($2, description),
+visibility = coalesce($3, visibility)
+where id = $4
+and user_id = $5
 and deleted_at is null
-returning posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at
+returning posts.id, posts.title, posts.description, posts.user_id, posts.created_at, posts.updated_at, posts.deleted_at, posts.visibility
 `
 
 type UpdatePostPartialParams struct {
 	Title       sql.NullString
 	Description sql.NullString
+	Visibility  sql.NullString
 	ID          int64
+	UserID      int64
 }
 
 func (q *Queries) UpdatePostPartial(ctx context.Context, arg UpdatePostPartialParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, updatePostPartial, arg.Title, arg.Description, arg.ID)
+	row := q.db.QueryRowContext(ctx, updatePostPartial,
+		arg.Title,
+		arg.Description,
+		arg.Visibility,
+		arg.ID,
+		arg.UserID,
+	)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -245,6 +518,7 @@ func (q *Queries) UpdatePostPartial(ctx context.Context, arg UpdatePostPartialPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
 	)
 	return i, err
 }
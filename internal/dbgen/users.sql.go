@@ -7,12 +7,13 @@ package dbgen
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createUser = `-- name: CreateUser :one
 insert into users (email, username, password_hash)
 values ($1, $2, $3)
-returning users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at
+returning users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name
 `
 
 type CreateUserParams struct {
@@ -32,6 +33,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
 	)
 	return i, err
 }
@@ -59,7 +62,7 @@ func (q *Queries) ExistsUserByUsername(ctx context.Context, username string) (bo
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at
+select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name
 from users
 where email = $1 and deleted_at is null
 limit 1
@@ -76,12 +79,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at 
+select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name 
 from users
 where id = $1
 limit 1
@@ -98,12 +103,14 @@ func (q *Queries) GetUserById(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at
+select users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name
 from users
 where username = $1 and deleted_at is null
 limit 1
@@ -120,6 +127,66 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
+	)
+	return i, err
+}
+
+const updateUserPrivacy = `-- name: UpdateUserPrivacy :one
+update users
+set is_private = $2
+where id = $1 and deleted_at is null
+returning users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name
+`
+
+type UpdateUserPrivacyParams struct {
+	ID        int64
+	IsPrivate bool
+}
+
+func (q *Queries) UpdateUserPrivacy(ctx context.Context, arg UpdateUserPrivacyParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPrivacy, arg.ID, arg.IsPrivate)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
+	)
+	return i, err
+}
+
+const updateUserDisplayName = `-- name: UpdateUserDisplayName :one
+update users
+set display_name = $2
+where id = $1 and deleted_at is null
+returning users.id, users.username, users.email, users.password_hash, users.created_at, users.updated_at, users.deleted_at, users.is_private, users.display_name
+`
+
+type UpdateUserDisplayNameParams struct {
+	ID          int64
+	DisplayName sql.NullString
+}
+
+func (q *Queries) UpdateUserDisplayName(ctx context.Context, arg UpdateUserDisplayNameParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserDisplayName, arg.ID, arg.DisplayName)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsPrivate,
+		&i.DisplayName,
 	)
 	return i, err
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type BlockHandler struct {
+	svc services.BlockService
+}
+
+func NewBlockHandler(svc services.BlockService) *BlockHandler {
+	return &BlockHandler{svc: svc}
+}
+
+type targetUserReq struct {
+	UserID int64 `json:"user_id"`
+}
+
+func (h *BlockHandler) writeErr(w http.ResponseWriter, r *http.Request, err error, code, msg string) {
+	switch {
+	case errors.Is(err, services.ErrSelfTarget):
+		resp.Error(w, r, http.StatusBadRequest, "SELF_TARGET", err.Error())
+	case errors.Is(err, repositories.ErrUserNotFound):
+		resp.Error(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found")
+	case errors.Is(err, repositories.ErrBlockNotFound), errors.Is(err, repositories.ErrMuteNotFound):
+		resp.Error(w, r, http.StatusNotFound, "NOT_FOUND", err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, code, msg)
+	}
+}
+
+func decodeTarget(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	var req targetUserReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return 0, false
+	}
+	if req.UserID <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "MISSING_FIELDS", "user_id is required")
+		return 0, false
+	}
+	return req.UserID, true
+}
+
+func targetFromURL(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_USER_ID", "invalid user id")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *BlockHandler) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	offset := helpers.ParseInt(r.URL.Query().Get("offset"), 0, 1_000_000)
+
+	items, err := h.svc.ListBlocks(r.Context(), userID, int32(limit), int32(offset))
+	if err != nil {
+		h.writeErr(w, r, err, "LIST_BLOCKS_FAIL", "cannot list blocks")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "offset": offset},
+	})
+}
+
+func (h *BlockHandler) Block(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := decodeTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Block(r.Context(), userID, targetID); err != nil {
+		h.writeErr(w, r, err, "BLOCK_FAIL", "cannot block user")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"blocked": true})
+}
+
+func (h *BlockHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Unblock(r.Context(), userID, targetID); err != nil {
+		h.writeErr(w, r, err, "UNBLOCK_FAIL", "cannot unblock user")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"blocked": false})
+}
+
+func (h *BlockHandler) ListMutes(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	offset := helpers.ParseInt(r.URL.Query().Get("offset"), 0, 1_000_000)
+
+	items, err := h.svc.ListMutes(r.Context(), userID, int32(limit), int32(offset))
+	if err != nil {
+		h.writeErr(w, r, err, "LIST_MUTES_FAIL", "cannot list mutes")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "offset": offset},
+	})
+}
+
+func (h *BlockHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := decodeTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Mute(r.Context(), userID, targetID); err != nil {
+		h.writeErr(w, r, err, "MUTE_FAIL", "cannot mute user")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"muted": true})
+}
+
+func (h *BlockHandler) Unmute(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Unmute(r.Context(), userID, targetID); err != nil {
+		h.writeErr(w, r, err, "UNMUTE_FAIL", "cannot unmute user")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"muted": false})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+)
+
+type FollowHandler struct {
+	svc services.FollowService
+}
+
+func NewFollowHandler(svc services.FollowService) *FollowHandler {
+	return &FollowHandler{svc: svc}
+}
+
+type updateSettingsReq struct {
+	IsPrivate *bool `json:"is_private"`
+}
+
+func (h *FollowHandler) writeErr(w http.ResponseWriter, r *http.Request, err error, code, msg string) {
+	switch {
+	case errors.Is(err, services.ErrSelfTarget):
+		resp.Error(w, r, http.StatusBadRequest, "SELF_TARGET", err.Error())
+	case errors.Is(err, services.ErrBlocked):
+		resp.Error(w, r, http.StatusForbidden, "BLOCKED", err.Error())
+	case errors.Is(err, repositories.ErrUserNotFound):
+		resp.Error(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found")
+	case errors.Is(err, repositories.ErrFollowNotFound), errors.Is(err, repositories.ErrFollowRequestNotFound):
+		resp.Error(w, r, http.StatusNotFound, "NOT_FOUND", err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, code, msg)
+	}
+}
+
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	st, err := h.svc.Follow(r.Context(), userID, targetID)
+	if err != nil {
+		h.writeErr(w, r, err, "FOLLOW_FAIL", "cannot follow user")
+		return
+	}
+	resp.OK(w, r, st)
+}
+
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	targetID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Unfollow(r.Context(), userID, targetID); err != nil {
+		h.writeErr(w, r, err, "UNFOLLOW_FAIL", "cannot unfollow user")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"following": false})
+}
+
+func (h *FollowHandler) ListRequests(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	offset := helpers.ParseInt(r.URL.Query().Get("offset"), 0, 1_000_000)
+
+	items, err := h.svc.ListRequests(r.Context(), userID, int32(limit), int32(offset))
+	if err != nil {
+		h.writeErr(w, r, err, "LIST_FOLLOW_REQUESTS_FAIL", "cannot list follow requests")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "offset": offset},
+	})
+}
+
+func (h *FollowHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	requesterID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Approve(r.Context(), userID, requesterID); err != nil {
+		h.writeErr(w, r, err, "APPROVE_FAIL", "cannot approve follow request")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"approved": true})
+}
+
+func (h *FollowHandler) Deny(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	requesterID, ok := targetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Deny(r.Context(), userID, requesterID); err != nil {
+		h.writeErr(w, r, err, "DENY_FAIL", "cannot deny follow request")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"approved": false})
+}
+
+func (h *FollowHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	var req updateSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+	if req.IsPrivate == nil {
+		resp.Error(w, r, http.StatusBadRequest, "MISSING_FIELDS", "is_private is required")
+		return
+	}
+
+	usr, err := h.svc.SetPrivate(r.Context(), userID, *req.IsPrivate)
+	if err != nil {
+		h.writeErr(w, r, err, "UPDATE_SETTINGS_FAIL", "cannot update settings")
+		return
+	}
+	resp.OK(w, r, usr)
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
 	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
 	"go-rest-chi/internal/resp"
 	"go-rest-chi/internal/services"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -19,24 +24,205 @@ func NewMediaHandler(svc services.MediaService) *MediaHandler {
 	return &MediaHandler{svc: svc}
 }
 
-func (h *MediaHandler) UploadPostMedia(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(200 << 20); err != nil {
+type createUploadReq struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	AltText  string `json:"alt_text"`
+}
+
+type updateMediaReq struct {
+	AltText string `json:"alt_text"`
+}
+
+func (h *MediaHandler) writeErr(w http.ResponseWriter, r *http.Request, err error, code, msg string) {
+	var tooLarge *services.FileTooLargeError
+	var tooMany *services.PostMediaLimitError
+	var altTooLong *services.AltTextTooLongError
+	var overQuota *services.StorageQuotaError
+	var rateLimited *services.UploadRateError
+	switch {
+	case errors.As(err, &tooLarge):
+		resp.ErrorDetails(w, r, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", err.Error(), tooLarge)
+	case errors.As(err, &overQuota):
+		resp.ErrorDetails(w, r, http.StatusRequestEntityTooLarge, "STORAGE_QUOTA_EXCEEDED", err.Error(), overQuota)
+	case errors.As(err, &rateLimited):
+		setRetryAfter(w, rateLimited.ResetAt)
+		resp.ErrorDetails(w, r, http.StatusTooManyRequests, "UPLOAD_RATE_LIMITED", err.Error(), rateLimited)
+	case errors.As(err, &tooMany):
+		resp.ErrorDetails(w, r, http.StatusUnprocessableEntity, "POST_MEDIA_LIMIT", err.Error(), tooMany)
+	case errors.As(err, &altTooLong):
+		resp.ErrorDetails(w, r, http.StatusBadRequest, "ALT_TEXT_TOO_LONG", err.Error(), altTooLong)
+	case errors.Is(err, services.ErrAltTextRequired):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "ALT_TEXT_REQUIRED", err.Error())
+	case errors.Is(err, services.ErrMixedPostMedia):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "MIXED_POST_MEDIA", err.Error())
+	case errors.Is(err, services.ErrUnsupportedMime):
+		resp.Error(w, r, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", err.Error())
+	case errors.Is(err, services.ErrMimeMismatch):
+		resp.Error(w, r, http.StatusUnsupportedMediaType, "MIME_MISMATCH", err.Error())
+	case errors.Is(err, services.ErrInvalidImage):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "INVALID_IMAGE", err.Error())
+	case errors.Is(err, services.ErrInvalidSize):
+		resp.Error(w, r, http.StatusBadRequest, "INVALID_SIZE", err.Error())
+	case errors.Is(err, services.ErrUploadNotFound):
+		resp.Error(w, r, http.StatusNotFound, "UPLOAD_NOT_FOUND", err.Error())
+	case errors.Is(err, services.ErrUploadCompleted):
+		resp.Error(w, r, http.StatusConflict, "UPLOAD_COMPLETED", err.Error())
+	case errors.Is(err, services.ErrUploadMissing):
+		resp.Error(w, r, http.StatusConflict, "UPLOAD_MISSING", err.Error())
+	case errors.Is(err, services.ErrUploadExpired):
+		resp.Error(w, r, http.StatusGone, "UPLOAD_EXPIRED", err.Error())
+	case errors.Is(err, services.ErrUploadMismatch):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "UPLOAD_MISMATCH", err.Error())
+	case errors.Is(err, services.ErrMediaNotFound):
+		resp.Error(w, r, http.StatusNotFound, "MEDIA_NOT_FOUND", err.Error())
+	case errors.Is(err, services.ErrPostNotFound):
+		resp.Error(w, r, http.StatusNotFound, "POST_NOT_FOUND", err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, code, msg)
+	}
+}
+
+// setRetryAfter tells the client how many seconds to wait until t.
+func setRetryAfter(w http.ResponseWriter, t time.Time) {
+	secs := int64(math.Ceil(time.Until(t).Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(max(secs, 1), 10))
+}
+
+// multipartOverhead leaves room for boundaries, part headers and small form
+// fields on top of the largest file any kind allows.
+const multipartOverhead = 1 << 20
+
+// maxAltTextField caps the bytes read from the "alt_text" form field; the
+// character limit itself is checked by the service.
+const maxAltTextField = 64 << 10
+
+// uploadPart is the streamed "file" part of a multipart upload.
+type uploadPart struct {
+	body     io.ReadCloser
+	filename string
+	mimeType string
+	kind     string
+	limit    int64
+	// altText is the "alt_text" field, when sent before the file.
+	altText string
+}
+
+// readUpload walks the multipart body up to the "file" part and returns it
+// as a stream capped at its kind's size limit; nothing is buffered to memory
+// or disk. Fields after the file are never read, so "alt_text" must come
+// first. It writes the error response itself when ok is false.
+func (h *MediaHandler) readUpload(w http.ResponseWriter, r *http.Request) (uploadPart, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.svc.MaxSize("")+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
 		resp.Error(w, r, http.StatusBadRequest, "BAD_MULTIPART", "invalid form")
+		return uploadPart{}, false
+	}
+
+	var altText string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			resp.Error(w, r, http.StatusBadRequest, "FILE_REQUIRED", "file is required")
+			return uploadPart{}, false
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				h.writeErr(w, r, &services.FileTooLargeError{Kind: "file", MaxBytes: h.svc.MaxSize("")}, "", "")
+				return uploadPart{}, false
+			}
+			resp.Error(w, r, http.StatusBadRequest, "BAD_MULTIPART", "invalid form")
+			return uploadPart{}, false
+		}
+
+		if part.FormName() == "alt_text" {
+			data, err := io.ReadAll(io.LimitReader(part, maxAltTextField+1))
+			part.Close()
+			if err != nil || len(data) > maxAltTextField {
+				resp.Error(w, r, http.StatusBadRequest, "BAD_MULTIPART", "invalid alt_text")
+				return uploadPart{}, false
+			}
+			altText = string(data)
+			continue
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		mimeType := part.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+
+		// A generic type is settled by sniffing; anything else is checked now
+		// so a disallowed file is refused before it is read.
+		var kind string
+		if mimeType != "application/octet-stream" {
+			if kind, err = h.svc.CheckType(mimeType); err != nil {
+				h.writeErr(w, r, err, "", "")
+				return uploadPart{}, false
+			}
+		}
+
+		// Streams can outlast the server read timeout; the size cap bounds them.
+		_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+		limit := h.svc.MaxSize(kind)
+		return uploadPart{
+			body:     http.MaxBytesReader(w, part, limit),
+			filename: part.FileName(),
+			mimeType: mimeType,
+			kind:     kind,
+			limit:    limit,
+			altText:  altText,
+		}, true
+	}
+}
+
+// writeUploadErr reports a failed streamed upload, turning a tripped size
+// cap into the structured 413.
+func (h *MediaHandler) writeUploadErr(w http.ResponseWriter, r *http.Request, up uploadPart, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = &services.FileTooLargeError{Kind: up.kind, MaxBytes: up.limit}
+	}
+
+	h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot save media")
+}
+
+func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
 		return
 	}
 
-	postIDStr := chi.URLParam(r, "id")
-	if postIDStr == "" {
-		resp.Error(w, r, http.StatusBadRequest, "POST_ID_REQUIRED", "post_id is required")
+	up, ok := h.readUpload(w, r)
+	if !ok {
 		return
 	}
+	defer up.body.Close()
 
-	file, header, err := r.FormFile("file")
+	pub, err := h.svc.Save(r.Context(), userID, up.filename, up.body, -1, up.mimeType, up.altText)
 	if err != nil {
-		resp.Error(w, r, http.StatusBadRequest, "FILE_REQUIRED", "file is required")
+		h.writeUploadErr(w, r, up, err)
+		return
+	}
+
+	resp.OK(w, r, pub)
+}
+
+func (h *MediaHandler) UploadPostMedia(w http.ResponseWriter, r *http.Request) {
+	postIDStr := chi.URLParam(r, "id")
+	if postIDStr == "" {
+		resp.Error(w, r, http.StatusBadRequest, "POST_ID_REQUIRED", "post_id is required")
 		return
 	}
-	defer file.Close()
 
 	postID, err := strconv.ParseInt(postIDStr, 10, 64)
 	if err != nil || postID <= 0 {
@@ -44,24 +230,159 @@ func (h *MediaHandler) UploadPostMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mimeType := header.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	up, ok := h.readUpload(w, r)
+	if !ok {
+		return
 	}
+	defer up.body.Close()
 
-	size := header.Size
+	pub, err := h.svc.SavePostMedia(r.Context(), userID, postID, up.filename, up.body, -1, up.mimeType, up.altText)
+	if err != nil {
+		h.writeUploadErr(w, r, up, err)
+		return
+	}
 
+	resp.OK(w, r, pub)
+}
+
+func (h *MediaHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
 	userID := auth.UserIDFromCtx(r.Context())
 	if userID == 0 {
 		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
 		return
 	}
 
-	pub, err := h.svc.SavePostMedia(r.Context(), userID, postID, header.Filename, file, size, mimeType)
+	var req createUploadReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+	if req.MimeType == "" {
+		resp.Error(w, r, http.StatusBadRequest, "MIME_TYPE_REQUIRED", "mime_type is required")
+		return
+	}
+
+	up, err := h.svc.CreateUpload(r.Context(), userID, req.Filename, req.Size, req.MimeType, req.AltText)
 	if err != nil {
-		resp.Error(w, r, http.StatusBadRequest, "UPLOAD_FAIL", fmt.Sprintf("cannot save: %v", err))
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot create upload")
 		return
 	}
 
+	resp.OK(w, r, up)
+}
+
+func (h *MediaHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_UPLOAD_ID", "invalid upload id")
+		return
+	}
+
+	pub, err := h.svc.CompleteUpload(r.Context(), userID, id)
+	if err != nil {
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot complete upload")
+		return
+	}
+
+	resp.OK(w, r, pub)
+}
+
+func (h *MediaHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != "image" && kind != "video" && kind != "audio" {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_KIND", "kind must be one of: image, video, audio")
+		return
+	}
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	offset := helpers.ParseInt(r.URL.Query().Get("offset"), 0, 1_000_000)
+
+	items, err := h.svc.ListOwned(r.Context(), userID, kind, int32(limit), int32(offset))
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "LIST_MEDIA_FAIL", "cannot list media")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "offset": offset},
+	})
+}
+
+func (h *MediaHandler) Quota(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	q, err := h.svc.Quota(r.Context(), userID)
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "QUOTA_FAIL", "cannot read quota")
+		return
+	}
+	resp.OK(w, r, q)
+}
+
+func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_MEDIA_ID", "invalid media id")
+		return
+	}
+
+	if err := h.svc.Delete(r.Context(), userID, id); err != nil {
+		h.writeErr(w, r, err, "DELETE_MEDIA_FAIL", "cannot delete media")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"deleted": true})
+}
+
+func (h *MediaHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_MEDIA_ID", "invalid media id")
+		return
+	}
+
+	var req updateMediaReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+
+	pub, err := h.svc.UpdateAltText(r.Context(), userID, id, req.AltText)
+	if err != nil {
+		h.writeErr(w, r, err, "UPDATE_MEDIA_FAIL", "cannot update media")
+		return
+	}
 	resp.OK(w, r, pub)
 }
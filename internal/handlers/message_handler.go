@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type MessageHandler struct {
+	svc services.MessageService
+}
+
+func NewMessageHandler(svc services.MessageService) *MessageHandler {
+	return &MessageHandler{svc: svc}
+}
+
+type createConversationReq struct {
+	ParticipantIDs []int64 `json:"participant_ids"`
+	Title          *string `json:"title"`
+}
+
+type sendMessageReq struct {
+	Body     string  `json:"body"`
+	MediaIDs []int64 `json:"media_ids"`
+}
+
+type markReadReq struct {
+	MessageID int64 `json:"message_id"`
+}
+
+func conversationID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func (h *MessageHandler) writeErr(w http.ResponseWriter, r *http.Request, err error, code, msg string) {
+	switch {
+	case errors.Is(err, services.ErrConversationNotFound):
+		resp.Error(w, r, http.StatusNotFound, "CONVERSATION_NOT_FOUND", "conversation not found")
+	case errors.Is(err, services.ErrBlocked):
+		resp.Error(w, r, http.StatusForbidden, "BLOCKED", err.Error())
+	case errors.Is(err, services.ErrNotFollower):
+		resp.Error(w, r, http.StatusForbidden, "NOT_FOLLOWER", err.Error())
+	case errors.Is(err, helpers.ErrBadCursor):
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CURSOR", err.Error())
+	case errors.Is(err, services.ErrInvalidParticipants),
+		errors.Is(err, services.ErrTooManyParticipants),
+		errors.Is(err, services.ErrEmptyMessage),
+		errors.Is(err, services.ErrMessageTooLong),
+		errors.Is(err, services.ErrTooManyAttachments),
+		errors.Is(err, services.ErrMediaNotOwned):
+		resp.Error(w, r, http.StatusBadRequest, code, err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, code, msg)
+	}
+}
+
+func (h *MessageHandler) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	var req createConversationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+
+	conv, err := h.svc.StartConversation(r.Context(), userID, req.ParticipantIDs, req.Title)
+	if err != nil {
+		h.writeErr(w, r, err, "CREATE_CONVERSATION_FAIL", "cannot create conversation")
+		return
+	}
+	resp.OK(w, r, conv)
+}
+
+func (h *MessageHandler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	cursor := r.URL.Query().Get("cursor")
+
+	items, next, err := h.svc.ListConversations(r.Context(), userID, cursor, int32(limit))
+	if err != nil {
+		h.writeErr(w, r, err, "LIST_CONVERSATIONS_FAIL", "cannot list conversations")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "next_cursor": next},
+	})
+}
+
+func (h *MessageHandler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, ok := conversationID(r)
+	if !ok {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CONVERSATION_ID", "invalid conversation id")
+		return
+	}
+
+	conv, err := h.svc.GetConversation(r.Context(), userID, id)
+	if err != nil {
+		h.writeErr(w, r, err, "GET_CONVERSATION_FAIL", "cannot load conversation")
+		return
+	}
+	resp.OK(w, r, conv)
+}
+
+func (h *MessageHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, ok := conversationID(r)
+	if !ok {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CONVERSATION_ID", "invalid conversation id")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 30, 100)
+	cursor := r.URL.Query().Get("cursor")
+
+	items, next, err := h.svc.ListMessages(r.Context(), userID, id, cursor, int32(limit))
+	if err != nil {
+		h.writeErr(w, r, err, "LIST_MESSAGES_FAIL", "cannot list messages")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items": items,
+		"page":  map[string]any{"limit": limit, "next_cursor": next},
+	})
+}
+
+func (h *MessageHandler) Send(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, ok := conversationID(r)
+	if !ok {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CONVERSATION_ID", "invalid conversation id")
+		return
+	}
+
+	var req sendMessageReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+
+	msg, err := h.svc.Send(r.Context(), userID, id, req.Body, req.MediaIDs)
+	if err != nil {
+		h.writeErr(w, r, err, "SEND_MESSAGE_FAIL", "cannot send message")
+		return
+	}
+	resp.OK(w, r, msg)
+}
+
+func (h *MessageHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, ok := conversationID(r)
+	if !ok {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CONVERSATION_ID", "invalid conversation id")
+		return
+	}
+
+	var req markReadReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+	if req.MessageID <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "MISSING_FIELDS", "message_id is required")
+		return
+	}
+
+	if err := h.svc.MarkRead(r.Context(), userID, id, req.MessageID); err != nil {
+		if errors.Is(err, repositories.ErrMessageNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "MESSAGE_NOT_FOUND", "message not found in conversation")
+			return
+		}
+		h.writeErr(w, r, err, "MARK_READ_FAIL", "cannot mark conversation as read")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"read": true})
+}
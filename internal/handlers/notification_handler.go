@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationHandler struct {
+	svc services.NotificationService
+}
+
+func NewNotificationHandler(svc services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{svc: svc}
+}
+
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	limit := helpers.ParseInt(r.URL.Query().Get("limit"), 20, 100)
+	offset := helpers.ParseInt(r.URL.Query().Get("offset"), 0, 1_000_000)
+
+	items, unread, err := h.svc.List(r.Context(), userID, int32(limit), int32(offset))
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "LIST_NOTIFICATIONS_FAIL", "cannot list notifications")
+		return
+	}
+	resp.OK(w, r, map[string]any{
+		"items":        items,
+		"unread_count": unread,
+		"page":         map[string]any{"limit": limit, "offset": offset},
+	})
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_NOTIFICATION_ID", "invalid notification id")
+		return
+	}
+
+	if err := h.svc.MarkRead(r.Context(), userID, id); err != nil {
+		if errors.Is(err, repositories.ErrNotificationNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "NOTIFICATION_NOT_FOUND", "notification not found or already read")
+			return
+		}
+		resp.Error(w, r, http.StatusInternalServerError, "MARK_READ_FAIL", "cannot mark notification as read")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"read": true})
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	if err := h.svc.MarkAllRead(r.Context(), userID); err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "MARK_READ_FAIL", "cannot mark notifications as read")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"read": true})
+}
+
+func (h *NotificationHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	prefs, err := h.svc.Preferences(r.Context(), userID)
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "PREFERENCES_FAIL", "cannot load preferences")
+		return
+	}
+	resp.OK(w, r, prefs)
+}
+
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	var req map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+
+	if len(req) == 0 {
+		resp.Error(w, r, http.StatusBadRequest, "EMPTY_PATCH", "no preferences to update")
+		return
+	}
+
+	prefs, err := h.svc.UpdatePreferences(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownNotificationType) {
+			resp.Error(w, r, http.StatusBadRequest, "UNKNOWN_TYPE", err.Error())
+			return
+		}
+		resp.Error(w, r, http.StatusInternalServerError, "PREFERENCES_FAIL", "cannot update preferences")
+		return
+	}
+	resp.OK(w, r, prefs)
+}
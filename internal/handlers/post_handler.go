@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"go-rest-chi/internal/auth"
 	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/repositories"
 	"go-rest-chi/internal/resp"
 	"go-rest-chi/internal/services"
 	"net/http"
@@ -20,13 +22,20 @@ type PostHandler struct {
 func NewPostHandler(s services.PostService) *PostHandler { return &PostHandler{svc: s} }
 
 type createPostReq struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Visibility  string  `json:"visibility"`
+	MediaIDs    []int64 `json:"media_ids"`
+}
+
+type reorderMediaReq struct {
+	MediaIDs []int64 `json:"media_ids"`
 }
 
 type updatePostReq struct {
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
+	Visibility  *string `json:"visibility"`
 }
 
 func (h *PostHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -48,9 +57,21 @@ func (h *PostHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.svc.Create(r.Context(), req.Title, req.Description, userID)
+	post, err := h.svc.Create(r.Context(), req.Title, req.Description, userID, strings.TrimSpace(req.Visibility), req.MediaIDs)
 	if err != nil {
-		resp.Error(w, r, http.StatusInternalServerError, "CREATE_POST_FAIL", "cannot create post")
+		var tooMany *services.PostMediaLimitError
+		switch {
+		case errors.Is(err, services.ErrInvalidVisibility):
+			resp.Error(w, r, http.StatusBadRequest, "INVALID_VISIBILITY", err.Error())
+		case errors.Is(err, services.ErrMediaNotOwned):
+			resp.Error(w, r, http.StatusBadRequest, "MEDIA_NOT_OWNED", err.Error())
+		case errors.As(err, &tooMany):
+			resp.ErrorDetails(w, r, http.StatusUnprocessableEntity, "POST_MEDIA_LIMIT", err.Error(), tooMany)
+		case errors.Is(err, services.ErrMixedPostMedia):
+			resp.Error(w, r, http.StatusUnprocessableEntity, "MIXED_POST_MEDIA", err.Error())
+		default:
+			resp.Error(w, r, http.StatusInternalServerError, "CREATE_POST_FAIL", "cannot create post")
+		}
 		return
 	}
 	resp.OK(w, r, post)
@@ -66,7 +87,9 @@ func (h *PostHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	items, err := h.svc.ListPaginated(r.Context(), userID, int32(limit), int32(offset))
+	viewerID := auth.UserIDFromCtx(r.Context())
+
+	items, err := h.svc.ListPaginated(r.Context(), viewerID, userID, int32(limit), int32(offset))
 	if err != nil {
 		resp.Error(w, r, http.StatusInternalServerError, "LIST_POSTS_FAIL", "cannot list posts")
 		return
@@ -77,6 +100,28 @@ func (h *PostHandler) List(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *PostHandler) Get(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_POST_ID", "invalid post id")
+		return
+	}
+
+	viewerID := auth.UserIDFromCtx(r.Context())
+
+	post, err := h.svc.Get(r.Context(), viewerID, id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "POST_NOT_FOUND", "post not found")
+			return
+		}
+		resp.Error(w, r, http.StatusInternalServerError, "GET_POST_FAIL", "cannot get post")
+		return
+	}
+	resp.OK(w, r, post)
+}
+
 func (h *PostHandler) UpdatePartial(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -91,7 +136,7 @@ func (h *PostHandler) UpdatePartial(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title == nil && req.Description == nil {
+	if req.Title == nil && req.Description == nil && req.Visibility == nil {
 		resp.Error(w, r, http.StatusBadRequest, "EMPTY_PATCH", "no fields to update")
 		return
 	}
@@ -109,8 +154,22 @@ func (h *PostHandler) UpdatePartial(w http.ResponseWriter, r *http.Request) {
 		req.Description = &d
 	}
 
-	post, err := h.svc.UpdatePartitial(r.Context(), id, req.Title, req.Description)
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	post, err := h.svc.UpdatePartitial(r.Context(), userID, id, req.Title, req.Description, req.Visibility)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidVisibility) {
+			resp.Error(w, r, http.StatusBadRequest, "INVALID_VISIBILITY", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrPostNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "POST_NOT_FOUND", "post not found")
+			return
+		}
 		resp.Error(w, r, http.StatusInternalServerError, "UPDATE_POST_FAIL", "cannot update post")
 		return
 	}
@@ -124,9 +183,82 @@ func (h *PostHandler) SoftDelete(w http.ResponseWriter, r *http.Request) {
 		resp.Error(w, r, http.StatusBadRequest, "BAD_POST_ID", "invalid post id")
 		return
 	}
-	if err := h.svc.SoftDelete(r.Context(), id); err != nil {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	if err := h.svc.SoftDelete(r.Context(), userID, id); err != nil {
+		if errors.Is(err, services.ErrPostNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "POST_NOT_FOUND", "post not found")
+			return
+		}
 		resp.Error(w, r, http.StatusInternalServerError, "DELETE_POST_FAIL", "cannot delete post")
 		return
 	}
 	resp.OK(w, r, map[string]bool{"deleted": true})
 }
+
+func (h *PostHandler) DetachMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_POST_ID", "invalid post id")
+		return
+	}
+	mediaID, err := strconv.ParseInt(chi.URLParam(r, "mediaId"), 10, 64)
+	if err != nil || mediaID <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_MEDIA_ID", "invalid media id")
+		return
+	}
+
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	if err := h.svc.DetachMedia(r.Context(), userID, id, mediaID); err != nil {
+		if errors.Is(err, services.ErrPostMediaNotFound) {
+			resp.Error(w, r, http.StatusNotFound, "POST_MEDIA_NOT_FOUND", err.Error())
+			return
+		}
+		resp.Error(w, r, http.StatusInternalServerError, "DETACH_MEDIA_FAIL", "cannot detach media")
+		return
+	}
+	resp.OK(w, r, map[string]bool{"detached": true})
+}
+
+func (h *PostHandler) ReorderMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_POST_ID", "invalid post id")
+		return
+	}
+
+	var req reorderMediaReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	post, err := h.svc.ReorderMedia(r.Context(), userID, id, req.MediaIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPostNotFound):
+			resp.Error(w, r, http.StatusNotFound, "POST_NOT_FOUND", "post not found")
+		case errors.Is(err, services.ErrMediaOrderMismatch):
+			resp.Error(w, r, http.StatusUnprocessableEntity, "MEDIA_ORDER_MISMATCH", err.Error())
+		default:
+			resp.Error(w, r, http.StatusInternalServerError, "REORDER_MEDIA_FAIL", "cannot reorder media")
+		}
+		return
+	}
+	resp.OK(w, r, post)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+)
+
+const (
+	searchTypePosts = "posts"
+	searchTypeUsers = "users"
+)
+
+type SearchHandler struct {
+	svc services.SearchService
+}
+
+func NewSearchHandler(svc services.SearchService) *SearchHandler {
+	return &SearchHandler{svc: svc}
+}
+
+func (h *SearchHandler) writeErr(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmptyQuery), errors.Is(err, services.ErrQueryTooLong):
+		resp.Error(w, r, http.StatusBadRequest, "BAD_QUERY", err.Error())
+	case errors.Is(err, helpers.ErrBadCursor):
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CURSOR", err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, "SEARCH_FAIL", "cannot search")
+	}
+}
+
+// Search looks up posts and users. Without a type both sections are
+// returned; a cursor pages a single section and therefore requires one.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := q.Get("q")
+	typ := q.Get("type")
+	cursor := q.Get("cursor")
+	limit := helpers.ParseInt(q.Get("limit"), 20, 50)
+
+	if typ != "" && typ != searchTypePosts && typ != searchTypeUsers {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_TYPE", "type must be posts or users")
+		return
+	}
+	if cursor != "" && typ == "" {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_CURSOR", "cursor requires a type")
+		return
+	}
+
+	viewerID := auth.UserIDFromCtx(r.Context())
+	out := map[string]any{}
+
+	if typ == "" || typ == searchTypePosts {
+		items, next, err := h.svc.SearchPosts(r.Context(), viewerID, query, cursor, int32(limit))
+		if err != nil {
+			h.writeErr(w, r, err)
+			return
+		}
+		out[searchTypePosts] = map[string]any{
+			"items": items,
+			"page":  map[string]any{"limit": limit, "next_cursor": next},
+		}
+	}
+
+	if typ == "" || typ == searchTypeUsers {
+		items, next, err := h.svc.SearchUsers(r.Context(), viewerID, query, cursor, int32(limit))
+		if err != nil {
+			h.writeErr(w, r, err)
+			return
+		}
+		out[searchTypeUsers] = map[string]any{
+			"items": items,
+			"page":  map[string]any{"limit": limit, "next_cursor": next},
+		}
+	}
+
+	resp.OK(w, r, out)
+}
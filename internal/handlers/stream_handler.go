@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/realtime"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type StreamHandler struct {
+	broker    realtime.Broker
+	blocks    services.BlockService
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(broker realtime.Broker, blocks services.BlockService, heartbeat time.Duration) *StreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 25 * time.Second
+	}
+	return &StreamHandler{broker: broker, blocks: blocks, heartbeat: heartbeat}
+}
+
+func lastEventID(r *http.Request) int64 {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// hiddenFeedEvent reports whether a feed event was authored by someone the
+// viewer blocked, muted or is blocked by.
+func hiddenFeedEvent(ev realtime.Event, hidden map[int64]struct{}) bool {
+	if ev.Topic != realtime.TopicFeed || len(hidden) == 0 {
+		return false
+	}
+
+	var author struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(ev.Data, &author); err != nil {
+		return false
+	}
+	_, ok := hidden[author.UserID]
+	return ok
+}
+
+func writeEvent(w http.ResponseWriter, ev realtime.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+	return err
+}
+
+// Stream serves Server-Sent Events for the feed and the caller's private
+// topic. Events missed since Last-Event-ID are replayed before live ones.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server wide write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	lastID := lastEventID(r)
+	topics := []string{realtime.TopicFeed, realtime.UserTopic(userID)}
+
+	// Loaded once per connection; clients reconnect often enough for block
+	// and mute changes to take effect.
+	hidden, err := h.blocks.HiddenAuthors(r.Context(), userID)
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "STREAM_FAIL", "cannot subscribe")
+		return
+	}
+
+	sub, replay, err := h.broker.Subscribe(r.Context(), topics, lastID)
+	if err != nil {
+		resp.Error(w, r, http.StatusInternalServerError, "STREAM_FAIL", "cannot subscribe")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	// Events published between subscribing and loading the replay arrive
+	// twice; each replayed id is skipped once when it comes in live.
+	replayed := make(map[int64]struct{}, len(replay))
+	for _, ev := range replay {
+		replayed[ev.ID] = struct{}{}
+		if hiddenFeedEvent(ev, hidden) {
+			continue
+		}
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if _, ok := replayed[ev.ID]; ok {
+				delete(replayed, ev.ID)
+				continue
+			}
+			if hiddenFeedEvent(ev, hidden) {
+				continue
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tus 1.0.0, https://tus.io/protocols/resumable-upload
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,checksum,termination"
+	tusChunkType  = "application/offset+octet-stream"
+
+	// statusChecksumMismatch is the tus checksum extension's status code.
+	statusChecksumMismatch = 460
+)
+
+type TusHandler struct {
+	svc services.ResumableUploadService
+}
+
+func NewTusHandler(svc services.ResumableUploadService) *TusHandler {
+	return &TusHandler{svc: svc}
+}
+
+func (h *TusHandler) writeErr(w http.ResponseWriter, r *http.Request, err error, code, msg string) {
+	var tooLarge *services.FileTooLargeError
+	var altTooLong *services.AltTextTooLongError
+	var overQuota *services.StorageQuotaError
+	var rateLimited *services.UploadRateError
+	switch {
+	case errors.Is(err, services.ErrResumableUploadNotFound):
+		resp.Error(w, r, http.StatusNotFound, "UPLOAD_NOT_FOUND", err.Error())
+	case errors.Is(err, services.ErrUploadExpired):
+		resp.Error(w, r, http.StatusGone, "UPLOAD_EXPIRED", err.Error())
+	case errors.Is(err, services.ErrInvalidSize):
+		resp.Error(w, r, http.StatusBadRequest, "INVALID_SIZE", err.Error())
+	case errors.As(err, &tooLarge):
+		resp.ErrorDetails(w, r, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", err.Error(), tooLarge)
+	case errors.As(err, &overQuota):
+		resp.ErrorDetails(w, r, http.StatusRequestEntityTooLarge, "STORAGE_QUOTA_EXCEEDED", err.Error(), overQuota)
+	case errors.As(err, &rateLimited):
+		setRetryAfter(w, rateLimited.ResetAt)
+		resp.ErrorDetails(w, r, http.StatusTooManyRequests, "UPLOAD_RATE_LIMITED", err.Error(), rateLimited)
+	case errors.Is(err, services.ErrUploadTooLarge), errors.Is(err, services.ErrChunkTooLarge):
+		resp.Error(w, r, http.StatusRequestEntityTooLarge, "TOO_LARGE", err.Error())
+	case errors.Is(err, services.ErrUnsupportedMime):
+		resp.Error(w, r, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", err.Error())
+	case errors.Is(err, services.ErrMimeMismatch):
+		resp.Error(w, r, http.StatusUnsupportedMediaType, "MIME_MISMATCH", err.Error())
+	case errors.Is(err, services.ErrInvalidImage):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "INVALID_IMAGE", err.Error())
+	case errors.As(err, &altTooLong):
+		resp.ErrorDetails(w, r, http.StatusBadRequest, "ALT_TEXT_TOO_LONG", err.Error(), altTooLong)
+	case errors.Is(err, services.ErrAltTextRequired):
+		resp.Error(w, r, http.StatusUnprocessableEntity, "ALT_TEXT_REQUIRED", err.Error())
+	case errors.Is(err, services.ErrOffsetMismatch):
+		resp.Error(w, r, http.StatusConflict, "OFFSET_MISMATCH", err.Error())
+	case errors.Is(err, services.ErrUploadFinishing):
+		resp.Error(w, r, http.StatusLocked, "UPLOAD_LOCKED", err.Error())
+	case errors.Is(err, services.ErrUnsupportedChecksum):
+		resp.Error(w, r, http.StatusBadRequest, "UNSUPPORTED_CHECKSUM", err.Error())
+	case errors.Is(err, services.ErrChecksumMismatch):
+		resp.Error(w, r, statusChecksumMismatch, "CHECKSUM_MISMATCH", err.Error())
+	default:
+		resp.Error(w, r, http.StatusInternalServerError, code, msg)
+	}
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma separated
+// "key base64(value)" pairs where the value may be omitted.
+func parseUploadMetadata(header string) (map[string]string, bool) {
+	out := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return out, true
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, enc, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, false
+		}
+		val, err := base64.StdEncoding.DecodeString(strings.TrimSpace(enc))
+		if err != nil {
+			return nil, false
+		}
+		out[key] = string(val)
+	}
+	return out, true
+}
+
+// Protocol sets Tus-Resumable on every response and rejects requests that do
+// not speak the supported version.
+func (h *TusHandler) Protocol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			resp.Error(w, r, http.StatusPreconditionFailed, "TUS_VERSION", "unsupported tus version")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *TusHandler) setUploadHeaders(w http.ResponseWriter, offset int64, expiresAt time.Time, mediaID *int64) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if mediaID != nil {
+		w.Header().Set("Media-Id", strconv.FormatInt(*mediaID, 10))
+	} else {
+		w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (h *TusHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.svc.MaxSize(), 10))
+	w.Header().Set("Tus-Checksum-Algorithm", strings.Join(services.ChecksumAlgorithms, ","))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TusHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_UPLOAD_LENGTH", "Upload-Length is required")
+		return
+	}
+
+	rawMeta := r.Header.Get("Upload-Metadata")
+	meta, ok := parseUploadMetadata(rawMeta)
+	if !ok {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_UPLOAD_METADATA", "invalid Upload-Metadata")
+		return
+	}
+
+	filename := meta["filename"]
+	mimeType := meta["filetype"]
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(strings.ToLower(filepath.Ext(filename)))
+	}
+
+	up, err := h.svc.Create(r.Context(), userID, length, filename, mimeType, rawMeta, meta["alt_text"])
+	if err != nil {
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot create upload")
+		return
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+up.ID)
+	w.Header().Set("Upload-Expires", up.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *TusHandler) Head(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	up, err := h.svc.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot load upload")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Length", strconv.FormatInt(up.Length, 10))
+	if up.Metadata != "" {
+		w.Header().Set("Upload-Metadata", up.Metadata)
+	}
+	h.setUploadHeaders(w, up.Offset, up.ExpiresAt, up.MediaID)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TusHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != tusChunkType {
+		resp.Error(w, r, http.StatusUnsupportedMediaType, "BAD_CONTENT_TYPE", "Content-Type must be "+tusChunkType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_UPLOAD_OFFSET", "Upload-Offset is required")
+		return
+	}
+
+	var algo string
+	var sum []byte
+	if v := r.Header.Get("Upload-Checksum"); v != "" {
+		a, enc, _ := strings.Cut(v, " ")
+		sum, err = base64.StdEncoding.DecodeString(enc)
+		if err != nil || len(sum) == 0 {
+			resp.Error(w, r, http.StatusBadRequest, "BAD_CHECKSUM", "invalid Upload-Checksum")
+			return
+		}
+		algo = a
+	}
+
+	// Chunks from slow clients outlive the server read timeout; the service
+	// caps them at the bytes still missing.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	up, err := h.svc.WriteChunk(r.Context(), userID, chi.URLParam(r, "id"), offset, r.Body, algo, sum)
+	if err != nil {
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot store chunk")
+		return
+	}
+
+	h.setUploadHeaders(w, up.Offset, up.ExpiresAt, up.MediaID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TusHandler) Terminate(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	if err := h.svc.Terminate(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.writeErr(w, r, err, "UPLOAD_FAIL", "cannot terminate upload")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
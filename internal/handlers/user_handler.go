@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"net/http"
+)
+
+type UserHandler struct {
+	svc services.UserService
+}
+
+func NewUserHandler(svc services.UserService) *UserHandler {
+	return &UserHandler{svc: svc}
+}
+
+type updateProfileReq struct {
+	DisplayName *string `json:"display_name"`
+}
+
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromCtx(r.Context())
+	if userID == 0 {
+		resp.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "missing user")
+		return
+	}
+
+	var req updateProfileReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error(w, r, http.StatusBadRequest, "BAD_JSON", "invalid json")
+		return
+	}
+	if req.DisplayName == nil {
+		resp.Error(w, r, http.StatusBadRequest, "EMPTY_PATCH", "no fields to update")
+		return
+	}
+
+	usr, err := h.svc.UpdateProfile(r.Context(), userID, *req.DisplayName)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDisplayNameLong):
+			resp.Error(w, r, http.StatusBadRequest, "DISPLAY_NAME_TOO_LONG", err.Error())
+		case errors.Is(err, repositories.ErrUserNotFound):
+			resp.Error(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found")
+		default:
+			resp.Error(w, r, http.StatusInternalServerError, "UPDATE_PROFILE_FAIL", "cannot update profile")
+		}
+		return
+	}
+	resp.OK(w, r, usr)
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrBadCursor = errors.New("invalid cursor")
+
+// EncodeCursor builds an opaque keyset cursor from a sort timestamp and a
+// tie-breaking id.
+func EncodeCursor(at time.Time, id int64) string {
+	raw := fmt.Sprintf("%d:%d", at.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, 0, ErrBadCursor
+	}
+
+	var nanos, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return time.Time{}, 0, ErrBadCursor
+	}
+	return time.Unix(0, nanos), id, nil
+}
+
+// EncodeRankCursor builds an opaque keyset cursor for relevance-ordered
+// results from a rank and a tie-breaking id.
+func EncodeRankCursor(rank float64, id int64) string {
+	raw := strconv.FormatFloat(rank, 'g', -1, 64) + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeRankCursor(s string) (float64, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, 0, ErrBadCursor
+	}
+
+	rankStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, ErrBadCursor
+	}
+	rank, err := strconv.ParseFloat(rankStr, 64)
+	if err != nil {
+		return 0, 0, ErrBadCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, 0, ErrBadCursor
+	}
+	return rank, id, nil
+}
@@ -67,3 +67,14 @@ func Csv(s string) []string {
 	return out
 
 }
+
+// CsvInts parses a comma separated list of integers; entries that are not
+// integers come back as 0 for the caller to reject.
+func CsvInts(s string) []int {
+	parts := Csv(s)
+	out := make([]int, 0, len(parts))
+	for _, p := range parts {
+		out = append(out, MustInt(p, 0))
+	}
+	return out
+}
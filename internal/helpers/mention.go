@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mentionRe = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,64})`)
+
+// ExtractMentions returns the unique @usernames found in texts, in order of
+// first appearance. Matching is case-insensitive like the username column.
+func ExtractMentions(texts ...string) []string {
+	seen := make(map[string]struct{})
+	out := make([]string, 0)
+
+	for _, t := range texts {
+		for _, m := range mentionRe.FindAllStringSubmatch(t, -1) {
+			key := strings.ToLower(m[1])
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SniffLen is how many leading bytes DetectMime looks at.
+const SniffLen = 512
+
+// ftypBrands maps ISO base media file brands (bytes 8-12 of an ftyp box) to
+// types http.DetectContentType does not tell apart.
+var ftypBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"avif": "image/avif",
+	"qt  ": "video/quicktime",
+	"isom": "video/mp4",
+	"iso2": "video/mp4",
+	"iso5": "video/mp4",
+	"mp41": "video/mp4",
+	"mp42": "video/mp4",
+	"avc1": "video/mp4",
+	"dash": "video/mp4",
+	"M4V ": "video/mp4",
+	"M4A ": "audio/mp4",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+}
+
+// mimeAliases folds common non-canonical names onto the names DetectMime
+// returns.
+var mimeAliases = map[string]string{
+	"image/jpg":       "image/jpeg",
+	"image/pjpeg":     "image/jpeg",
+	"image/x-png":     "image/png",
+	"audio/mp3":       "audio/mpeg",
+	"audio/x-mp3":     "audio/mpeg",
+	"audio/wav":       "audio/wave",
+	"audio/x-wav":     "audio/wave",
+	"audio/x-m4a":     "audio/mp4",
+	"audio/m4a":       "audio/mp4",
+	"application/ogg": "audio/ogg",
+}
+
+// NormalizeMime lowercases ct, drops its parameters and resolves aliases.
+func NormalizeMime(ct string) string {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(ct))
+	}
+	if alias, ok := mimeAliases[mt]; ok {
+		return alias
+	}
+	return mt
+}
+
+// DetectMime identifies content from its magic bytes, ignoring whatever the
+// client claimed.
+func DetectMime(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		if ct, ok := ftypBrands[string(head[8:12])]; ok {
+			return ct
+		}
+	}
+	return NormalizeMime(http.DetectContentType(head))
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import "testing"
+
+// ftyp builds the head of an ISO base media file with the given brand.
+func ftyp(brand string) []byte {
+	return append([]byte{0, 0, 0, 0x18, 'f', 't', 'y', 'p'}, brand+"\x00\x00\x00\x00"...)
+}
+
+func TestDetectMime(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"webm", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01"), "video/webm"},
+		{"mp3", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), "audio/mpeg"},
+		{"wav", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), "audio/wave"},
+		{"ogg", []byte("OggS\x00\x02\x00\x00"), "audio/ogg"},
+		{"mp4", ftyp("isom"), "video/mp4"},
+		{"mov", ftyp("qt  "), "video/quicktime"},
+		{"m4a", ftyp("M4A "), "audio/mp4"},
+		{"heic", ftyp("heic"), "image/heic"},
+		{"avif", ftyp("avif"), "image/avif"},
+		{"html posing as an image", []byte("<!DOCTYPE html><html>"), "text/html"},
+		{"text", []byte("just some words"), "text/plain"},
+		{"empty", nil, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectMime(tt.head); got != tt.want {
+				t.Errorf("DetectMime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMime(t *testing.T) {
+	tests := map[string]string{
+		"image/jpeg":                 "image/jpeg",
+		"IMAGE/JPG":                  "image/jpeg",
+		"image/png; charset=binary":  "image/png",
+		"audio/x-wav":                "audio/wave",
+		"application/ogg":            "audio/ogg",
+		" video/mp4 ":                "video/mp4",
+		"text/plain; charset=utf-8":  "text/plain",
+		"not a media type; ===":      "not a media type; ===",
+		"application/octet-stream  ": "application/octet-stream",
+	}
+	for in, want := range tests {
+		if got := NormalizeMime(in); got != want {
+			t.Errorf("NormalizeMime(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package imaging inspects and cleans uploaded images before they reach
+// storage.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+)
+
+// maxPixels refuses decompression bombs before any pixel is decoded.
+const maxPixels = 50_000_000
+
+var ErrInvalidImage = errors.New("invalid or unsupported image")
+
+// Info describes a cleaned image.
+type Info struct {
+	Width  int
+	Height int
+	// MimeType is the type of the cleaned bytes. It differs from the input
+	// only for a WebP that had to be rotated, which is re-encoded as JPEG,
+	// or PNG when it has transparency, as there is no WebP encoder.
+	MimeType string
+}
+
+// Clean strips EXIF, XMP and similar metadata (including GPS location) from
+// data and bakes the EXIF orientation into the pixels, so the result
+// displays the same everywhere. Formats are cleaned losslessly where
+// possible; an image is only re-encoded when it has to be rotated.
+func Clean(mimeType string, data []byte) ([]byte, Info, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, Info{}, fmt.Errorf("%w: %dx%d", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+	info := Info{Width: cfg.Width, Height: cfg.Height, MimeType: mimeType}
+
+	var (
+		orientation int
+		strip       func([]byte) ([]byte, error)
+	)
+	switch mimeType {
+	case "image/jpeg":
+		orientation, strip = jpegOrientation(data), stripJPEG
+	case "image/png":
+		orientation, strip = pngOrientation(data), stripPNG
+	case "image/webp":
+		orientation, strip = webpOrientation(data), stripWebP
+	case "image/gif":
+		// GIF has no EXIF orientation.
+		orientation, strip = 1, stripGIF
+	default:
+		return nil, Info{}, fmt.Errorf("%w: %s", ErrInvalidImage, mimeType)
+	}
+
+	if orientation > 1 {
+		return reorient(mimeType, data, orientation)
+	}
+	out, err := strip(data)
+	return out, info, err
+}
+
+// reorient decodes, rotates and re-encodes an image. Encoding from pixels
+// drops every metadata chunk on the way.
+func reorient(mimeType string, data []byte, orientation int) ([]byte, Info, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	img = Orient(img, orientation)
+	b := img.Bounds()
+	info := Info{Width: b.Dx(), Height: b.Dy(), MimeType: mimeType}
+
+	if mimeType == "image/webp" {
+		info.MimeType = "image/png"
+		if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+			info.MimeType = "image/jpeg"
+		}
+	}
+
+	var buf bytes.Buffer
+	switch info.MimeType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("encode %s: %w", info.MimeType, err)
+	}
+	return buf.Bytes(), info, nil
+}
@@ -0,0 +1,363 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// testImage is w x h, red in its left half and blue in the right, so
+// rotations can be told apart.
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{0, 0, 255, 255}
+			if x < w/2 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > b
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegSegment builds a marker segment with payload.
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// exifOrientationPayload is an APP1 payload whose IFD0 holds only the
+// orientation tag.
+func exifOrientationPayload(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+// withSegments inserts segments right after the SOI marker of a JPEG.
+func withSegments(data []byte, segs ...[]byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, s := range segs {
+		out = append(out, s...)
+	}
+	return append(out, data[2:]...)
+}
+
+// pngChunk builds a PNG chunk with a valid CRC.
+func pngChunk(typ string, payload []byte) []byte {
+	c := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	c = append(c, typ...)
+	c = append(c, payload...)
+	return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+}
+
+// webpChunk builds a RIFF chunk, padded to an even length.
+func webpChunk(fourcc string, payload []byte) []byte {
+	c := append([]byte(fourcc), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	c = append(c, payload...)
+	if len(payload)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func hasJPEGMarker(t *testing.T, data []byte, marker byte) bool {
+	t.Helper()
+
+	found := false
+	if _, err := walkJPEG(data, func(m byte, _, _ []byte) bool {
+		found = found || m == marker
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestCleanJPEGStripsMetadata(t *testing.T) {
+	data := withSegments(encodeJPEG(t, testImage(8, 4)),
+		jpegSegment(0xE1, exifOrientationPayload(1)),
+		jpegSegment(0xFE, []byte("shot at home")),
+	)
+
+	out, info, err := Clean("image/jpeg", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (Info{Width: 8, Height: 4, MimeType: "image/jpeg"}) {
+		t.Errorf("info = %+v", info)
+	}
+	if hasJPEGMarker(t, out, 0xE1) || hasJPEGMarker(t, out, 0xFE) {
+		t.Error("APP1 or COM segment kept")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("cleaned jpeg does not decode: %v", err)
+	}
+}
+
+func TestCleanJPEGAppliesOrientation(t *testing.T) {
+	// Orientation 6 is stored rotated 90° counter-clockwise.
+	data := withSegments(encodeJPEG(t, testImage(32, 16)), jpegSegment(0xE1, exifOrientationPayload(6)))
+
+	out, info, err := Clean("image/jpeg", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (Info{Width: 16, Height: 32, MimeType: "image/jpeg"}) {
+		t.Errorf("info = %+v, want 16x32", info)
+	}
+	if got := jpegOrientation(out); got != 1 {
+		t.Errorf("orientation after clean = %d, want 1", got)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The red left half ends up on top.
+	if !isRed(img.At(8, 4)) || isRed(img.At(8, 28)) {
+		t.Error("image not rotated clockwise")
+	}
+}
+
+func TestCleanPNGStripsText(t *testing.T) {
+	data := encodePNG(t, testImage(3, 2))
+	iend := len(data) - 12
+	data = append(append(append([]byte{}, data[:iend]...), pngChunk("tEXt", []byte("Author\x00someone"))...), data[iend:]...)
+
+	out, info, err := Clean("image/png", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (Info{Width: 3, Height: 2, MimeType: "image/png"}) {
+		t.Errorf("info = %+v", info)
+	}
+	if bytes.Contains(out, []byte("tEXt")) {
+		t.Error("tEXt chunk kept")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("cleaned png does not decode: %v", err)
+	}
+}
+
+func TestCleanPNGAppliesOrientation(t *testing.T) {
+	// eXIf holds the TIFF data without the JPEG "Exif" header, and comes
+	// right after IHDR here.
+	data := encodePNG(t, testImage(32, 16))
+	exif := pngChunk("eXIf", exifOrientationPayload(6)[len(exifHeader):])
+	data = append(append(append([]byte{}, data[:33]...), exif...), data[33:]...)
+
+	out, info, err := Clean("image/png", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (Info{Width: 16, Height: 32, MimeType: "image/png"}) {
+		t.Errorf("info = %+v, want a 16x32 png", info)
+	}
+	if bytes.Contains(out, []byte("eXIf")) {
+		t.Error("eXIf chunk kept")
+	}
+
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRed(img.At(8, 4)) || isRed(img.At(8, 28)) {
+		t.Error("image not rotated clockwise")
+	}
+}
+
+// webpWithEXIF wraps the VP8L bitstream of a simple WebP file in the
+// extended format, with an EXIF chunk after the image data.
+func webpWithEXIF(t *testing.T, data, exif []byte) ([]byte, image.Config) {
+	t.Helper()
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagEXIF
+	w, h := cfg.Width-1, cfg.Height-1
+	vp8x[4], vp8x[5], vp8x[6] = byte(w), byte(w>>8), byte(w>>16)
+	vp8x[7], vp8x[8], vp8x[9] = byte(h), byte(h>>8), byte(h>>16)
+
+	body := append([]byte("WEBP"), webpChunk("VP8X", vp8x)...)
+	body = append(body, data[12:]...)
+	body = append(body, webpChunk("EXIF", exif)...)
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(out, body...), cfg
+}
+
+func TestCleanWebPAppliesOrientation(t *testing.T) {
+	src, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, cfg := webpWithEXIF(t, src, exifOrientationPayload(6))
+
+	out, info, err := Clean("image/webp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != cfg.Height || info.Height != cfg.Width {
+		t.Errorf("info = %+v, want %dx%d", info, cfg.Height, cfg.Width)
+	}
+
+	// There is no WebP encoder, so the rotated image changes format.
+	img, format, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("cleaned image does not decode: %v", err)
+	}
+	if "image/"+format != info.MimeType {
+		t.Errorf("cleaned image is %s, info says %s", format, info.MimeType)
+	}
+	if b := img.Bounds(); b.Dx() != info.Width || b.Dy() != info.Height {
+		t.Errorf("cleaned image is %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestCleanWebPWithoutOrientation(t *testing.T) {
+	src, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := webpWithEXIF(t, src, exifOrientationPayload(1))
+
+	out, info, err := Clean("image/webp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MimeType != "image/webp" || bytes.Contains(out, []byte("EXIF")) {
+		t.Errorf("info = %+v; want the webp kept, without EXIF", info)
+	}
+}
+
+func TestCleanGIFStripsMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	pal := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	if err := gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{pal, pal}, Delay: []int{10, 10}}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	comment := []byte("\x21\xfe\x0cshot at home\x00")
+	xmp := []byte("\x21\xff\x0b" + gifXMP + "\x05<x:x>\x00")
+	data = append(append(append(append([]byte{}, data[:len(data)-1]...), comment...), xmp...), 0x3B)
+
+	out, info, err := Clean("image/gif", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != (Info{Width: 4, Height: 4, MimeType: "image/gif"}) {
+		t.Errorf("info = %+v", info)
+	}
+	if bytes.Contains(out, []byte("shot at home")) || bytes.Contains(out, []byte(gifXMP)) {
+		t.Error("comment or XMP extension kept")
+	}
+	if !bytes.Contains(out, []byte("NETSCAPE2.0")) {
+		t.Error("looping extension dropped")
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("cleaned gif does not decode: %v", err)
+	}
+	if len(g.Image) != 2 {
+		t.Errorf("cleaned gif has %d frames, want 2", len(g.Image))
+	}
+}
+
+func TestCleanRejects(t *testing.T) {
+	// A PNG that claims to be 20000 x 20000 pixels.
+	bomb := encodePNG(t, testImage(1, 1))
+	ihdr := append([]byte{}, bomb[16:29]...)
+	binary.BigEndian.PutUint32(ihdr[0:], 20000)
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	bomb = append(append(append([]byte{}, bomb[:8]...), pngChunk("IHDR", ihdr)...), bomb[33:]...)
+
+	tests := []struct {
+		name     string
+		mimeType string
+		data     []byte
+	}{
+		{"garbage", "image/png", []byte("not an image")},
+		{"too many pixels", "image/png", bomb},
+		{"unsupported type", "image/bmp", encodePNG(t, testImage(1, 1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Clean(tt.mimeType, tt.data); !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("err = %v, want ErrInvalidImage", err)
+			}
+		})
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagEXIF | webpFlagXMP
+	body := append([]byte("WEBP"), webpChunk("VP8X", vp8x)...)
+	body = append(body, webpChunk("VP8L", []byte("pixels"))...)
+	body = append(body, webpChunk("EXIF", []byte("exif data"))...)
+	body = append(body, webpChunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	out, err := stripWebP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("EXIF")) || bytes.Contains(out, []byte("XMP ")) {
+		t.Error("metadata chunk kept")
+	}
+	if !bytes.Contains(out, []byte("VP8L")) {
+		t.Error("image chunk dropped")
+	}
+	if flags := out[20]; flags&(webpFlagEXIF|webpFlagXMP) != 0 {
+		t.Errorf("VP8X flags = %#x, metadata bits still set", flags)
+	}
+	if size := binary.LittleEndian.Uint32(out[4:]); int(size) != len(out)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(out)-8)
+	}
+}
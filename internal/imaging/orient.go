@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Orient applies an EXIF orientation (1-8) so the result is upright.
+func Orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, defaulting to 1.
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	_, _ = walkJPEG(data, func(marker byte, payload, _ []byte) bool {
+		if marker == 0xE1 && bytes.HasPrefix(payload, exifHeader) {
+			orientation = exifOrientation(payload[len(exifHeader):])
+			return false
+		}
+		return true
+	})
+	return orientation
+}
+
+// pngOrientation reads the orientation from an eXIf chunk, defaulting to 1.
+func pngOrientation(data []byte) int {
+	orientation := 1
+	_ = walkPNG(data, func(typ string, payload, _ []byte) bool {
+		if typ == "eXIf" {
+			orientation = exifOrientation(bytes.TrimPrefix(payload, exifHeader))
+			return false
+		}
+		// eXIf must come before the image data.
+		return typ != "IDAT"
+	})
+	return orientation
+}
+
+// webpOrientation reads the orientation from an EXIF chunk, defaulting to 1.
+// Some writers keep the JPEG "Exif" header in front of the TIFF data.
+func webpOrientation(data []byte) int {
+	orientation := 1
+	_ = walkWebP(data, func(fourcc string, payload, _ []byte) bool {
+		if fourcc == "EXIF" {
+			orientation = exifOrientation(bytes.TrimPrefix(payload, exifHeader))
+			return false
+		}
+		return true
+	})
+	return orientation
+}
+
+// exifOrientation finds tag 0x0112 in IFD0 of a TIFF-structured EXIF block.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+
+	off := int(bo.Uint32(tiff[4:]))
+	if off < 8 || off+2 > len(tiff) {
+		return 1
+	}
+
+	n := int(bo.Uint16(tiff[off:]))
+	for i := 0; i < n; i++ {
+		e := off + 2 + i*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 {
+			if v := int(bo.Uint16(tiff[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,188 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// placeholderSize is the longest side images are shrunk to before hashing;
+// a placeholder is a blur, so more pixels only cost time.
+const placeholderSize = 64
+
+// Placeholder is what clients render while an image loads.
+type Placeholder struct {
+	// BlurHash, see https://blurha.sh.
+	BlurHash string
+	// Color is the dominant color as #rrggbb, empty for fully transparent
+	// images.
+	Color string
+}
+
+// Placeholders computes the BlurHash and dominant color of an encoded image.
+func Placeholders(data []byte) (Placeholder, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Placeholder{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		return Placeholder{}, fmt.Errorf("%w: empty image", ErrInvalidImage)
+	}
+	if s := max(w, h); s > placeholderSize {
+		w, h = max(1, w*placeholderSize/s), max(1, h*placeholderSize/s)
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), src, b, draw.Src, nil)
+
+	// Four components along the long side, three along the short one.
+	cx, cy := 4, 3
+	if h > w {
+		cx, cy = 3, 4
+	}
+	return Placeholder{
+		BlurHash: blurHash(small, cx, cy),
+		Color:    dominantColor(small),
+	}, nil
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func base83(sb *strings.Builder, v, length int) {
+	for i := 1; i <= length; i++ {
+		d := v / int(math.Pow(83, float64(length-i))) % 83
+		sb.WriteByte(base83Chars[d])
+	}
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = max(0, min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signPow raises |v| to exp and keeps the sign of v.
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// blurHash encodes img with cx by cy cosine components.
+func blurHash(img *image.NRGBA, cx, cy int) string {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	// Linear RGB of every pixel, computed once.
+	lin := make([][3]float64, w*h)
+	for y := range h {
+		for x := range w {
+			c := img.NRGBAAt(x, y)
+			lin[y*w+x] = [3]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)}
+		}
+	}
+
+	factors := make([][3]float64, 0, cx*cy)
+	for j := range cy {
+		for i := range cx {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+
+			var f [3]float64
+			for y := range h {
+				by := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := range w {
+					basis := by * math.Cos(math.Pi*float64(i)*float64(x)/float64(w))
+					p := lin[y*w+x]
+					f[0] += basis * p[0]
+					f[1] += basis * p[1]
+					f[2] += basis * p[2]
+				}
+			}
+
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	base83(&sb, (cx-1)+(cy-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actual := 0.0
+		for _, f := range ac {
+			actual = max(actual, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantised := int(max(0, min(82, math.Floor(actual*166-0.5))))
+		maxValue = float64(quantised+1) / 166
+		base83(&sb, quantised, 1)
+	} else {
+		base83(&sb, 0, 1)
+	}
+
+	base83(&sb, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+
+	for _, f := range ac {
+		q := func(v float64) int {
+			return int(max(0, min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		base83(&sb, q(f[0])*19*19+q(f[1])*19+q(f[2]), 2)
+	}
+	return sb.String()
+}
+
+// dominantColor buckets the opaque pixels of img by their top four bits per
+// channel and returns the average color of the fullest bucket.
+func dominantColor(img *image.NRGBA) string {
+	type bucket struct {
+		n       int
+		r, g, b int
+	}
+	var buckets [4096]bucket
+
+	best := -1
+	b := img.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			if c.A < 128 {
+				continue
+			}
+			k := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			bk := &buckets[k]
+			bk.n++
+			bk.r += int(c.R)
+			bk.g += int(c.G)
+			bk.b += int(c.B)
+			if best < 0 || bk.n > buckets[best].n {
+				best = k
+			}
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+
+	bk := buckets[best]
+	c := color.RGBA{R: uint8(bk.r / bk.n), G: uint8(bk.g / bk.n), B: uint8(bk.b / bk.n)}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
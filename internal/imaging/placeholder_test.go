@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func solidImage(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func decodeBase83(s string) int {
+	v := 0
+	for _, c := range s {
+		v = v*83 + strings.IndexRune(base83Chars, c)
+	}
+	return v
+}
+
+func TestPlaceholdersSolidColor(t *testing.T) {
+	p, err := Placeholders(encodePNG(t, solidImage(120, 60, color.NRGBA{0x33, 0x66, 0x99, 0xff})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Color != "#336699" {
+		t.Errorf("Color = %q, want #336699", p.Color)
+	}
+
+	// Size flag, max AC, DC and 11 AC components of two characters each.
+	if len(p.BlurHash) != 1+1+4+2*11 {
+		t.Fatalf("BlurHash %q has length %d", p.BlurHash, len(p.BlurHash))
+	}
+	if got := decodeBase83(p.BlurHash[:1]); got != (4-1)+(3-1)*9 {
+		t.Errorf("size flag = %d, want 4x3 components", got)
+	}
+	if got := decodeBase83(p.BlurHash[2:6]); got != 0x336699 {
+		t.Errorf("DC = %06x, want 336699", got)
+	}
+}
+
+func TestPlaceholdersPortrait(t *testing.T) {
+	p, err := Placeholders(encodePNG(t, testImage(30, 90)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeBase83(p.BlurHash[:1]); got != (3-1)+(4-1)*9 {
+		t.Errorf("size flag = %d, want 3x4 components", got)
+	}
+}
+
+func TestPlaceholdersDominantColor(t *testing.T) {
+	img := solidImage(100, 100, color.NRGBA{0xe0, 0x10, 0x10, 0xff})
+	draw.Draw(img, image.Rect(0, 0, 30, 100), image.NewUniform(color.NRGBA{0x10, 0x10, 0xe0, 0xff}), image.Point{}, draw.Src)
+
+	p, err := Placeholders(encodePNG(t, img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Color != "#e01010" {
+		t.Errorf("Color = %q, want #e01010", p.Color)
+	}
+}
+
+func TestPlaceholdersTransparent(t *testing.T) {
+	p, err := Placeholders(encodePNG(t, solidImage(10, 10, color.NRGBA{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Color != "" {
+		t.Errorf("Color = %q, want none", p.Color)
+	}
+	if p.BlurHash == "" {
+		t.Error("BlurHash is empty")
+	}
+}
+
+func TestPlaceholdersInvalid(t *testing.T) {
+	if _, err := Placeholders([]byte("not an image")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("err = %v, want ErrInvalidImage", err)
+	}
+}
@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// Variant is a resized rendition of an image.
+type Variant struct {
+	Width    int
+	Height   int
+	MimeType string
+	Ext      string
+	Data     []byte
+}
+
+// Variants renders data at each of widths that is narrower than the
+// original, keeping the aspect ratio. Opaque results are JPEG, anything with
+// transparency is PNG.
+func Variants(data []byte, widths []int) ([]Variant, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	b := src.Bounds()
+	out := make([]Variant, 0, len(widths))
+	for _, w := range widths {
+		if w <= 0 || w >= b.Dx() {
+			continue
+		}
+		h := max(1, b.Dy()*w/b.Dx())
+
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+		v, err := encode(dst)
+		if err != nil {
+			return nil, err
+		}
+		v.Width, v.Height = w, h
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func encode(img *image.RGBA) (Variant, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 82}); err != nil {
+			return Variant{}, fmt.Errorf("jpeg encode: %w", err)
+		}
+		return Variant{MimeType: "image/jpeg", Ext: ".jpg", Data: buf.Bytes()}, nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return Variant{}, fmt.Errorf("png encode: %w", err)
+	}
+	return Variant{MimeType: "image/png", Ext: ".png", Data: buf.Bytes()}, nil
+}
@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	data := encodePNG(t, testImage(200, 100))
+
+	got, err := Variants(data, []int{50, 0, 120, 200, 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Widths that are not narrower than the original are skipped.
+	want := []Variant{
+		{Width: 50, Height: 25, MimeType: "image/jpeg", Ext: ".jpg"},
+		{Width: 120, Height: 60, MimeType: "image/jpeg", Ext: ".jpg"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(got), len(want))
+	}
+	for i, v := range got {
+		w := want[i]
+		if v.Width != w.Width || v.Height != w.Height || v.MimeType != w.MimeType || v.Ext != w.Ext {
+			t.Errorf("variant %d = %dx%d %s %s, want %dx%d %s %s",
+				i, v.Width, v.Height, v.MimeType, v.Ext, w.Width, w.Height, w.MimeType, w.Ext)
+		}
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatalf("variant %d does not decode: %v", i, err)
+		}
+		if format != "jpeg" || cfg.Width != v.Width || cfg.Height != v.Height {
+			t.Errorf("variant %d encodes a %dx%d %s", i, cfg.Width, cfg.Height, format)
+		}
+	}
+}
+
+func TestVariantsKeepTransparency(t *testing.T) {
+	img := testImage(100, 100)
+	img.Set(10, 10, color.NRGBA{})
+
+	got, err := Variants(encodePNG(t, img), []int{50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].MimeType != "image/png" || got[0].Ext != ".png" {
+		t.Fatalf("variants = %+v, want one png", got)
+	}
+}
+
+func TestVariantsInvalid(t *testing.T) {
+	if _, err := Variants([]byte("not an image"), []int{50}); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("err = %v, want ErrInvalidImage", err)
+	}
+}
@@ -0,0 +1,251 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	pngMagic   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// walkJPEG calls fn for every marker segment ahead of the image data with
+// the marker, its payload and the raw segment bytes, and returns the offset
+// where the image data starts. fn returns false to stop early.
+func walkJPEG(data []byte, fn func(marker byte, payload, raw []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, fmt.Errorf("%w: not a jpeg", ErrInvalidImage)
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 0, fmt.Errorf("%w: bad jpeg marker", ErrInvalidImage)
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++ // fill byte
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return i, nil
+		}
+
+		l := int(binary.BigEndian.Uint16(data[i+2:]))
+		if l < 2 || i+2+l > len(data) {
+			return 0, fmt.Errorf("%w: truncated jpeg segment", ErrInvalidImage)
+		}
+		if !fn(marker, data[i+4:i+2+l], data[i:i+2+l]) {
+			return i, nil
+		}
+		i += 2 + l
+	}
+	return 0, fmt.Errorf("%w: jpeg has no image data", ErrInvalidImage)
+}
+
+// stripJPEG drops EXIF/XMP (APP1), IPTC (APP13) and comment segments. JFIF,
+// ICC profiles and Adobe color info are kept so colors do not shift.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+
+	scan, err := walkJPEG(data, func(marker byte, _, raw []byte) bool {
+		switch marker {
+		case 0xE1, 0xED, 0xFE:
+		default:
+			out = append(out, raw...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[scan:]...), nil
+}
+
+// pngMetadata lists ancillary chunks that can carry EXIF or free text.
+var pngMetadata = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// walkPNG calls fn for every chunk up to and including IEND with its type,
+// payload and raw bytes. fn returns false to stop early.
+func walkPNG(data []byte, fn func(typ string, payload, raw []byte) bool) error {
+	if !bytes.HasPrefix(data, pngMagic) {
+		return fmt.Errorf("%w: not a png", ErrInvalidImage)
+	}
+
+	for i := len(pngMagic); i < len(data); {
+		if i+12 > len(data) {
+			return fmt.Errorf("%w: truncated png chunk", ErrInvalidImage)
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return fmt.Errorf("%w: truncated png chunk", ErrInvalidImage)
+		}
+
+		typ := string(data[i+4 : i+8])
+		if !fn(typ, data[i+8:end-4], data[i:end]) || typ == "IEND" {
+			return nil
+		}
+		i = end
+	}
+	return nil
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngMagic...)
+
+	err := walkPNG(data, func(typ string, _, raw []byte) bool {
+		if !pngMetadata[typ] {
+			out = append(out, raw...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WebP VP8X flags announcing EXIF and XMP chunks.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// walkWebP calls fn for every chunk of a RIFF WebP file with its fourcc,
+// payload and raw bytes, padding included. fn returns false to stop early.
+func walkWebP(data []byte, fn func(fourcc string, payload, raw []byte) bool) error {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return fmt.Errorf("%w: not a webp", ErrInvalidImage)
+	}
+
+	for i := 12; i+8 <= len(data); {
+		fourcc := string(data[i : i+4])
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + n + n%2
+		if end > len(data) {
+			end = len(data)
+			if i+8+n > end {
+				return fmt.Errorf("%w: truncated webp chunk", ErrInvalidImage)
+			}
+		}
+
+		if !fn(fourcc, data[i+8:i+8+n], data[i:end]) {
+			return nil
+		}
+		i = end
+	}
+	return nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	out := make([]byte, 12, len(data))
+
+	err := walkWebP(data, func(fourcc string, payload, raw []byte) bool {
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, raw...)
+			if len(payload) > 0 {
+				out[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		default:
+			out = append(out, raw...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	copy(out, data[:12])
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// gifXMP is the application identifier and authentication code of the XMP
+// extension.
+const gifXMP = "XMP DataXMP"
+
+// stripGIF drops comment and XMP application extensions, keeping every
+// other block (NETSCAPE looping included) byte for byte.
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, fmt.Errorf("%w: not a gif", ErrInvalidImage)
+	}
+
+	// Header, logical screen descriptor and global color table.
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, fmt.Errorf("%w: truncated gif", ErrInvalidImage)
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:i]...)
+
+	// subBlocks returns the end of the data sub-blocks starting at j.
+	subBlocks := func(j int) (int, error) {
+		for j < len(data) {
+			n := int(data[j])
+			j++
+			if n == 0 {
+				return j, nil
+			}
+			j += n
+		}
+		return 0, fmt.Errorf("%w: truncated gif block", ErrInvalidImage)
+	}
+
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x3B: // trailer
+			return append(out, 0x3B), nil
+		case 0x21: // extension
+			if i+2 > len(data) {
+				return nil, fmt.Errorf("%w: truncated gif block", ErrInvalidImage)
+			}
+			label := data[i+1]
+			end, err := subBlocks(i + 2)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			isXMP := label == 0xFF && i-start > 3+len(gifXMP) && data[start+2] == byte(len(gifXMP)) &&
+				string(data[start+3:start+3+len(gifXMP)]) == gifXMP
+			if label == 0xFE || isXMP {
+				continue
+			}
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return nil, fmt.Errorf("%w: truncated gif block", ErrInvalidImage)
+			}
+			j := i + 10
+			if flags := data[i+9]; flags&0x80 != 0 {
+				j += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data.
+			end, err := subBlocks(j + 1)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: bad gif block", ErrInvalidImage)
+		}
+		out = append(out, data[start:i]...)
+	}
+	return nil, fmt.Errorf("%w: gif has no trailer", ErrInvalidImage)
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RelatedUser is an entry of the caller's block, mute or follow request list.
+type RelatedUser struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
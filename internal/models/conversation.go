@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+const (
+	ConversationDirect = "direct"
+	ConversationGroup  = "group"
+)
+
+type Conversation struct {
+	ID                int64         `json:"id"`
+	Kind              string        `json:"kind"`
+	Title             *string       `json:"title,omitempty"`
+	CreatedBy         int64         `json:"created_by"`
+	LastActivityAt    time.Time     `json:"last_activity_at"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	LastReadMessageID *int64        `json:"last_read_message_id,omitempty"`
+	UnreadCount       int64         `json:"unread_count"`
+	Participants      []Participant `json:"participants"`
+}
+
+type Participant struct {
+	UserID            int64     `json:"user_id"`
+	Username          string    `json:"username"`
+	LastReadMessageID *int64    `json:"last_read_message_id,omitempty"`
+	JoinedAt          time.Time `json:"joined_at"`
+}
+
+type ConversationPublic struct {
+	ID             int64         `json:"id"`
+	Kind           string        `json:"kind"`
+	Title          *string       `json:"title,omitempty"`
+	CreatedBy      int64         `json:"created_by"`
+	LastActivityAt time.Time     `json:"last_activity_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UnreadCount    int64         `json:"unread_count"`
+	Participants   []Participant `json:"participants"`
+}
+
+func (c Conversation) Public() ConversationPublic {
+	return ConversationPublic{
+		ID:             c.ID,
+		Kind:           c.Kind,
+		Title:          c.Title,
+		CreatedBy:      c.CreatedBy,
+		LastActivityAt: c.LastActivityAt,
+		CreatedAt:      c.CreatedAt,
+		UnreadCount:    c.UnreadCount,
+		Participants:   c.Participants,
+	}
+}
+
+type Message struct {
+	ID             int64      `json:"id"`
+	ConversationID int64      `json:"conversation_id"`
+	SenderID       int64      `json:"sender_id"`
+	Body           string     `json:"body"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Medias         []Media    `json:"medias"`
+}
+
+type MessagePublic struct {
+	ID             int64         `json:"id"`
+	ConversationID int64         `json:"conversation_id"`
+	SenderID       int64         `json:"sender_id"`
+	Body           string        `json:"body"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Medias         []MediaPublic `json:"medias"`
+}
@@ -0,0 +1,12 @@
+package models
+
+const (
+	FollowPending  = "pending"
+	FollowAccepted = "accepted"
+)
+
+// FollowStatus is returned to the follower after a follow attempt.
+type FollowStatus struct {
+	UserID int64  `json:"user_id"`
+	Status string `json:"status"`
+}
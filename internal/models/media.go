@@ -1,6 +1,18 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Media status: uploads are accepted as processing and settle on ready or
+// failed once the processing pipeline has run.
+const (
+	MediaStatusProcessing = "processing"
+	MediaStatusReady      = "ready"
+	MediaStatusFailed     = "failed"
+)
 
 type Media struct {
 	ID         int64      `json:"id"`
@@ -12,8 +24,56 @@ type Media struct {
 	Width      *int32     `json:"width,omitempty"`
 	Height     *int32     `json:"height,omitempty"`
 	DurationMs *int32     `json:"duration_ms,omitempty"`
+	VideoCodec string     `json:"video_codec,omitempty"`
+	AudioCodec string     `json:"audio_codec,omitempty"`
+	PosterKey  string     `json:"poster_key,omitempty"` // still frame of a video
+	Status     string     `json:"status"`
 	CreatedAt  time.Time  `json:"created_at"`
 	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+
+	// BlurHash and DominantColor are placeholders for images.
+	BlurHash      string `json:"blurhash,omitempty"`
+	DominantColor string `json:"dominant_color,omitempty"`
+	// ContentHash is the hex SHA-256 of the stored bytes, the same for media
+	// deduplicated onto one object.
+	ContentHash string `json:"content_hash,omitempty"`
+	// AltText describes the media for people who cannot see or hear it.
+	AltText string `json:"alt_text,omitempty"`
+	// Variants are resized renditions of an image, narrowest first.
+	Variants []MediaVariant `json:"variants,omitempty"`
+}
+
+// ServedObject is what the static media route needs to know about a stored
+// object: its type, and the hash of its bytes when recorded.
+type ServedObject struct {
+	MimeType    string
+	ContentHash string
+}
+
+// QuotaUsage is a user's storage use against their quota. Limits are
+// omitted when unlimited.
+type QuotaUsage struct {
+	UsedBytes      int64 `json:"used_bytes"`
+	MaxBytes       int64 `json:"max_bytes,omitempty"`
+	FilesToday     int   `json:"files_today"`
+	MaxFilesPerDay int   `json:"max_files_per_day,omitempty"`
+	// FilesResetAt is when the daily file count starts over (UTC midnight).
+	FilesResetAt time.Time `json:"files_reset_at"`
+}
+
+type MediaVariant struct {
+	Width      int32  `json:"width"`
+	Height     int32  `json:"height"`
+	StorageKey string `json:"storage_key"`
+	MimeType   string `json:"mime_type"`
+	SizeBytes  int64  `json:"size_bytes"`
+}
+
+type MediaVariantPublic struct {
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	MimeType string `json:"mime_type"`
+	URL      string `json:"url"`
 }
 
 type MediaPublic struct {
@@ -24,15 +84,74 @@ type MediaPublic struct {
 	Width      *int32 `json:"width,omitempty"`
 	Height     *int32 `json:"height,omitempty"`
 	DurationMs *int32 `json:"duration_ms,omitempty"`
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+	PosterURL  string `json:"poster_url,omitempty"`
+	Status     string `json:"status"`
+	AltText    string `json:"alt_text,omitempty"`
+	// ContentHash is the hex SHA-256 of the bytes served at URL, for clients
+	// to check a download against.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Warnings are advice about the media as sent by its owner, such as a
+	// missing alt text; only set in responses to the owner's own writes.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// BlurHash and DominantColor (#rrggbb) stand in for an image until it
+	// has loaded.
+	BlurHash      string `json:"blurhash,omitempty"`
+	DominantColor string `json:"dominant_color,omitempty"`
+
+	Variants []MediaVariantPublic `json:"variants,omitempty"`
+	// Srcset lists the variants and the original for an <img srcset>.
+	Srcset string `json:"srcset,omitempty"`
 }
 
 func (m Media) PublicWithURL(u string) MediaPublic {
 	return MediaPublic{
-		ID:       m.ID,
-		Kind:     m.Kind,
-		MimeType: m.MimeType,
-		URL:      u,
-		Width:    m.Width,
-		Height:   m.Height,
+		ID:         m.ID,
+		Kind:       m.Kind,
+		MimeType:   m.MimeType,
+		URL:        u,
+		Width:      m.Width,
+		Height:     m.Height,
+		DurationMs: m.DurationMs,
+		VideoCodec: m.VideoCodec,
+		AudioCodec: m.AudioCodec,
+		Status:     m.Status,
+		AltText:    m.AltText,
+
+		BlurHash:      m.BlurHash,
+		DominantColor: m.DominantColor,
+		ContentHash:   m.ContentHash,
+	}
+}
+
+// PublicWith resolves the URL of the original, of the poster and of every
+// variant through urlFor.
+func (m Media) PublicWith(urlFor func(key string) string) MediaPublic {
+	pub := m.PublicWithURL(urlFor(m.StorageKey))
+	if m.PosterKey != "" {
+		pub.PosterURL = urlFor(m.PosterKey)
+	}
+	if len(m.Variants) == 0 {
+		return pub
+	}
+
+	srcset := make([]string, 0, len(m.Variants)+1)
+	pub.Variants = make([]MediaVariantPublic, 0, len(m.Variants))
+	for _, v := range m.Variants {
+		url := urlFor(v.StorageKey)
+		pub.Variants = append(pub.Variants, MediaVariantPublic{
+			Width:    v.Width,
+			Height:   v.Height,
+			MimeType: v.MimeType,
+			URL:      url,
+		})
+		srcset = append(srcset, fmt.Sprintf("%s %dw", url, v.Width))
+	}
+	if m.Width != nil {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", pub.URL, *m.Width))
 	}
+	pub.Srcset = strings.Join(srcset, ", ")
+	return pub
 }
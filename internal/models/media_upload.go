@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MediaUpload is a pending direct-to-storage upload. The media row only
+// exists once the upload is completed.
+type MediaUpload struct {
+	ID          int64      `json:"id"`
+	OwnerID     int64      `json:"owner_id"`
+	Kind        string     `json:"kind"`
+	StorageKey  string     `json:"storage_key"`
+	MimeType    string     `json:"mime_type"`
+	SizeBytes   int64      `json:"size_bytes"`
+	AltText     string     `json:"alt_text,omitempty"`
+	MediaID     *int64     `json:"media_id,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// MediaUploadPublic tells the client where and how to send the bytes.
+type MediaUploadPublic struct {
+	ID        int64             `json:"id"`
+	Method    string            `json:"method"`
+	UploadURL string            `json:"upload_url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
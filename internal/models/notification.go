@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	NotificationFollow         = "follow"
+	NotificationFollowRequest  = "follow_request"
+	NotificationFollowAccepted = "follow_accepted"
+	NotificationReaction       = "reaction"
+	NotificationComment        = "comment"
+	NotificationMention        = "mention"
+)
+
+var NotificationTypes = []string{
+	NotificationFollow,
+	NotificationFollowRequest,
+	NotificationFollowAccepted,
+	NotificationReaction,
+	NotificationComment,
+	NotificationMention,
+}
+
+func IsNotificationType(t string) bool {
+	for _, nt := range NotificationTypes {
+		if nt == t {
+			return true
+		}
+	}
+	return false
+}
+
+type Notification struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	ActorID       int64      `json:"actor_id"`
+	ActorUsername string     `json:"actor_username"`
+	Type          string     `json:"type"`
+	EntityID      *int64     `json:"entity_id,omitempty"`
+	GroupKey      string     `json:"group_key"`
+	ActorCount    int32      `json:"actor_count"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type NotificationActor struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type NotificationPublic struct {
+	ID          int64             `json:"id"`
+	Type        string            `json:"type"`
+	Actor       NotificationActor `json:"actor"`
+	OthersCount int32             `json:"others_count"`
+	EntityID    *int64            `json:"entity_id,omitempty"`
+	Summary     string            `json:"summary"`
+	Read        bool              `json:"read"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (n Notification) Public() NotificationPublic {
+	others := n.ActorCount - 1
+	if others < 0 {
+		others = 0
+	}
+
+	return NotificationPublic{
+		ID:          n.ID,
+		Type:        n.Type,
+		Actor:       NotificationActor{ID: n.ActorID, Username: n.ActorUsername},
+		OthersCount: others,
+		EntityID:    n.EntityID,
+		Summary:     n.summary(others),
+		Read:        n.ReadAt != nil,
+		CreatedAt:   n.CreatedAt,
+		UpdatedAt:   n.UpdatedAt,
+	}
+}
+
+func (n Notification) summary(others int32) string {
+	who := n.ActorUsername
+	switch {
+	case others == 1:
+		who += " and 1 other"
+	case others > 1:
+		who += fmt.Sprintf(" and %d others", others)
+	}
+
+	switch n.Type {
+	case NotificationFollow:
+		return who + " started following you"
+	case NotificationFollowRequest:
+		return who + " requested to follow you"
+	case NotificationFollowAccepted:
+		return who + " accepted your follow request"
+	case NotificationReaction:
+		return who + " reacted to your post"
+	case NotificationComment:
+		return who + " commented on your post"
+	case NotificationMention:
+		return who + " mentioned you in a post"
+	default:
+		return who
+	}
+}
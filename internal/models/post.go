@@ -4,6 +4,29 @@ import (
 	"time"
 )
 
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityMentioned = "mentioned"
+	VisibilityUnlisted  = "unlisted"
+)
+
+var PostVisibilities = []string{
+	VisibilityPublic,
+	VisibilityFollowers,
+	VisibilityMentioned,
+	VisibilityUnlisted,
+}
+
+func IsPostVisibility(v string) bool {
+	for _, pv := range PostVisibilities {
+		if pv == v {
+			return true
+		}
+	}
+	return false
+}
+
 type Post struct {
 	Id          int64      `json:"id"`
 	Title       string     `json:"title"`
@@ -12,6 +35,7 @@ type Post struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Visibility  string     `json:"visibility"`
 }
 
 type PostPublic struct {
@@ -19,6 +43,7 @@ type PostPublic struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	UserId      int64     `json:"user_id"`
+	Visibility  string    `json:"visibility"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -38,6 +63,7 @@ func (p Post) Public() PostPublic {
 		Title:       p.Title,
 		Description: p.Description,
 		UserId:      p.UserId,
+		Visibility:  p.Visibility,
 		CreatedAt:   p.CreatedAt,
 	}
 }
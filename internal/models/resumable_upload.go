@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ResumableUpload tracks a tus upload. Offset is how many bytes the server
+// holds; MediaID is set once all Length bytes arrived and were processed.
+type ResumableUpload struct {
+	ID        string
+	OwnerID   int64
+	Filename  string
+	MimeType  string
+	Length    int64
+	Offset    int64
+	Metadata  string
+	AltText   string
+	MediaID   *int64
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (u ResumableUpload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// ResumableUploadPart is one stored PATCH body.
+type ResumableUploadPart struct {
+	Offset     int64
+	StorageKey string
+	Size       int64
+}
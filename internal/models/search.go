@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type PostSearchResult struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	Visibility  string    `json:"visibility"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Snippet is HTML-escaped text with matches wrapped in <mark> tags.
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type UserSearchResult struct {
+	ID          int64   `json:"id"`
+	Username    string  `json:"username"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Rank        float64 `json:"rank"`
+}
@@ -10,20 +10,26 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	IsPrivate    bool       `json:"is_private"`
+	DisplayName  *string    `json:"display_name,omitempty"`
 }
 
 type UserPublic struct {
-	Id        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	Id          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	Email       string    `json:"email"`
+	IsPrivate   bool      `json:"is_private"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 func (u User) Public() UserPublic {
 	return UserPublic{
-		Id:        u.Id,
-		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
+		Id:          u.Id,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		IsPrivate:   u.IsPrivate,
+		CreatedAt:   u.CreatedAt,
 	}
 }
@@ -0,0 +1,34 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+)
+
+// Container is the pure Go prober: it reads MP4/QuickTime and Matroska/WebM
+// headers without decoding anything, so it finds no posters and knows no
+// other formats.
+type Container struct{}
+
+// Probe implements Prober.
+func (Container) Probe(ctx context.Context, f *os.File) (Info, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+
+	head := make([]byte, 12)
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return Info{}, ErrUnsupported
+	}
+
+	switch {
+	case bytes.Equal(head[4:8], []byte("ftyp")):
+		return probeMP4(f, st.Size())
+	case binary.BigEndian.Uint32(head[:4]) == ebmlHeader:
+		return probeWebM(f, st.Size())
+	}
+	return Info{}, ErrUnsupported
+}
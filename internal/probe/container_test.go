@@ -0,0 +1,215 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func probeBytes(t *testing.T, data []byte) (Info, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "media")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return Container{}.Probe(context.Background(), f)
+}
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// mp4Track is a trak box with a handler, a sample entry and, for video, a
+// tkhd of the given size and transformation matrix.
+func mp4Track(handler, codec string, width, height uint32, matrix [9]int32) []byte {
+	var children [][]byte
+	if handler == "vide" {
+		tkhd := make([]byte, 40)
+		for _, v := range matrix {
+			tkhd = binary.BigEndian.AppendUint32(tkhd, uint32(v))
+		}
+		tkhd = append(tkhd, u32(width<<16)...)
+		tkhd = append(tkhd, u32(height<<16)...)
+		children = append(children, mp4Box("tkhd", tkhd))
+	}
+
+	hdlr := mp4Box("hdlr", make([]byte, 8), []byte(handler), make([]byte, 13))
+	stsd := mp4Box("stsd", make([]byte, 4), u32(1), mp4Box(codec, make([]byte, 8)))
+	minf := mp4Box("minf", mp4Box("stbl", stsd))
+	return mp4Box("trak", append(children, mp4Box("mdia", hdlr, minf))...)
+}
+
+var (
+	identity  = [9]int32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	rotated90 = [9]int32{0, 0x10000, 0, -0x10000, 0, 0, 0, 0, 0x40000000}
+)
+
+func testMP4(matrix [9]int32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 12500)
+
+	moov := mp4Box("moov",
+		mp4Box("mvhd", mvhd),
+		mp4Track("vide", "avc1", 1920, 1080, matrix),
+		mp4Track("soun", "mp4a", 0, 0, identity),
+	)
+	// An mdat of size 0 runs to the end of the file.
+	mdat := append(u32(0), "mdat\x00\x00\x00\x00"...)
+	return bytes.Join([][]byte{mp4Box("ftyp", []byte("isom"), u32(0x200), []byte("isomiso2")), moov, mdat}, nil)
+}
+
+func TestProbeMP4(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [9]int32
+		want   Info
+	}{
+		{"landscape", identity, Info{Duration: 12500 * time.Millisecond, Width: 1920, Height: 1080, VideoCodec: "h264", AudioCodec: "aac"}},
+		{"rotated", rotated90, Info{Duration: 12500 * time.Millisecond, Width: 1080, Height: 1920, VideoCodec: "h264", AudioCodec: "aac"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeBytes(t, testMP4(tt.matrix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Probe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeMP4Malformed(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(0))
+
+	noMoov := append(append([]byte{}, ftyp...), mp4Box("free", make([]byte, 8))...)
+	overrun := append(append([]byte{}, ftyp...), u32(4096)...)
+	overrun = append(overrun, "moov\x00\x00\x00\x00"...)
+
+	for name, data := range map[string][]byte{"no moov": noMoov, "box overruns file": overrun} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := probeBytes(t, data); !errors.Is(err, ErrMalformed) {
+				t.Errorf("err = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+// ebml encodes an element with its id, marker bits included, and a size.
+func ebml(id uint32, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	n := len(body)
+	if n < 0x7F {
+		out = append(out, 0x80|byte(n))
+	} else {
+		out = append(out, 0x40|byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+// ebmlUnknown is an element of unknown size, running to the end of its
+// parent.
+func ebmlUnknown(id uint32, payload ...[]byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, id)
+	out = append(out, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	return append(out, bytes.Join(payload, nil)...)
+}
+
+func uintBytes(v uint64) []byte {
+	b := binary.BigEndian.AppendUint64(nil, v)
+	return bytes.TrimLeft(b, "\x00")
+}
+
+func testWebM() []byte {
+	video := ebml(mkvVideo,
+		ebml(mkvPixelW, uintBytes(640)),
+		ebml(mkvPixelH, uintBytes(480)),
+		ebml(mkvDisplayW, uintBytes(853)),
+		ebml(mkvDisplayH, uintBytes(480)),
+	)
+	tracks := ebml(mkvTracks,
+		ebml(mkvTrackEntry, ebml(mkvTrackType, uintBytes(1)), ebml(mkvCodecID, []byte("V_VP9")), video),
+		ebml(mkvTrackEntry, ebml(mkvTrackType, uintBytes(2)), ebml(mkvCodecID, []byte("A_OPUS"))),
+	)
+	info := ebml(mkvInfo,
+		ebml(mkvTimescale, uintBytes(1_000_000)),
+		ebml(mkvDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(4500))),
+	)
+	cluster := ebml(mkvCluster, make([]byte, 32))
+
+	return append(
+		ebml(ebmlHeader, ebml(0x4282, []byte("webm"))),
+		ebmlUnknown(mkvSegment, info, tracks, cluster)...,
+	)
+}
+
+func TestProbeWebM(t *testing.T) {
+	got, err := probeBytes(t, testWebM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The display size wins over the coded one.
+	want := Info{Duration: 4500 * time.Millisecond, Width: 853, Height: 480, VideoCodec: "vp9", AudioCodec: "opus"}
+	if got != want {
+		t.Errorf("Probe = %+v, want %+v", got, want)
+	}
+}
+
+func TestProbeWebMNoSegment(t *testing.T) {
+	data := ebml(ebmlHeader, ebml(0x4282, []byte("webm")))
+	if _, err := probeBytes(t, data); !errors.Is(err, ErrMalformed) {
+		t.Errorf("err = %v, want ErrMalformed", err)
+	}
+}
+
+func TestProbeUnsupported(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"mp3":   []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"),
+		"short": []byte("ftyp"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := probeBytes(t, data); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("err = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+}
+
+func TestPosterOffset(t *testing.T) {
+	tests := map[time.Duration]time.Duration{
+		0:                      time.Second,
+		time.Second:            500 * time.Millisecond,
+		2 * time.Second:        time.Second,
+		10 * time.Minute:       time.Second,
+		300 * time.Millisecond: 150 * time.Millisecond,
+	}
+	for d, want := range tests {
+		if got := PosterOffset(d); got != want {
+			t.Errorf("PosterOffset(%s) = %s, want %s", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FFmpeg probes with ffprobe and extracts posters with ffmpeg.
+type FFmpeg struct {
+	ffprobe string
+	// ffmpeg is empty when the binary is missing; Poster then reports
+	// ErrUnsupported.
+	ffmpeg string
+}
+
+// NewFFmpeg resolves both binaries, by name on PATH or by path. ok is false
+// when ffprobe is missing, in which case the caller should fall back to a
+// pure Go prober.
+func NewFFmpeg(ffprobePath, ffmpegPath string) (*FFmpeg, bool) {
+	probe, err := exec.LookPath(ffprobePath)
+	if ffprobePath == "" || err != nil {
+		return nil, false
+	}
+
+	f := &FFmpeg{ffprobe: probe}
+	if ffmpegPath != "" {
+		if p, err := exec.LookPath(ffmpegPath); err == nil {
+			f.ffmpeg = p
+		}
+	}
+	return f, true
+}
+
+type ffprobeOutput struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Duration  string `json:"duration"`
+		Tags      struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+func parseSeconds(s string) time.Duration {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+// inputPath is where the binaries find the file handed to run: the first
+// extra file is descriptor 3 of the child.
+const inputPath = "/dev/fd/3"
+
+// run executes a binary with in open as inputPath and returns its stdout,
+// with stderr in the error. The binary never resolves the original path, so
+// it reads exactly the file the caller opened.
+func run(ctx context.Context, in *os.File, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.ExtraFiles = []*os.File{in}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Probe implements Prober.
+func (f *FFmpeg) Probe(ctx context.Context, in *os.File) (Info, error) {
+	out, err := run(ctx, in, f.ffprobe,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		inputPath,
+	)
+	if err != nil {
+		return Info{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	var res ffprobeOutput
+	if err := json.Unmarshal(out, &res); err != nil {
+		return Info{}, fmt.Errorf("ffprobe output: %w", err)
+	}
+
+	info := Info{Duration: parseSeconds(res.Format.Duration)}
+	for _, s := range res.Streams {
+		switch s.CodecType {
+		case "video":
+			// Cover art in audio files shows up as a one-frame video stream.
+			if info.VideoCodec != "" || s.CodecName == "mjpeg" || s.CodecName == "png" {
+				continue
+			}
+			info.VideoCodec = s.CodecName
+			info.Width, info.Height = s.Width, s.Height
+
+			rotation := 0.0
+			if r, err := strconv.ParseFloat(s.Tags.Rotate, 64); err == nil {
+				rotation = r
+			}
+			for _, sd := range s.SideDataList {
+				if sd.Rotation != 0 {
+					rotation = sd.Rotation
+				}
+			}
+			if int(rotation)%180 != 0 {
+				info.Width, info.Height = info.Height, info.Width
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = s.CodecName
+			}
+		default:
+			continue
+		}
+		if info.Duration == 0 {
+			info.Duration = parseSeconds(s.Duration)
+		}
+	}
+	return info, nil
+}
+
+// Poster implements PosterExtractor.
+func (f *FFmpeg) Poster(ctx context.Context, in *os.File, at time.Duration) ([]byte, error) {
+	if f.ffmpeg == "" {
+		return nil, ErrUnsupported
+	}
+
+	// -ss before -i seeks on keyframes, which is fast and close enough for a
+	// preview; the rotation metadata is applied by ffmpeg itself.
+	return run(ctx, in, f.ffmpeg,
+		"-v", "error",
+		"-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", inputPath,
+		"-frames:v", "1",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"-q:v", "3",
+		"pipe:1",
+	)
+}
@@ -0,0 +1,242 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// box is one ISO BMFF (MP4, QuickTime) box: payload bytes [start, end).
+type box struct {
+	typ        string
+	start, end int64
+}
+
+// readBoxes lists the boxes in [start, end).
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var out []box
+	hdr := make([]byte, 16)
+	for off := start; off+8 <= end; {
+		if _, err := r.ReadAt(hdr[:8], off); err != nil {
+			return nil, fmt.Errorf("%w: box header: %v", ErrMalformed, err)
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		payload := off + 8
+
+		switch size {
+		case 0:
+			// The last box runs to the end of the file.
+			size = end - off
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], off+8); err != nil {
+				return nil, fmt.Errorf("%w: box header: %v", ErrMalformed, err)
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			payload += 8
+		}
+		if size < payload-off || off+size > end {
+			return nil, fmt.Errorf("%w: box %q overruns its parent", ErrMalformed, typ)
+		}
+
+		out = append(out, box{typ: typ, start: payload, end: off + size})
+		off += size
+	}
+	return out, nil
+}
+
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// readPayload reads up to max bytes of b's payload.
+func readPayload(r io.ReaderAt, b box, max int64) ([]byte, error) {
+	n := min(b.end-b.start, max)
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, b.start); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: box %q: %v", ErrMalformed, b.typ, err)
+	}
+	return buf, nil
+}
+
+// mp4Codecs maps sample entry types to the names ffprobe uses.
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"av01": "av1",
+	"vp08": "vp8",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"Opus": "opus",
+	"fLaC": "flac",
+	".mp3": "mp3",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"alac": "alac",
+}
+
+func mp4Codec(fourcc string) string {
+	if name, ok := mp4Codecs[fourcc]; ok {
+		return name
+	}
+	return strings.ToLower(strings.TrimSpace(fourcc))
+}
+
+// probeMP4 reads the movie header and the first video and audio track.
+func probeMP4(r io.ReaderAt, size int64) (Info, error) {
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return Info{}, err
+	}
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return Info{}, fmt.Errorf("%w: no moov box", ErrMalformed)
+	}
+	children, err := readBoxes(r, moov.start, moov.end)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	if mvhd, ok := findBox(children, "mvhd"); ok {
+		p, err := readPayload(r, mvhd, 32)
+		if err != nil {
+			return Info{}, err
+		}
+		info.Duration = mp4Duration(p)
+	}
+
+	for _, trak := range children {
+		if trak.typ != "trak" {
+			continue
+		}
+		if err := probeTrak(r, trak, &info); err != nil {
+			return Info{}, err
+		}
+	}
+	return info, nil
+}
+
+// mp4Duration reads the timescale and duration of an mvhd payload.
+func mp4Duration(p []byte) time.Duration {
+	var scale, dur uint64
+	switch {
+	case len(p) >= 20 && p[0] == 0:
+		scale = uint64(binary.BigEndian.Uint32(p[12:16]))
+		dur = uint64(binary.BigEndian.Uint32(p[16:20]))
+	case len(p) >= 32 && p[0] == 1:
+		scale = uint64(binary.BigEndian.Uint32(p[20:24]))
+		dur = binary.BigEndian.Uint64(p[24:32])
+	}
+	// All ones marks an unknown duration.
+	if scale == 0 || dur == 0 || dur == 0xFFFFFFFF || dur == 1<<64-1 {
+		return 0
+	}
+	return time.Duration(float64(dur) / float64(scale) * float64(time.Second))
+}
+
+func probeTrak(r io.ReaderAt, trak box, info *Info) error {
+	children, err := readBoxes(r, trak.start, trak.end)
+	if err != nil {
+		return err
+	}
+	mdia, ok := findBox(children, "mdia")
+	if !ok {
+		return nil
+	}
+	mdiaChildren, err := readBoxes(r, mdia.start, mdia.end)
+	if err != nil {
+		return err
+	}
+
+	hdlr, ok := findBox(mdiaChildren, "hdlr")
+	if !ok {
+		return nil
+	}
+	h, err := readPayload(r, hdlr, 12)
+	if err != nil || len(h) < 12 {
+		return err
+	}
+	handler := string(h[8:12])
+	if (handler == "vide" && info.VideoCodec != "") || (handler == "soun" && info.AudioCodec != "") {
+		return nil
+	}
+
+	codec, err := trakCodec(r, mdiaChildren)
+	if err != nil {
+		return err
+	}
+
+	switch handler {
+	case "vide":
+		info.VideoCodec = codec
+		if tkhd, ok := findBox(children, "tkhd"); ok {
+			p, err := readPayload(r, tkhd, 96)
+			if err != nil {
+				return err
+			}
+			info.Width, info.Height = tkhdSize(p)
+		}
+	case "soun":
+		info.AudioCodec = codec
+	}
+	return nil
+}
+
+// trakCodec reads the first sample entry type from mdia/minf/stbl/stsd.
+func trakCodec(r io.ReaderAt, mdiaChildren []box) (string, error) {
+	parent := mdiaChildren
+	for _, typ := range []string{"minf", "stbl"} {
+		b, ok := findBox(parent, typ)
+		if !ok {
+			return "", nil
+		}
+		var err error
+		if parent, err = readBoxes(r, b.start, b.end); err != nil {
+			return "", err
+		}
+	}
+
+	stsd, ok := findBox(parent, "stsd")
+	if !ok {
+		return "", nil
+	}
+	p, err := readPayload(r, stsd, 16)
+	if err != nil || len(p) < 16 {
+		return "", err
+	}
+	return mp4Codec(string(p[12:16])), nil
+}
+
+// tkhdSize reads the presentation size of a tkhd payload and swaps it for
+// tracks rotated by 90 or 270 degrees.
+func tkhdSize(p []byte) (int, int) {
+	matrix, size := 40, 76
+	if len(p) > 0 && p[0] == 1 {
+		matrix, size = 52, 88
+	}
+	if len(p) < size+8 {
+		return 0, 0
+	}
+
+	// Both are 16.16 fixed point.
+	w := int(binary.BigEndian.Uint32(p[size:size+4]) >> 16)
+	h := int(binary.BigEndian.Uint32(p[size+4:size+8]) >> 16)
+
+	a := int32(binary.BigEndian.Uint32(p[matrix : matrix+4]))
+	d := int32(binary.BigEndian.Uint32(p[matrix+16 : matrix+20]))
+	if a == 0 && d == 0 {
+		w, h = h, w
+	}
+	return w, h
+}
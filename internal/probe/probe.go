@@ -0,0 +1,51 @@
+// Package probe reads duration, dimensions and codecs from video and audio
+// files, and extracts poster frames from videos.
+package probe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+var (
+	// ErrUnsupported means the prober cannot read this kind of file. The
+	// file is not necessarily broken.
+	ErrUnsupported = errors.New("unsupported media container")
+	// ErrMalformed means the file claims a known container but its structure
+	// is broken.
+	ErrMalformed = errors.New("malformed media container")
+)
+
+// Info is what a prober found; zero fields were not found. Width and Height
+// are as displayed, after any rotation.
+type Info struct {
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+}
+
+// Prober reads the metadata of an open media file. Files are taken open
+// rather than by path so callers decide how they are reached, e.g. through a
+// rooted storage handle that refuses symlinks.
+type Prober interface {
+	Probe(ctx context.Context, f *os.File) (Info, error)
+}
+
+// PosterExtractor is implemented by probers that can decode video frames.
+type PosterExtractor interface {
+	// Poster returns the frame at the given offset as a JPEG.
+	Poster(ctx context.Context, f *os.File, at time.Duration) ([]byte, error)
+}
+
+// PosterOffset picks the poster frame of a video of length d: one second in,
+// which skips fade-ins, or halfway through shorter videos.
+func PosterOffset(d time.Duration) time.Duration {
+	if d <= 0 || d > 2*time.Second {
+		return time.Second
+	}
+	return d / 2
+}
@@ -0,0 +1,306 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Matroska element ids, marker bits included.
+const (
+	ebmlHeader    = 0x1A45DFA3
+	mkvSegment    = 0x18538067
+	mkvInfo       = 0x1549A966
+	mkvTimescale  = 0x2AD7B1
+	mkvDuration   = 0x4489
+	mkvTracks     = 0x1654AE6B
+	mkvTrackEntry = 0xAE
+	mkvTrackType  = 0x83
+	mkvCodecID    = 0x86
+	mkvVideo      = 0xE0
+	mkvPixelW     = 0xB0
+	mkvPixelH     = 0xBA
+	mkvDisplayW   = 0x54B0
+	mkvDisplayH   = 0x54BA
+	mkvCluster    = 0x1F43B675
+)
+
+// element is one EBML element: payload bytes [start, end).
+type element struct {
+	id         uint32
+	start, end int64
+}
+
+// readVint reads a variable length integer at off and returns its value, its
+// length and, for sizes, whether all value bits were set (unknown size).
+func readVint(r io.ReaderAt, off int64, keepMarker bool) (uint64, int, bool, error) {
+	var first [1]byte
+	if _, err := r.ReadAt(first[:], off); err != nil {
+		return 0, 0, false, err
+	}
+	n := 1
+	for mask := byte(0x80); n <= 8 && first[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 {
+		return 0, 0, false, fmt.Errorf("%w: bad vint", ErrMalformed)
+	}
+
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		return 0, 0, false, err
+	}
+	if !keepMarker {
+		buf[0] &= 0xFF >> n
+	}
+
+	var v uint64
+	for _, b := range buf {
+		v = v<<8 | uint64(b)
+	}
+	allOnes := v == 1<<(7*n)-1
+	return v, n, allOnes, nil
+}
+
+// readElements lists the elements in [start, end), stopping at the first
+// cluster: everything the prober needs comes before the media data.
+func readElements(r io.ReaderAt, start, end int64) ([]element, error) {
+	var out []element
+	for off := start; off < end; {
+		id, idLen, _, err := readVint(r, off, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: element id: %v", ErrMalformed, err)
+		}
+		size, sizeLen, unknown, err := readVint(r, off+int64(idLen), false)
+		if err != nil {
+			return nil, fmt.Errorf("%w: element size: %v", ErrMalformed, err)
+		}
+		if id == mkvCluster {
+			break
+		}
+
+		payload := off + int64(idLen+sizeLen)
+		elemEnd := end
+		if !unknown {
+			elemEnd = payload + int64(size)
+			if elemEnd > end {
+				// Truncated files are common; keep what fits.
+				elemEnd = end
+			}
+		}
+		out = append(out, element{id: uint32(id), start: payload, end: elemEnd})
+		off = elemEnd
+	}
+	return out, nil
+}
+
+func readBytes(r io.ReaderAt, e element) ([]byte, error) {
+	buf := make([]byte, min(e.end-e.start, 256))
+	if _, err := r.ReadAt(buf, e.start); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: element %x: %v", ErrMalformed, e.id, err)
+	}
+	return buf, nil
+}
+
+func readUint(r io.ReaderAt, e element) (uint64, error) {
+	b, err := readBytes(r, e)
+	if err != nil || len(b) > 8 {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func readFloat(r io.ReaderAt, e element) (float64, error) {
+	b, err := readBytes(r, e)
+	if err != nil {
+		return 0, err
+	}
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return 0, nil
+}
+
+// mkvCodecs maps Matroska codec ids to the names ffprobe uses.
+var mkvCodecs = map[string]string{
+	"V_VP8":            "vp8",
+	"V_VP9":            "vp9",
+	"V_AV1":            "av1",
+	"V_MPEG4/ISO/AVC":  "h264",
+	"V_MPEGH/ISO/HEVC": "hevc",
+	"A_OPUS":           "opus",
+	"A_VORBIS":         "vorbis",
+	"A_AAC":            "aac",
+	"A_FLAC":           "flac",
+	"A_MPEG/L3":        "mp3",
+}
+
+func mkvCodec(id string) string {
+	id = strings.TrimRight(id, "\x00")
+	if name, ok := mkvCodecs[id]; ok {
+		return name
+	}
+	_, name, _ := strings.Cut(id, "_")
+	return strings.ToLower(name)
+}
+
+// probeWebM reads the segment info and the first video and audio track of a
+// Matroska or WebM file.
+func probeWebM(r io.ReaderAt, size int64) (Info, error) {
+	top, err := readElements(r, 0, size)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var segment element
+	found := false
+	for _, e := range top {
+		if e.id == mkvSegment {
+			segment, found = e, true
+			break
+		}
+	}
+	if !found {
+		return Info{}, fmt.Errorf("%w: no segment", ErrMalformed)
+	}
+
+	children, err := readElements(r, segment.start, segment.end)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	for _, e := range children {
+		switch e.id {
+		case mkvInfo:
+			if info.Duration, err = webmDuration(r, e); err != nil {
+				return Info{}, err
+			}
+		case mkvTracks:
+			if err := webmTracks(r, e, &info); err != nil {
+				return Info{}, err
+			}
+		}
+	}
+	return info, nil
+}
+
+func webmDuration(r io.ReaderAt, infoElem element) (time.Duration, error) {
+	children, err := readElements(r, infoElem.start, infoElem.end)
+	if err != nil {
+		return 0, err
+	}
+
+	scale := uint64(1_000_000) // nanoseconds per tick, the Matroska default
+	var ticks float64
+	for _, e := range children {
+		switch e.id {
+		case mkvTimescale:
+			if scale, err = readUint(r, e); err != nil {
+				return 0, err
+			}
+		case mkvDuration:
+			if ticks, err = readFloat(r, e); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if ticks <= 0 || math.IsNaN(ticks) || math.IsInf(ticks, 0) {
+		return 0, nil
+	}
+	return time.Duration(ticks * float64(scale)), nil
+}
+
+func webmTracks(r io.ReaderAt, tracks element, info *Info) error {
+	entries, err := readElements(r, tracks.start, tracks.end)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.id != mkvTrackEntry {
+			continue
+		}
+		fields, err := readElements(r, entry.start, entry.end)
+		if err != nil {
+			return err
+		}
+
+		var typ uint64
+		var codec string
+		var video element
+		hasVideo := false
+		for _, f := range fields {
+			switch f.id {
+			case mkvTrackType:
+				if typ, err = readUint(r, f); err != nil {
+					return err
+				}
+			case mkvCodecID:
+				b, err := readBytes(r, f)
+				if err != nil {
+					return err
+				}
+				codec = mkvCodec(string(b))
+			case mkvVideo:
+				video, hasVideo = f, true
+			}
+		}
+
+		switch {
+		case typ == 1 && info.VideoCodec == "":
+			info.VideoCodec = codec
+			if hasVideo {
+				if info.Width, info.Height, err = webmSize(r, video); err != nil {
+					return err
+				}
+			}
+		case typ == 2 && info.AudioCodec == "":
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
+
+// webmSize prefers the display size, which accounts for anamorphic video,
+// over the coded pixel size.
+func webmSize(r io.ReaderAt, video element) (int, int, error) {
+	fields, err := readElements(r, video.start, video.end)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var pw, ph, dw, dh uint64
+	for _, f := range fields {
+		var dst *uint64
+		switch f.id {
+		case mkvPixelW:
+			dst = &pw
+		case mkvPixelH:
+			dst = &ph
+		case mkvDisplayW:
+			dst = &dw
+		case mkvDisplayH:
+			dst = &dh
+		default:
+			continue
+		}
+		if *dst, err = readUint(r, f); err != nil {
+			return 0, 0, err
+		}
+	}
+	if dw > 0 && dh > 0 {
+		return int(dw), int(dh), nil
+	}
+	return int(pw), int(ph), nil
+}
@@ -0,0 +1,69 @@
+package realtime
+
+import "sync"
+
+// subscriberBuffer bounds how far a slow client can fall behind before it is
+// disconnected; it then reconnects and catches up through replay.
+const subscriberBuffer = 64
+
+type hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[*Subscription]struct{})}
+}
+
+func (h *hub) add(topics []string) *Subscription {
+	set := make(map[string]struct{}, len(topics))
+	for _, t := range topics {
+		set[t] = struct{}{}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, topics: set, ch: ch, hub: h}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (h *hub) broadcast(ev Event) {
+	h.mu.RLock()
+	var slow []*Subscription
+	for sub := range h.subs {
+		if _, ok := sub.topics[ev.Topic]; !ok {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.remove(sub)
+	}
+}
+
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,84 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Memory is the in-process broker. It only reaches subscribers of the same
+// instance and keeps the last replaySize events for Last-Event-ID replay.
+// Ids start from the clock so they keep increasing across restarts.
+type Memory struct {
+	hub *hub
+
+	mu         sync.Mutex
+	seq        int64
+	ring       []Event
+	replaySize int
+}
+
+func NewMemory(replaySize int) *Memory {
+	if replaySize <= 0 {
+		replaySize = 1000
+	}
+	return &Memory{
+		hub:        newHub(),
+		seq:        time.Now().UnixMicro(),
+		ring:       make([]Event, 0, replaySize),
+		replaySize: replaySize,
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, topic, typ string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.seq++
+	ev := Event{ID: m.seq, Topic: topic, Type: typ, Data: payload, CreatedAt: time.Now()}
+	if len(m.ring) == m.replaySize {
+		m.ring = append(m.ring[:0], m.ring[1:]...)
+	}
+	m.ring = append(m.ring, ev)
+	// Broadcasting under the lock delivers events in id order; it never
+	// blocks on slow subscribers.
+	m.hub.broadcast(ev)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topics []string, lastEventID int64) (*Subscription, []Event, error) {
+	sub := m.hub.add(topics)
+	if lastEventID <= 0 {
+		return sub, nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// An id from the future was issued before a restart with the clock set
+	// back; nothing retained can be matched to it.
+	if lastEventID > m.seq {
+		return sub, nil, nil
+	}
+
+	var replay []Event
+	for _, ev := range m.ring {
+		if ev.ID <= lastEventID {
+			continue
+		}
+		if _, ok := sub.topics[ev.Topic]; ok {
+			replay = append(replay, ev)
+		}
+	}
+	return sub, replay, nil
+}
+
+func (m *Memory) Close() error {
+	m.hub.closeAll()
+	return nil
+}
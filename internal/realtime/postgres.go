@@ -0,0 +1,197 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pgChannel must match the channel used by the NotifyRealtimeEvent query.
+const pgChannel = "realtime_events"
+
+// Postgres persists events in realtime_events and fans them out to every
+// instance with LISTEN/NOTIFY. The notification only carries the event id,
+// which keeps payloads clear of the 8000 byte NOTIFY limit.
+//
+// Events are inserted one at a time, so ids commit in order: a reader that
+// has seen an id never later finds a smaller one appear, which is what makes
+// Last-Event-ID replay gap free. Publish holds an advisory lock for its own
+// short transaction only, so publishers wait on each other but never on, or
+// block, readers, pruning or the writes that led to the event.
+type Postgres struct {
+	db          *appdb.SQL
+	q           *dbgen.Queries
+	dsn         string
+	hub         *hub
+	replayLimit int32
+	retention   time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPostgres(sql *appdb.SQL, dsn string, replayLimit int, retention time.Duration) *Postgres {
+	if replayLimit <= 0 {
+		replayLimit = 1000
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		db:          sql,
+		q:           sql.Q,
+		dsn:         dsn,
+		hub:         newHub(),
+		replayLimit: int32(replayLimit),
+		retention:   retention,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	go p.run(ctx)
+	return p
+}
+
+func toEvent(r dbgen.RealtimeEvent) Event {
+	return Event{
+		ID:        r.ID,
+		Topic:     r.Topic,
+		Type:      r.Type,
+		Data:      r.Payload,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func (p *Postgres) Publish(ctx context.Context, topic, typ string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	// The lock is held until commit, which also sends the notification.
+	return p.db.InTx(ctx, func(q *dbgen.Queries) error {
+		if err := q.LockRealtimePublish(ctx); err != nil {
+			return fmt.Errorf("LockRealtimePublish: %w", err)
+		}
+
+		row, err := q.CreateRealtimeEvent(ctx, dbgen.CreateRealtimeEventParams{
+			Topic:   topic,
+			Type:    typ,
+			Payload: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("CreateRealtimeEvent: %w", err)
+		}
+
+		if err := q.NotifyRealtimeEvent(ctx, row.ID); err != nil {
+			return fmt.Errorf("NotifyRealtimeEvent: %w", err)
+		}
+		return nil
+	})
+}
+
+func (p *Postgres) Subscribe(ctx context.Context, topics []string, lastEventID int64) (*Subscription, []Event, error) {
+	sub := p.hub.add(topics)
+	if lastEventID <= 0 {
+		return sub, nil, nil
+	}
+
+	rows, err := p.q.ListRealtimeEventsSince(ctx, dbgen.ListRealtimeEventsSinceParams{
+		AfterID: lastEventID,
+		Topics:  topics,
+		Limit:   p.replayLimit,
+	})
+	if err != nil {
+		sub.Close()
+		return nil, nil, fmt.Errorf("ListRealtimeEventsSince: %w", err)
+	}
+
+	replay := make([]Event, 0, len(rows))
+	for _, r := range rows {
+		replay = append(replay, toEvent(r))
+	}
+	return sub, replay, nil
+}
+
+func (p *Postgres) Close() error {
+	p.cancel()
+	<-p.done
+	p.hub.closeAll()
+	return nil
+}
+
+func (p *Postgres) run(ctx context.Context) {
+	defer close(p.done)
+
+	go p.prune(ctx)
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		if err := p.listen(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("realtime listen: %v (retrying in %s)", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+	}
+}
+
+func (p *Postgres) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "listen "+pgChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		id, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		row, err := p.q.GetRealtimeEvent(ctx, id)
+		if err != nil {
+			log.Printf("realtime load event %d: %v", id, err)
+			continue
+		}
+		p.hub.broadcast(toEvent(row))
+	}
+}
+
+func (p *Postgres) prune(ctx context.Context) {
+	if p.retention <= 0 {
+		return
+	}
+
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := p.q.DeleteRealtimeEventsBefore(ctx, time.Now().Add(-p.retention)); err != nil {
+				log.Printf("realtime prune: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package realtime
+
+import (
+	"fmt"
+	"go-rest-chi/internal/config"
+	appdb "go-rest-chi/internal/db"
+)
+
+func NewFromConfig(cfg config.RealtimeConfig, sql *appdb.SQL, dsn string) (Broker, error) {
+	switch cfg.Driver {
+	case "memory":
+		return NewMemory(cfg.ReplayLimit), nil
+	case "postgres":
+		return NewPostgres(sql, dsn, cfg.ReplayLimit, cfg.Retention), nil
+	default:
+		return nil, fmt.Errorf("unknown realtime driver %v", cfg.Driver)
+	}
+}
@@ -0,0 +1,58 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	TopicFeed = "feed"
+
+	EventNotification = "notification.created"
+	EventPostCreated  = "post.created"
+	EventPostUpdated  = "post.updated"
+	EventPostDeleted  = "post.deleted"
+
+	EventMessageCreated   = "message.created"
+	EventConversationRead = "conversation.read"
+
+	EventMediaReady  = "media.ready"
+	EventMediaFailed = "media.failed"
+)
+
+// UserTopic is the private topic every authenticated stream subscribes to.
+func UserTopic(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Topic     string          `json:"topic"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Broker is the pub/sub abstraction behind the event stream. Event ids are
+// monotonically increasing per broker so clients can resume with Last-Event-ID.
+type Broker interface {
+	Publish(ctx context.Context, topic, typ string, data any) error
+	// Subscribe registers for live events on topics and returns, alongside the
+	// subscription, the retained events newer than lastEventID.
+	Subscribe(ctx context.Context, topics []string, lastEventID int64) (*Subscription, []Event, error)
+	Close() error
+}
+
+type Subscription struct {
+	C <-chan Event
+
+	topics map[string]struct{}
+	ch     chan Event
+	hub    *hub
+}
+
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/models"
+)
+
+var (
+	ErrBlockNotFound = errors.New("block not found")
+	ErrMuteNotFound  = errors.New("mute not found")
+)
+
+type BlockRepository interface {
+	Block(ctx context.Context, blockerID, blockedID int64) error
+	Unblock(ctx context.Context, blockerID, blockedID int64) error
+	ListBlocks(ctx context.Context, blockerID int64, limit, offset int32) ([]models.RelatedUser, error)
+	IsBlockedEither(ctx context.Context, userA, userB int64) (bool, error)
+	Mute(ctx context.Context, muterID, mutedID int64) error
+	Unmute(ctx context.Context, muterID, mutedID int64) error
+	ListMutes(ctx context.Context, muterID int64, limit, offset int32) ([]models.RelatedUser, error)
+	HiddenUserIDs(ctx context.Context, userID int64) ([]int64, error)
+}
+
+type blockRepo struct {
+	q *dbgen.Queries
+}
+
+func NewBlockRepository(db *appdb.SQL) BlockRepository {
+	return &blockRepo{q: db.Q}
+}
+
+// Block implements BlockRepository.
+func (b *blockRepo) Block(ctx context.Context, blockerID int64, blockedID int64) error {
+	return b.q.CreateBlock(ctx, dbgen.CreateBlockParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+}
+
+// Unblock implements BlockRepository.
+func (b *blockRepo) Unblock(ctx context.Context, blockerID int64, blockedID int64) error {
+	affected, err := b.q.DeleteBlock(ctx, dbgen.DeleteBlockParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		return fmt.Errorf("DeleteBlock: %w", err)
+	}
+	if affected == 0 {
+		return ErrBlockNotFound
+	}
+	return nil
+}
+
+// ListBlocks implements BlockRepository.
+func (b *blockRepo) ListBlocks(ctx context.Context, blockerID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	rows, err := b.q.ListBlocks(ctx, dbgen.ListBlocksParams{
+		BlockerID: blockerID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListBlocks: %w", err)
+	}
+
+	out := make([]models.RelatedUser, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.RelatedUser{UserID: r.UserID, Username: r.Username, CreatedAt: r.CreatedAt})
+	}
+	return out, nil
+}
+
+// IsBlockedEither implements BlockRepository.
+func (b *blockRepo) IsBlockedEither(ctx context.Context, userA int64, userB int64) (bool, error) {
+	return b.q.IsBlockedEither(ctx, dbgen.IsBlockedEitherParams{
+		UserA: userA,
+		UserB: userB,
+	})
+}
+
+// Mute implements BlockRepository.
+func (b *blockRepo) Mute(ctx context.Context, muterID int64, mutedID int64) error {
+	return b.q.CreateMute(ctx, dbgen.CreateMuteParams{
+		MuterID: muterID,
+		MutedID: mutedID,
+	})
+}
+
+// Unmute implements BlockRepository.
+func (b *blockRepo) Unmute(ctx context.Context, muterID int64, mutedID int64) error {
+	affected, err := b.q.DeleteMute(ctx, dbgen.DeleteMuteParams{
+		MuterID: muterID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		return fmt.Errorf("DeleteMute: %w", err)
+	}
+	if affected == 0 {
+		return ErrMuteNotFound
+	}
+	return nil
+}
+
+// ListMutes implements BlockRepository.
+func (b *blockRepo) ListMutes(ctx context.Context, muterID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	rows, err := b.q.ListMutes(ctx, dbgen.ListMutesParams{
+		MuterID: muterID,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListMutes: %w", err)
+	}
+
+	out := make([]models.RelatedUser, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.RelatedUser{UserID: r.UserID, Username: r.Username, CreatedAt: r.CreatedAt})
+	}
+	return out, nil
+}
+
+// HiddenUserIDs implements BlockRepository.
+func (b *blockRepo) HiddenUserIDs(ctx context.Context, userID int64) ([]int64, error) {
+	return b.q.ListHiddenUserIDs(ctx, userID)
+}
@@ -0,0 +1,391 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+	"time"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrMessageNotFound      = errors.New("message not found")
+)
+
+type ConversationRepository interface {
+	Create(ctx context.Context, kind string, title *string, createdBy int64, participantIDs []int64) (models.Conversation, error)
+	// CreateDirect returns the direct conversation between createdBy and
+	// otherID, creating it unless one exists. Of racing calls only one
+	// creates it; the others return it.
+	CreateDirect(ctx context.Context, createdBy, otherID int64) (models.Conversation, error)
+	FindDirect(ctx context.Context, userA, userB int64) (models.Conversation, error)
+	GetForUser(ctx context.Context, id, userID int64) (models.Conversation, error)
+	ListByUser(ctx context.Context, userID int64, beforeAt *time.Time, beforeID *int64, limit int32) ([]models.Conversation, error)
+	CreateMessage(ctx context.Context, conversationID, senderID int64, body string, mediaIDs []int64) (models.Message, error)
+	ListMessages(ctx context.Context, conversationID int64, beforeID *int64, limit int32) ([]models.Message, error)
+	MarkRead(ctx context.Context, conversationID, userID, messageID int64) error
+}
+
+type conversationRepo struct {
+	db *appdb.SQL
+	q  *dbgen.Queries
+}
+
+func NewConversationRepository(db *appdb.SQL) ConversationRepository {
+	return &conversationRepo{db: db, q: db.Q}
+}
+
+func toConversationModel(c dbgen.Conversation) models.Conversation {
+	return models.Conversation{
+		ID:             c.ID,
+		Kind:           c.Kind,
+		Title:          helpers.PtrFromNull(c.Title.Valid, c.Title.String),
+		CreatedBy:      c.CreatedBy,
+		LastActivityAt: c.LastActivityAt,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+func toMessageModel(m dbgen.Message) models.Message {
+	return models.Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+		DeletedAt:      m.DeletedAt,
+		Medias:         []models.Media{},
+	}
+}
+
+func (c *conversationRepo) participantsByConversation(ctx context.Context, ids []int64) (map[int64][]models.Participant, error) {
+	rows, err := c.q.ListConversationParticipants(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ListConversationParticipants: %w", err)
+	}
+
+	out := make(map[int64][]models.Participant, len(ids))
+	for _, r := range rows {
+		out[r.ConversationID] = append(out[r.ConversationID], models.Participant{
+			UserID:            r.UserID,
+			Username:          r.Username,
+			LastReadMessageID: helpers.PtrFromNull(r.LastReadMessageID.Valid, r.LastReadMessageID.Int64),
+			JoinedAt:          r.JoinedAt,
+		})
+	}
+	return out, nil
+}
+
+func (c *conversationRepo) withParticipants(ctx context.Context, conv models.Conversation) (models.Conversation, error) {
+	parts, err := c.participantsByConversation(ctx, []int64{conv.ID})
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	conv.Participants = parts[conv.ID]
+	return conv, nil
+}
+
+// Create implements ConversationRepository.
+func (c *conversationRepo) Create(ctx context.Context, kind string, title *string, createdBy int64, participantIDs []int64) (models.Conversation, error) {
+	t := helpers.ToNull(title, func(v string) sql.NullString {
+		return sql.NullString{String: v, Valid: true}
+	})
+
+	var row dbgen.Conversation
+	err := c.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = q.CreateConversation(ctx, dbgen.CreateConversationParams{
+			Kind:      kind,
+			Title:     t,
+			CreatedBy: createdBy,
+		})
+		if err != nil {
+			return fmt.Errorf("CreateConversation: %w", err)
+		}
+
+		for _, uid := range participantIDs {
+			if err := q.AddConversationParticipant(ctx, dbgen.AddConversationParticipantParams{
+				ConversationID: row.ID,
+				UserID:         uid,
+			}); err != nil {
+				return fmt.Errorf("AddConversationParticipant: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	return c.withParticipants(ctx, toConversationModel(row))
+}
+
+// errDirectExists rolls back a direct conversation another request created
+// first.
+var errDirectExists = errors.New("direct conversation exists")
+
+// CreateDirect implements ConversationRepository.
+func (c *conversationRepo) CreateDirect(ctx context.Context, createdBy int64, otherID int64) (models.Conversation, error) {
+	conv, err := c.FindDirect(ctx, createdBy, otherID)
+	if !errors.Is(err, ErrConversationNotFound) {
+		return conv, err
+	}
+
+	var row dbgen.Conversation
+	err = c.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = q.CreateConversation(ctx, dbgen.CreateConversationParams{
+			Kind:      models.ConversationDirect,
+			CreatedBy: createdBy,
+		})
+		if err != nil {
+			return fmt.Errorf("CreateConversation: %w", err)
+		}
+
+		// Waits for a racing insert of the same pair to commit or roll back.
+		affected, err := q.AddDirectConversation(ctx, dbgen.AddDirectConversationParams{
+			ConversationID: row.ID,
+			UserA:          createdBy,
+			UserB:          otherID,
+		})
+		if err != nil {
+			return fmt.Errorf("AddDirectConversation: %w", err)
+		}
+		if affected == 0 {
+			return errDirectExists
+		}
+
+		for _, uid := range []int64{createdBy, otherID} {
+			if err := q.AddConversationParticipant(ctx, dbgen.AddConversationParticipantParams{
+				ConversationID: row.ID,
+				UserID:         uid,
+			}); err != nil {
+				return fmt.Errorf("AddConversationParticipant: %w", err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errDirectExists) {
+		return c.FindDirect(ctx, createdBy, otherID)
+	}
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	return c.withParticipants(ctx, toConversationModel(row))
+}
+
+// FindDirect implements ConversationRepository.
+func (c *conversationRepo) FindDirect(ctx context.Context, userA int64, userB int64) (models.Conversation, error) {
+	row, err := c.q.FindDirectConversation(ctx, dbgen.FindDirectConversationParams{
+		UserA: userA,
+		UserB: userB,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Conversation{}, ErrConversationNotFound
+		}
+		return models.Conversation{}, fmt.Errorf("FindDirectConversation: %w", err)
+	}
+
+	return c.withParticipants(ctx, toConversationModel(row))
+}
+
+// GetForUser implements ConversationRepository.
+func (c *conversationRepo) GetForUser(ctx context.Context, id int64, userID int64) (models.Conversation, error) {
+	row, err := c.q.GetConversationForUser(ctx, dbgen.GetConversationForUserParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Conversation{}, ErrConversationNotFound
+		}
+		return models.Conversation{}, fmt.Errorf("GetConversationForUser: %w", err)
+	}
+
+	return c.withParticipants(ctx, toConversationModel(row))
+}
+
+// ListByUser implements ConversationRepository.
+func (c *conversationRepo) ListByUser(ctx context.Context, userID int64, beforeAt *time.Time, beforeID *int64, limit int32) ([]models.Conversation, error) {
+	bid := helpers.ToNull(beforeID, func(v int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: v, Valid: true}
+	})
+
+	rows, err := c.q.ListConversationsByUser(ctx, dbgen.ListConversationsByUserParams{
+		UserID:   userID,
+		BeforeAt: beforeAt,
+		BeforeID: bid,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListConversationsByUser: %w", err)
+	}
+
+	ids := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+
+	parts, err := c.participantsByConversation(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Conversation, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.Conversation{
+			ID:                r.ID,
+			Kind:              r.Kind,
+			Title:             helpers.PtrFromNull(r.Title.Valid, r.Title.String),
+			CreatedBy:         r.CreatedBy,
+			LastActivityAt:    r.LastActivityAt,
+			CreatedAt:         r.CreatedAt,
+			UpdatedAt:         r.UpdatedAt,
+			LastReadMessageID: helpers.PtrFromNull(r.LastReadMessageID.Valid, r.LastReadMessageID.Int64),
+			UnreadCount:       r.UnreadCount,
+			Participants:      parts[r.ID],
+		})
+	}
+	return out, nil
+}
+
+// CreateMessage implements ConversationRepository.
+func (c *conversationRepo) CreateMessage(ctx context.Context, conversationID int64, senderID int64, body string, mediaIDs []int64) (models.Message, error) {
+	var row dbgen.Message
+	err := c.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = q.CreateMessage(ctx, dbgen.CreateMessageParams{
+			ConversationID: conversationID,
+			SenderID:       senderID,
+			Body:           body,
+		})
+		if err != nil {
+			return fmt.Errorf("CreateMessage: %w", err)
+		}
+
+		for pos, mid := range mediaIDs {
+			if err := q.AttachMediaToMessage(ctx, dbgen.AttachMediaToMessageParams{
+				MessageID: row.ID,
+				MediaID:   mid,
+				Position:  int32(pos),
+			}); err != nil {
+				return fmt.Errorf("AttachMediaToMessage: %w", err)
+			}
+		}
+
+		if err := q.TouchConversation(ctx, conversationID); err != nil {
+			return fmt.Errorf("TouchConversation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	msgs, err := c.attachMedia(ctx, []models.Message{toMessageModel(row)})
+	if err != nil {
+		return models.Message{}, err
+	}
+	return msgs[0], nil
+}
+
+// ListMessages implements ConversationRepository.
+func (c *conversationRepo) ListMessages(ctx context.Context, conversationID int64, beforeID *int64, limit int32) ([]models.Message, error) {
+	bid := helpers.ToNull(beforeID, func(v int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: v, Valid: true}
+	})
+
+	rows, err := c.q.ListMessagesPaginated(ctx, dbgen.ListMessagesPaginatedParams{
+		ConversationID: conversationID,
+		BeforeID:       bid,
+		Limit:          limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListMessagesPaginated: %w", err)
+	}
+
+	out := make([]models.Message, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, toMessageModel(r))
+	}
+	return c.attachMedia(ctx, out)
+}
+
+func (c *conversationRepo) attachMedia(ctx context.Context, msgs []models.Message) ([]models.Message, error) {
+	if len(msgs) == 0 {
+		return msgs, nil
+	}
+
+	ids := make([]int64, 0, len(msgs))
+	idx := make(map[int64]int, len(msgs))
+	for i, m := range msgs {
+		ids = append(ids, m.ID)
+		idx[m.ID] = i
+	}
+
+	rows, err := c.q.ListMessageMedia(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ListMessageMedia: %w", err)
+	}
+
+	for _, r := range rows {
+		i := idx[r.MessageID]
+		msgs[i].Medias = append(msgs[i].Medias, toMediaModel(dbgen.Medium{
+			ID:         r.ID,
+			OwnerID:    r.OwnerID,
+			Kind:       r.Kind,
+			StorageKey: r.StorageKey,
+			MimeType:   r.MimeType,
+			SizeBytes:  r.SizeBytes,
+			Width:      r.Width,
+			Height:     r.Height,
+			DurationMs: r.DurationMs,
+			CreatedAt:  r.CreatedAt,
+			DeletedAt:  r.DeletedAt,
+			Status:     r.Status,
+			VideoCodec: r.VideoCodec,
+			AudioCodec: r.AudioCodec,
+			PosterKey:  r.PosterKey,
+
+			Blurhash:      r.Blurhash,
+			DominantColor: r.DominantColor,
+			ContentHash:   r.ContentHash,
+			AltText:       r.AltText,
+		}))
+	}
+
+	var ms []*models.Media
+	for i := range msgs {
+		for j := range msgs[i].Medias {
+			ms = append(ms, &msgs[i].Medias[j])
+		}
+	}
+	if err := attachVariants(ctx, c.q, ms); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// MarkRead implements ConversationRepository.
+func (c *conversationRepo) MarkRead(ctx context.Context, conversationID int64, userID int64, messageID int64) error {
+	affected, err := c.q.MarkConversationRead(ctx, dbgen.MarkConversationReadParams{
+		MessageID:      messageID,
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkConversationRead: %w", err)
+	}
+	if affected == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
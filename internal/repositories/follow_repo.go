@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/models"
+)
+
+var (
+	ErrFollowNotFound        = errors.New("follow not found")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+)
+
+type FollowRepository interface {
+	// Follow records followerID following followeeID with the given status
+	// and returns the stored status, and whether the relation is new; an
+	// existing relation is left untouched.
+	Follow(ctx context.Context, followerID, followeeID int64, status string) (string, bool, error)
+	Unfollow(ctx context.Context, followerID, followeeID int64) error
+	RemoveBetween(ctx context.Context, userA, userB int64) error
+	Accept(ctx context.Context, followerID, followeeID int64) error
+	Reject(ctx context.Context, followerID, followeeID int64) error
+	AcceptAll(ctx context.Context, followeeID int64) error
+	ListRequests(ctx context.Context, followeeID int64, limit, offset int32) ([]models.RelatedUser, error)
+	FollowerIDs(ctx context.Context, followeeID int64) ([]int64, error)
+	IsFollowing(ctx context.Context, followerID, followeeID int64) (bool, error)
+}
+
+type followRepo struct {
+	q *dbgen.Queries
+}
+
+func NewFollowRepository(db *appdb.SQL) FollowRepository {
+	return &followRepo{q: db.Q}
+}
+
+// Follow implements FollowRepository.
+func (f *followRepo) Follow(ctx context.Context, followerID int64, followeeID int64, status string) (string, bool, error) {
+	row, err := f.q.UpsertFollow(ctx, dbgen.UpsertFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		Status:     status,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("UpsertFollow: %w", err)
+	}
+	return row.Status, row.Inserted, nil
+}
+
+// Unfollow implements FollowRepository.
+func (f *followRepo) Unfollow(ctx context.Context, followerID int64, followeeID int64) error {
+	affected, err := f.q.DeleteFollow(ctx, dbgen.DeleteFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		return fmt.Errorf("DeleteFollow: %w", err)
+	}
+	if affected == 0 {
+		return ErrFollowNotFound
+	}
+	return nil
+}
+
+// RemoveBetween implements FollowRepository.
+func (f *followRepo) RemoveBetween(ctx context.Context, userA int64, userB int64) error {
+	return f.q.DeleteFollowsBetween(ctx, dbgen.DeleteFollowsBetweenParams{
+		UserA: userA,
+		UserB: userB,
+	})
+}
+
+// Accept implements FollowRepository.
+func (f *followRepo) Accept(ctx context.Context, followerID int64, followeeID int64) error {
+	affected, err := f.q.AcceptFollow(ctx, dbgen.AcceptFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		return fmt.Errorf("AcceptFollow: %w", err)
+	}
+	if affected == 0 {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+// Reject implements FollowRepository.
+func (f *followRepo) Reject(ctx context.Context, followerID int64, followeeID int64) error {
+	affected, err := f.q.DeletePendingFollow(ctx, dbgen.DeletePendingFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		return fmt.Errorf("DeletePendingFollow: %w", err)
+	}
+	if affected == 0 {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+// AcceptAll implements FollowRepository.
+func (f *followRepo) AcceptAll(ctx context.Context, followeeID int64) error {
+	return f.q.AcceptAllPendingFollows(ctx, followeeID)
+}
+
+// ListRequests implements FollowRepository.
+func (f *followRepo) ListRequests(ctx context.Context, followeeID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	rows, err := f.q.ListFollowRequests(ctx, dbgen.ListFollowRequestsParams{
+		FolloweeID: followeeID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListFollowRequests: %w", err)
+	}
+
+	out := make([]models.RelatedUser, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.RelatedUser{UserID: r.UserID, Username: r.Username, CreatedAt: r.CreatedAt})
+	}
+	return out, nil
+}
+
+// FollowerIDs implements FollowRepository.
+func (f *followRepo) FollowerIDs(ctx context.Context, followeeID int64) ([]int64, error) {
+	return f.q.ListAcceptedFollowerIDs(ctx, followeeID)
+}
+
+// IsFollowing implements FollowRepository.
+func (f *followRepo) IsFollowing(ctx context.Context, followerID int64, followeeID int64) (bool, error) {
+	return f.q.IsFollowing(ctx, dbgen.IsFollowingParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/models"
+	"time"
+)
+
+// MediaGCCutoffs decide which media garbage collection removes: media
+// created before UnattachedBefore that no post or message holds, and media
+// deleted before DeletedBefore. Posts and messages deleted before
+// DeletedBefore no longer hold their media.
+type MediaGCCutoffs struct {
+	UnattachedBefore time.Time
+	DeletedBefore    time.Time
+}
+
+type MediaGCRepository interface {
+	// ListUnattached returns media, with variants, that no post or message
+	// holds, oldest first.
+	ListUnattached(ctx context.Context, c MediaGCCutoffs, limit int32) ([]models.Media, error)
+	// ListDeleted returns media, with variants, deleted past retention.
+	ListDeleted(ctx context.Context, c MediaGCCutoffs, limit int32) ([]models.Media, error)
+	// Purge deletes the row of media that is still collectable under c and
+	// drops its reference on the stored original, reporting whether it was
+	// the last. Media attached since it was listed gives ErrMediaNotFound.
+	Purge(ctx context.Context, id int64, c MediaGCCutoffs) (bool, error)
+
+	// ListExpiredUploads returns direct uploads never completed in time.
+	ListExpiredUploads(ctx context.Context, limit int32) ([]models.MediaUpload, error)
+	// DeleteExpiredUpload reports false when the upload was completed or
+	// removed since it was listed.
+	DeleteExpiredUpload(ctx context.Context, id int64) (bool, error)
+	// ListExpiredResumable returns ids of resumable uploads never finished
+	// in time.
+	ListExpiredResumable(ctx context.Context, limit int32) ([]string, error)
+	// DeleteExpiredResumable deletes an expired resumable upload and returns
+	// the storage keys of its parts; ok is false when it was finished or
+	// removed since it was listed.
+	DeleteExpiredResumable(ctx context.Context, id string) (keys []string, ok bool, err error)
+
+	// KnownKeys returns the keys, of those given, that a row refers to.
+	KnownKeys(ctx context.Context, keys []string) ([]string, error)
+}
+
+type mediaGCRepo struct {
+	db *appdb.SQL
+	q  *dbgen.Queries
+}
+
+func NewMediaGCRepository(db *appdb.SQL) MediaGCRepository {
+	return &mediaGCRepo{db: db, q: db.Q}
+}
+
+// withVariants converts media rows and attaches their variants, whose
+// objects go with the original.
+func (g *mediaGCRepo) withVariants(ctx context.Context, rows []dbgen.Medium) ([]models.Media, error) {
+	out := make([]models.Media, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, toMediaModel(r))
+	}
+
+	ms := make([]*models.Media, 0, len(out))
+	for i := range out {
+		ms = append(ms, &out[i])
+	}
+	if err := attachVariants(ctx, g.q, ms); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListUnattached implements MediaGCRepository.
+func (g *mediaGCRepo) ListUnattached(ctx context.Context, c MediaGCCutoffs, limit int32) ([]models.Media, error) {
+	rows, err := g.q.ListUnattachedMedia(ctx, dbgen.ListUnattachedMediaParams{
+		CreatedBefore: c.UnattachedBefore,
+		DeletedBefore: &c.DeletedBefore,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListUnattachedMedia: %w", err)
+	}
+	return g.withVariants(ctx, rows)
+}
+
+// ListDeleted implements MediaGCRepository.
+func (g *mediaGCRepo) ListDeleted(ctx context.Context, c MediaGCCutoffs, limit int32) ([]models.Media, error) {
+	rows, err := g.q.ListDeletedMedia(ctx, dbgen.ListDeletedMediaParams{
+		DeletedBefore: &c.DeletedBefore,
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListDeletedMedia: %w", err)
+	}
+	return g.withVariants(ctx, rows)
+}
+
+// Purge implements MediaGCRepository.
+func (g *mediaGCRepo) Purge(ctx context.Context, id int64, c MediaGCCutoffs) (bool, error) {
+	last := false
+	err := g.db.InTx(ctx, func(q *dbgen.Queries) error {
+		key, err := q.PurgeMedia(ctx, dbgen.PurgeMediaParams{
+			ID:            id,
+			DeletedBefore: &c.DeletedBefore,
+			CreatedBefore: c.UnattachedBefore,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrMediaNotFound
+			}
+			return fmt.Errorf("PurgeMedia: %w", err)
+		}
+
+		last, err = releaseObject(ctx, q, key)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return last, nil
+}
+
+// ListExpiredUploads implements MediaGCRepository.
+func (g *mediaGCRepo) ListExpiredUploads(ctx context.Context, limit int32) ([]models.MediaUpload, error) {
+	rows, err := g.q.ListExpiredMediaUploads(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ListExpiredMediaUploads: %w", err)
+	}
+
+	out := make([]models.MediaUpload, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, toMediaUploadModel(r))
+	}
+	return out, nil
+}
+
+// DeleteExpiredUpload implements MediaGCRepository.
+func (g *mediaGCRepo) DeleteExpiredUpload(ctx context.Context, id int64) (bool, error) {
+	affected, err := g.q.DeleteExpiredMediaUpload(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("DeleteExpiredMediaUpload: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ListExpiredResumable implements MediaGCRepository.
+func (g *mediaGCRepo) ListExpiredResumable(ctx context.Context, limit int32) ([]string, error) {
+	ids, err := g.q.ListExpiredResumableUploadIDs(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ListExpiredResumableUploadIDs: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteExpiredResumable implements MediaGCRepository.
+func (g *mediaGCRepo) DeleteExpiredResumable(ctx context.Context, id string) ([]string, bool, error) {
+	var keys []string
+	ok := false
+	err := g.db.InTx(ctx, func(q *dbgen.Queries) error {
+		parts, err := q.ListResumableUploadParts(ctx, id)
+		if err != nil {
+			return fmt.Errorf("ListResumableUploadParts: %w", err)
+		}
+
+		// The parts go with the upload row.
+		affected, err := q.DeleteExpiredResumableUpload(ctx, id)
+		if err != nil {
+			return fmt.Errorf("DeleteExpiredResumableUpload: %w", err)
+		}
+		if affected == 0 {
+			return nil
+		}
+
+		ok = true
+		for _, p := range parts {
+			keys = append(keys, p.StorageKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return keys, ok, nil
+}
+
+// KnownKeys implements MediaGCRepository.
+func (g *mediaGCRepo) KnownKeys(ctx context.Context, keys []string) ([]string, error) {
+	known, err := g.q.ListKnownStorageKeys(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("ListKnownStorageKeys: %w", err)
+	}
+	return known, nil
+}
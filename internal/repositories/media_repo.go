@@ -3,11 +3,13 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	appdb "go-rest-chi/internal/db"
 	"go-rest-chi/internal/dbgen"
 	"go-rest-chi/internal/helpers"
 	"go-rest-chi/internal/models"
+	"time"
 )
 
 type CreateMediaParams struct {
@@ -19,20 +21,183 @@ type CreateMediaParams struct {
 	Width      *int32
 	Height     *int32
 	DurationMs *int32
+	Status     string
+	// ContentHash, when set, deduplicates the stored object: the media row
+	// points at the object of an earlier upload by the same owner with the
+	// same hash instead of StorageKey, and the caller deletes its own copy.
+	ContentHash string
+	AltText     string
+}
+
+var (
+	ErrMediaNotFound   = errors.New("media not found")
+	ErrUploadNotFound  = errors.New("upload not found")
+	ErrUploadCompleted = errors.New("upload already completed")
+)
+
+// MediaKindsCheck vets the kinds of every media a post would hold. It runs
+// inside the attaching transaction, with the post locked.
+type MediaKindsCheck func(kinds []string) error
+
+type CreateUploadParams struct {
+	OwnerID    int64
+	Kind       string
+	StorageKey string
+	MimeType   string
+	SizeBytes  int64
+	ExpiresAt  time.Time
+	AltText    string
+}
+
+// MediaUsage is what one owner stores: bytes of live media, each shared
+// original counted once, and files uploaded since some time, deleted since
+// or not.
+type MediaUsage struct {
+	Bytes int64
+	Files int
 }
 
 type MediaRepository interface {
 	Create(ctx context.Context, p CreateMediaParams) (models.Media, error)
-	AttachToPost(ctx context.Context, postID, mediaID int64, position int) error
+	// AttachToPost appends media of ownerID after the media already on a
+	// post of ownerID, once check accepts the result. Any other post gives
+	// ErrPostNotFound, any other media ErrMediaNotFound.
+	AttachToPost(ctx context.Context, ownerID, postID, mediaID int64, check MediaKindsCheck) error
+	ListOwnedByIDs(ctx context.Context, ownerID int64, ids []int64) ([]models.Media, error)
+	// ListOwned pages through the live media of ownerID, newest first; an
+	// empty kind lists every kind.
+	ListOwned(ctx context.Context, ownerID int64, kind string, limit, offset int32) ([]models.Media, error)
+	// SoftDelete hides media of ownerID everywhere it is attached; its
+	// objects are removed by garbage collection. Media of anyone else gives
+	// ErrMediaNotFound.
+	SoftDelete(ctx context.Context, ownerID, id int64) error
+	// GetServedObject looks up the live media, variant or poster stored
+	// under key; keys of none give ErrMediaNotFound.
+	GetServedObject(ctx context.Context, key string) (models.ServedObject, error)
+	// Usage sums the media of ownerID, counting deduplicated media once at
+	// the size of their object, and files created since since.
+	Usage(ctx context.Context, ownerID int64, since time.Time) (MediaUsage, error)
+	// UpdateAltText replaces the alt text of media of ownerID; an empty alt
+	// clears it. Media of anyone else gives ErrMediaNotFound.
+	UpdateAltText(ctx context.Context, ownerID, id int64, alt string) (models.Media, error)
+	CreateVariant(ctx context.Context, mediaID int64, v models.MediaVariant) (models.MediaVariant, error)
+	CreateUpload(ctx context.Context, p CreateUploadParams) (models.MediaUpload, error)
+	GetUpload(ctx context.Context, ownerID, id int64) (models.MediaUpload, error)
+	// GetUploadByKey finds the upload whose object is PUT to key.
+	GetUploadByKey(ctx context.Context, key string) (models.MediaUpload, error)
+	// CompleteUpload creates the media row and marks the upload done in one
+	// transaction; a second completion fails with ErrUploadCompleted.
+	CompleteUpload(ctx context.Context, uploadID int64, p CreateMediaParams) (models.Media, error)
+
+	// Claim locks processing media for one worker until lockedUntil. Media
+	// that is not processing, or is held by another worker, gives
+	// ErrMediaNotFound.
+	Claim(ctx context.Context, id int64, lockedUntil time.Time) (models.Media, error)
+	// FinishProcessing stores the status and metadata of processed media and
+	// releases its lock.
+	FinishProcessing(ctx context.Context, m models.Media) error
+	// ListPending returns processing media no worker holds, oldest first.
+	ListPending(ctx context.Context, limit int32) ([]int64, error)
+
+	// ReleaseObject drops one reference to a stored original and reports
+	// whether it was the last, in which case the caller deletes the object.
+	ReleaseObject(ctx context.Context, storageKey string) (bool, error)
 }
 
 type mediaRepo struct {
-	q *dbgen.Queries
+	db *appdb.SQL
+	q  *dbgen.Queries
 }
 
 func NewMediaRepository(sql *appdb.SQL) MediaRepository {
-	return &mediaRepo{q: sql.Q}
+	return &mediaRepo{db: sql, q: sql.Q}
+
+}
+
+func toMediaVariantModel(v dbgen.MediaVariant) models.MediaVariant {
+	return models.MediaVariant{
+		Width:      v.Width,
+		Height:     v.Height,
+		StorageKey: v.StorageKey,
+		MimeType:   v.MimeType,
+		SizeBytes:  v.SizeBytes,
+	}
+}
+
+// loadVariants returns the variants of the given media, keyed by media id.
+func loadVariants(ctx context.Context, q *dbgen.Queries, mediaIDs []int64) (map[int64][]models.MediaVariant, error) {
+	out := make(map[int64][]models.MediaVariant)
+	if len(mediaIDs) == 0 {
+		return out, nil
+	}
+
+	rows, err := q.ListMediaVariants(ctx, mediaIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ListMediaVariants: %w", err)
+	}
+	for _, r := range rows {
+		out[r.MediaID] = append(out[r.MediaID], toMediaVariantModel(r))
+	}
+	return out, nil
+}
+
+// attachVariants fills Variants on every media item reachable through ms.
+func attachVariants(ctx context.Context, q *dbgen.Queries, ms []*models.Media) error {
+	ids := make([]int64, 0, len(ms))
+	for _, m := range ms {
+		ids = append(ids, m.ID)
+	}
+
+	byID, err := loadVariants(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		m.Variants = byID[m.ID]
+	}
+	return nil
+}
+
+func toMediaUploadModel(u dbgen.MediaUpload) models.MediaUpload {
+	return models.MediaUpload{
+		ID:          u.ID,
+		OwnerID:     u.OwnerID,
+		Kind:        u.Kind,
+		StorageKey:  u.StorageKey,
+		MimeType:    u.MimeType,
+		SizeBytes:   u.SizeBytes,
+		MediaID:     helpers.PtrFromNull(u.MediaID.Valid, u.MediaID.Int64),
+		ExpiresAt:   u.ExpiresAt,
+		AltText:     helpers.ValueOr(u.AltText.Valid, u.AltText.String, ""),
+		CompletedAt: u.CompletedAt,
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt32(p *int32) sql.NullInt32 {
+	return helpers.ToNull(p, func(v int32) sql.NullInt32 {
+		return sql.NullInt32{Int32: v, Valid: true}
+	})
+}
 
+func toCreateMediaArgs(p CreateMediaParams) dbgen.CreateMediaParams {
+	return dbgen.CreateMediaParams{
+		OwnerID:     p.OwnerID,
+		Kind:        p.Kind,
+		StorageKey:  p.StorageKey,
+		MimeType:    p.MimeType,
+		SizeBytes:   p.SizeBytes,
+		Width:       nullInt32(p.Width),
+		Height:      nullInt32(p.Height),
+		DurationMs:  nullInt32(p.DurationMs),
+		Status:      p.Status,
+		ContentHash: nullString(p.ContentHash),
+		AltText:     nullString(p.AltText),
+	}
 }
 
 func toMediaModel(m dbgen.Medium) models.Media {
@@ -50,49 +215,376 @@ func toMediaModel(m dbgen.Medium) models.Media {
 		Height:     height,
 		DurationMs: duration,
 		SizeBytes:  m.SizeBytes,
+		VideoCodec: helpers.ValueOr(m.VideoCodec.Valid, m.VideoCodec.String, ""),
+		AudioCodec: helpers.ValueOr(m.AudioCodec.Valid, m.AudioCodec.String, ""),
+		PosterKey:  helpers.ValueOr(m.PosterKey.Valid, m.PosterKey.String, ""),
+		Status:     m.Status,
 		CreatedAt:  m.CreatedAt,
 		DeletedAt:  m.DeletedAt,
+
+		BlurHash:      helpers.ValueOr(m.Blurhash.Valid, m.Blurhash.String, ""),
+		DominantColor: helpers.ValueOr(m.DominantColor.Valid, m.DominantColor.String, ""),
+		ContentHash:   helpers.ValueOr(m.ContentHash.Valid, m.ContentHash.String, ""),
+		AltText:       helpers.ValueOr(m.AltText.Valid, m.AltText.String, ""),
 	}
 
 	return out
 }
 
+// mediaObjectQueries are the queries that create media and count the
+// references to their stored originals.
+type mediaObjectQueries interface {
+	AcquireMediaObject(ctx context.Context, arg dbgen.AcquireMediaObjectParams) (string, error)
+	CreateMedia(ctx context.Context, arg dbgen.CreateMediaParams) (dbgen.Medium, error)
+	ReleaseMediaObject(ctx context.Context, storageKey string) (int32, error)
+	DeleteMediaObject(ctx context.Context, storageKey string) error
+}
+
+// createMedia records a media row, taking a reference on its stored object
+// first when the content hash is known.
+func createMedia(ctx context.Context, q mediaObjectQueries, p CreateMediaParams) (dbgen.Medium, error) {
+	if p.ContentHash != "" {
+		key, err := q.AcquireMediaObject(ctx, dbgen.AcquireMediaObjectParams{
+			StorageKey:  p.StorageKey,
+			OwnerID:     p.OwnerID,
+			ContentHash: p.ContentHash,
+			SizeBytes:   p.SizeBytes,
+		})
+		if err != nil {
+			return dbgen.Medium{}, fmt.Errorf("AcquireMediaObject: %w", err)
+		}
+		p.StorageKey = key
+	}
+
+	row, err := q.CreateMedia(ctx, toCreateMediaArgs(p))
+	if err != nil {
+		return dbgen.Medium{}, fmt.Errorf("CreateMedia: %w", err)
+	}
+	return row, nil
+}
+
 // Create implements MediaRepository.
 func (m *mediaRepo) Create(ctx context.Context, p CreateMediaParams) (models.Media, error) {
+	var row dbgen.Medium
+	err := m.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = createMedia(ctx, q, p)
+		return err
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return toMediaModel(row), nil
+}
 
-	width := helpers.ToNull(p.Width, func(v int32) sql.NullInt32 {
-		return sql.NullInt32{Int32: v, Valid: true}
+// AttachToPost implements MediaRepository.
+func (m *mediaRepo) AttachToPost(ctx context.Context, ownerID int64, postID int64, mediaID int64, check MediaKindsCheck) error {
+	return m.db.InTx(ctx, func(q *dbgen.Queries) error {
+		if _, err := q.LockOwnedPost(ctx, dbgen.LockOwnedPostParams{ID: postID, UserID: ownerID}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPostNotFound
+			}
+			return fmt.Errorf("LockOwnedPost: %w", err)
+		}
+
+		owned, err := q.ListOwnedMediaByIDs(ctx, dbgen.ListOwnedMediaByIDsParams{
+			Ids:     []int64{mediaID},
+			OwnerID: ownerID,
+		})
+		if err != nil {
+			return fmt.Errorf("ListOwnedMediaByIDs: %w", err)
+		}
+		if len(owned) == 0 {
+			return ErrMediaNotFound
+		}
+
+		if check != nil {
+			kinds, err := q.ListPostMediaKinds(ctx, postID)
+			if err != nil {
+				return fmt.Errorf("ListPostMediaKinds: %w", err)
+			}
+			if err := check(append(kinds, owned[0].Kind)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.AttachMediaToPost(ctx, dbgen.AttachMediaToPostParams{
+			MediaID: mediaID,
+			PostID:  postID,
+			UserID:  ownerID,
+		}); err != nil {
+			return fmt.Errorf("AttachMediaToPost: %w", err)
+		}
+		return nil
 	})
+}
 
-	height := helpers.ToNull(p.Height, func(v int32) sql.NullInt32 {
-		return sql.NullInt32{Int32: v, Valid: true}
+// ListOwnedByIDs implements MediaRepository.
+func (m *mediaRepo) ListOwnedByIDs(ctx context.Context, ownerID int64, ids []int64) ([]models.Media, error) {
+	rows, err := m.q.ListOwnedMediaByIDs(ctx, dbgen.ListOwnedMediaByIDsParams{
+		Ids:     ids,
+		OwnerID: ownerID,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("ListOwnedMediaByIDs: %w", err)
+	}
 
-	duration := helpers.ToNull(p.DurationMs, func(v int32) sql.NullInt32 {
-		return sql.NullInt32{Int32: v, Valid: true}
+	out := make([]models.Media, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, toMediaModel(r))
+	}
+	return out, nil
+}
+
+// ListOwned implements MediaRepository.
+func (m *mediaRepo) ListOwned(ctx context.Context, ownerID int64, kind string, limit int32, offset int32) ([]models.Media, error) {
+	rows, err := m.q.ListMediaByOwner(ctx, dbgen.ListMediaByOwnerParams{
+		OwnerID: ownerID,
+		Kind:    nullString(kind),
+		Limit:   limit,
+		Offset:  offset,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("ListMediaByOwner: %w", err)
+	}
+
+	out := make([]models.Media, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, toMediaModel(r))
+	}
 
-	row, err := m.q.CreateMedia(ctx, dbgen.CreateMediaParams{
+	ms := make([]*models.Media, 0, len(out))
+	for i := range out {
+		ms = append(ms, &out[i])
+	}
+	if err := attachVariants(ctx, m.q, ms); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SoftDelete implements MediaRepository.
+func (m *mediaRepo) SoftDelete(ctx context.Context, ownerID int64, id int64) error {
+	affected, err := m.q.SoftDeleteMedia(ctx, dbgen.SoftDeleteMediaParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		return fmt.Errorf("SoftDeleteMedia: %w", err)
+	}
+	if affected == 0 {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// GetServedObject implements MediaRepository.
+func (m *mediaRepo) GetServedObject(ctx context.Context, key string) (models.ServedObject, error) {
+	row, err := m.q.GetServedObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ServedObject{}, ErrMediaNotFound
+		}
+		return models.ServedObject{}, fmt.Errorf("GetServedObject: %w", err)
+	}
+	return models.ServedObject{MimeType: row.MimeType, ContentHash: row.ContentHash.String}, nil
+}
+
+// Usage implements MediaRepository.
+func (m *mediaRepo) Usage(ctx context.Context, ownerID int64, since time.Time) (MediaUsage, error) {
+	row, err := m.q.GetMediaUsage(ctx, dbgen.GetMediaUsageParams{
+		OwnerID: ownerID,
+		Since:   since,
+	})
+	if err != nil {
+		return MediaUsage{}, fmt.Errorf("GetMediaUsage: %w", err)
+	}
+	return MediaUsage{Bytes: row.TotalBytes, Files: int(row.FilesSince)}, nil
+}
+
+// UpdateAltText implements MediaRepository.
+func (m *mediaRepo) UpdateAltText(ctx context.Context, ownerID int64, id int64, alt string) (models.Media, error) {
+	row, err := m.q.UpdateMediaAltText(ctx, dbgen.UpdateMediaAltTextParams{
+		AltText: nullString(alt),
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Media{}, ErrMediaNotFound
+		}
+		return models.Media{}, fmt.Errorf("UpdateMediaAltText: %w", err)
+	}
+
+	out := toMediaModel(row)
+	if err := attachVariants(ctx, m.q, []*models.Media{&out}); err != nil {
+		return models.Media{}, err
+	}
+	return out, nil
+}
+
+// CreateUpload implements MediaRepository.
+func (m *mediaRepo) CreateUpload(ctx context.Context, p CreateUploadParams) (models.MediaUpload, error) {
+	row, err := m.q.CreateMediaUpload(ctx, dbgen.CreateMediaUploadParams{
 		OwnerID:    p.OwnerID,
 		Kind:       p.Kind,
 		StorageKey: p.StorageKey,
 		MimeType:   p.MimeType,
 		SizeBytes:  p.SizeBytes,
-		Width:      width,
-		Height:     height,
-		DurationMs: duration,
+		ExpiresAt:  p.ExpiresAt,
+		AltText:    nullString(p.AltText),
 	})
 	if err != nil {
-		return models.Media{}, fmt.Errorf("CreateMedia: %w", err)
+		return models.MediaUpload{}, fmt.Errorf("CreateMediaUpload: %w", err)
+	}
+	return toMediaUploadModel(row), nil
+}
+
+// GetUpload implements MediaRepository.
+func (m *mediaRepo) GetUpload(ctx context.Context, ownerID int64, id int64) (models.MediaUpload, error) {
+	row, err := m.q.GetMediaUpload(ctx, dbgen.GetMediaUploadParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MediaUpload{}, ErrUploadNotFound
+		}
+		return models.MediaUpload{}, fmt.Errorf("GetMediaUpload: %w", err)
+	}
+	return toMediaUploadModel(row), nil
+}
+
+// GetUploadByKey implements MediaRepository.
+func (m *mediaRepo) GetUploadByKey(ctx context.Context, key string) (models.MediaUpload, error) {
+	row, err := m.q.GetMediaUploadByKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MediaUpload{}, ErrUploadNotFound
+		}
+		return models.MediaUpload{}, fmt.Errorf("GetMediaUploadByKey: %w", err)
+	}
+	return toMediaUploadModel(row), nil
+}
+
+// CompleteUpload implements MediaRepository.
+func (m *mediaRepo) CompleteUpload(ctx context.Context, uploadID int64, p CreateMediaParams) (models.Media, error) {
+	var row dbgen.Medium
+	err := m.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = createMedia(ctx, q, p)
+		if err != nil {
+			return err
+		}
+
+		affected, err := q.CompleteMediaUpload(ctx, dbgen.CompleteMediaUploadParams{
+			ID:      uploadID,
+			MediaID: sql.NullInt64{Int64: row.ID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("CompleteMediaUpload: %w", err)
+		}
+		if affected == 0 {
+			return ErrUploadCompleted
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Media{}, err
 	}
 	return toMediaModel(row), nil
 }
 
-// AttachToPost implements MediaRepository.
-func (m *mediaRepo) AttachToPost(ctx context.Context, postID int64, mediaID int64, position int) error {
-	return m.q.AttachMediaToPost(ctx, dbgen.AttachMediaToPostParams{
-		PostID:   postID,
-		MediaID:  mediaID,
-		Position: int32(position),
+// CreateVariant implements MediaRepository.
+func (m *mediaRepo) CreateVariant(ctx context.Context, mediaID int64, v models.MediaVariant) (models.MediaVariant, error) {
+	row, err := m.q.CreateMediaVariant(ctx, dbgen.CreateMediaVariantParams{
+		MediaID:    mediaID,
+		Width:      v.Width,
+		Height:     v.Height,
+		StorageKey: v.StorageKey,
+		MimeType:   v.MimeType,
+		SizeBytes:  v.SizeBytes,
+	})
+	if err != nil {
+		return models.MediaVariant{}, fmt.Errorf("CreateMediaVariant: %w", err)
+	}
+	return toMediaVariantModel(row), nil
+}
+
+// Claim implements MediaRepository.
+func (m *mediaRepo) Claim(ctx context.Context, id int64, lockedUntil time.Time) (models.Media, error) {
+	row, err := m.q.ClaimMediaForProcessing(ctx, dbgen.ClaimMediaForProcessingParams{
+		LockedUntil: &lockedUntil,
+		ID:          id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Media{}, ErrMediaNotFound
+		}
+		return models.Media{}, fmt.Errorf("ClaimMediaForProcessing: %w", err)
+	}
+	return toMediaModel(row), nil
+}
+
+// FinishProcessing implements MediaRepository.
+func (m *mediaRepo) FinishProcessing(ctx context.Context, md models.Media) error {
+	if err := m.q.FinishMediaProcessing(ctx, dbgen.FinishMediaProcessingParams{
+		Status:        md.Status,
+		Width:         nullInt32(md.Width),
+		Height:        nullInt32(md.Height),
+		DurationMs:    nullInt32(md.DurationMs),
+		VideoCodec:    nullString(md.VideoCodec),
+		AudioCodec:    nullString(md.AudioCodec),
+		PosterKey:     nullString(md.PosterKey),
+		Blurhash:      nullString(md.BlurHash),
+		DominantColor: nullString(md.DominantColor),
+		ID:            md.ID,
+	}); err != nil {
+		return fmt.Errorf("FinishMediaProcessing: %w", err)
+	}
+	return nil
+}
+
+// ListPending implements MediaRepository.
+func (m *mediaRepo) ListPending(ctx context.Context, limit int32) ([]int64, error) {
+	ids, err := m.q.ListPendingMediaIDs(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ListPendingMediaIDs: %w", err)
+	}
+	return ids, nil
+}
+
+// releaseObject drops one reference to a stored original and reports
+// whether it was the last. Objects stored before deduplication have no row
+// and were never shared.
+func releaseObject(ctx context.Context, q mediaObjectQueries, storageKey string) (bool, error) {
+	refs, err := q.ReleaseMediaObject(ctx, storageKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ReleaseMediaObject: %w", err)
+	}
+	if refs > 0 {
+		return false, nil
+	}
+
+	if err := q.DeleteMediaObject(ctx, storageKey); err != nil {
+		return false, fmt.Errorf("DeleteMediaObject: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseObject implements MediaRepository.
+func (m *mediaRepo) ReleaseObject(ctx context.Context, storageKey string) (bool, error) {
+	last := false
+	err := m.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		last, err = releaseObject(ctx, q, storageKey)
+		return err
 	})
+	if err != nil {
+		return false, err
+	}
+	return last, nil
 }
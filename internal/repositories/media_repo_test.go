@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"go-rest-chi/internal/dbgen"
+	"testing"
+)
+
+type fakeObject struct {
+	key   string
+	owner int64
+	hash  string
+	refs  int32
+}
+
+// fakeObjectQueries keeps media_objects in memory with the semantics of the
+// queries in db/queries/media_objects.sql.
+type fakeObjectQueries struct {
+	objects map[string]*fakeObject
+	deleted []string
+}
+
+func newFakeObjectQueries() *fakeObjectQueries {
+	return &fakeObjectQueries{objects: make(map[string]*fakeObject)}
+}
+
+func (f *fakeObjectQueries) AcquireMediaObject(ctx context.Context, arg dbgen.AcquireMediaObjectParams) (string, error) {
+	for _, o := range f.objects {
+		if o.owner == arg.OwnerID && o.hash == arg.ContentHash {
+			o.refs++
+			return o.key, nil
+		}
+	}
+	f.objects[arg.StorageKey] = &fakeObject{key: arg.StorageKey, owner: arg.OwnerID, hash: arg.ContentHash, refs: 1}
+	return arg.StorageKey, nil
+}
+
+func (f *fakeObjectQueries) CreateMedia(ctx context.Context, arg dbgen.CreateMediaParams) (dbgen.Medium, error) {
+	return dbgen.Medium{OwnerID: arg.OwnerID, StorageKey: arg.StorageKey, ContentHash: arg.ContentHash}, nil
+}
+
+func (f *fakeObjectQueries) ReleaseMediaObject(ctx context.Context, storageKey string) (int32, error) {
+	o, ok := f.objects[storageKey]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	o.refs--
+	return o.refs, nil
+}
+
+func (f *fakeObjectQueries) DeleteMediaObject(ctx context.Context, storageKey string) error {
+	if o, ok := f.objects[storageKey]; ok && o.refs == 0 {
+		delete(f.objects, storageKey)
+		f.deleted = append(f.deleted, storageKey)
+	}
+	return nil
+}
+
+func TestMediaObjectRefCount(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeObjectQueries()
+
+	create := func(key, hash string) string {
+		t.Helper()
+		row, err := createMedia(ctx, q, CreateMediaParams{OwnerID: 1, StorageKey: key, ContentHash: hash})
+		if err != nil {
+			t.Fatalf("createMedia(%s): %v", key, err)
+		}
+		return row.StorageKey
+	}
+	release := func(key string) bool {
+		t.Helper()
+		last, err := releaseObject(ctx, q, key)
+		if err != nil {
+			t.Fatalf("releaseObject(%s): %v", key, err)
+		}
+		return last
+	}
+
+	if got := create("a", "h1"); got != "a" {
+		t.Fatalf("first upload stored under %q, want a", got)
+	}
+	if got := create("b", "h1"); got != "a" {
+		t.Fatalf("duplicate stored under %q, want the shared a", got)
+	}
+	if got := create("c", "h2"); got != "c" {
+		t.Fatalf("other content stored under %q, want c", got)
+	}
+
+	if release("a") {
+		t.Fatal("first release of a shared object reported the last reference")
+	}
+	if len(q.deleted) != 0 {
+		t.Fatalf("deleted %v while a reference is held", q.deleted)
+	}
+	if !release("a") {
+		t.Fatal("releasing the last reference did not report it")
+	}
+	if len(q.deleted) != 1 || q.deleted[0] != "a" {
+		t.Fatalf("deleted %v, want [a] once", q.deleted)
+	}
+	if _, ok := q.objects["c"]; !ok {
+		t.Fatal("object of other content deleted")
+	}
+
+	// The content uploaded again after its object went starts a new one.
+	if got := create("d", "h1"); got != "d" {
+		t.Fatalf("re-upload stored under %q, want d", got)
+	}
+	if !release("d") || len(q.deleted) != 2 {
+		t.Fatalf("re-upload not deleted with its only reference, deleted %v", q.deleted)
+	}
+}
+
+func TestReleaseObjectWithoutRow(t *testing.T) {
+	// Originals stored before deduplication were never shared.
+	q := newFakeObjectQueries()
+	last, err := releaseObject(context.Background(), q, "legacy")
+	if err != nil || !last {
+		t.Fatalf("releaseObject = %v, %v, want true, nil", last, err)
+	}
+}
+
+func TestCreateMediaWithoutHash(t *testing.T) {
+	q := newFakeObjectQueries()
+	row, err := createMedia(context.Background(), q, CreateMediaParams{OwnerID: 1, StorageKey: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.StorageKey != "a" || len(q.objects) != 0 {
+		t.Fatalf("media without a hash stored under %q with objects %v", row.StorageKey, q.objects)
+	}
+}
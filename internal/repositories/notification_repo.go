@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type CreateNotificationParams struct {
+	UserID   int64
+	ActorID  int64
+	Type     string
+	EntityID *int64
+	GroupKey string
+}
+
+type NotificationRepository interface {
+	Upsert(ctx context.Context, p CreateNotificationParams) (models.Notification, error)
+	ListByUser(ctx context.Context, userID int64, limit, offset int32) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userID int64) (int64, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+	IsTypeEnabled(ctx context.Context, userID int64, typ string) (bool, error)
+	ListPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+	SetPreference(ctx context.Context, userID int64, typ string, enabled bool) error
+}
+
+type notificationRepo struct {
+	db *appdb.SQL
+	q  *dbgen.Queries
+}
+
+func NewNotificationRepository(db *appdb.SQL) NotificationRepository {
+	return &notificationRepo{db: db, q: db.Q}
+}
+
+// Upsert implements NotificationRepository.
+func (n *notificationRepo) Upsert(ctx context.Context, p CreateNotificationParams) (models.Notification, error) {
+	entityID := helpers.ToNull(p.EntityID, func(v int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: v, Valid: true}
+	})
+
+	// The same actor acting again, on and off, counts once.
+	var row dbgen.RefreshNotificationActorCountRow
+	err := n.db.InTx(ctx, func(q *dbgen.Queries) error {
+		id, err := q.UpsertNotification(ctx, dbgen.UpsertNotificationParams{
+			UserID:   p.UserID,
+			ActorID:  p.ActorID,
+			Type:     p.Type,
+			EntityID: entityID,
+			GroupKey: p.GroupKey,
+		})
+		if err != nil {
+			return fmt.Errorf("UpsertNotification: %w", err)
+		}
+		if err := q.AddNotificationActor(ctx, dbgen.AddNotificationActorParams{
+			NotificationID: id,
+			ActorID:        p.ActorID,
+		}); err != nil {
+			return fmt.Errorf("AddNotificationActor: %w", err)
+		}
+		row, err = q.RefreshNotificationActorCount(ctx, id)
+		if err != nil {
+			return fmt.Errorf("RefreshNotificationActorCount: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Notification{}, err
+	}
+	return models.Notification{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		ActorID:       row.ActorID,
+		ActorUsername: row.ActorUsername,
+		Type:          row.Type,
+		EntityID:      helpers.PtrFromNull(row.EntityID.Valid, row.EntityID.Int64),
+		GroupKey:      row.GroupKey,
+		ActorCount:    row.ActorCount,
+		ReadAt:        row.ReadAt,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}, nil
+}
+
+// ListByUser implements NotificationRepository.
+func (n *notificationRepo) ListByUser(ctx context.Context, userID int64, limit int32, offset int32) ([]models.Notification, error) {
+	rows, err := n.q.ListNotificationsByUser(ctx, dbgen.ListNotificationsByUserParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListNotificationsByUser: %w", err)
+	}
+
+	out := make([]models.Notification, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.Notification{
+			ID:            r.ID,
+			UserID:        r.UserID,
+			ActorID:       r.ActorID,
+			ActorUsername: r.ActorUsername,
+			Type:          r.Type,
+			EntityID:      helpers.PtrFromNull(r.EntityID.Valid, r.EntityID.Int64),
+			GroupKey:      r.GroupKey,
+			ActorCount:    r.ActorCount,
+			ReadAt:        r.ReadAt,
+			CreatedAt:     r.CreatedAt,
+			UpdatedAt:     r.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+// CountUnread implements NotificationRepository.
+func (n *notificationRepo) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	return n.q.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkRead implements NotificationRepository.
+func (n *notificationRepo) MarkRead(ctx context.Context, userID int64, id int64) error {
+	affected, err := n.q.MarkNotificationRead(ctx, dbgen.MarkNotificationReadParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkNotificationRead: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead implements NotificationRepository.
+func (n *notificationRepo) MarkAllRead(ctx context.Context, userID int64) error {
+	return n.q.MarkAllNotificationsRead(ctx, userID)
+}
+
+// IsTypeEnabled implements NotificationRepository.
+func (n *notificationRepo) IsTypeEnabled(ctx context.Context, userID int64, typ string) (bool, error) {
+	return n.q.IsNotificationTypeEnabled(ctx, dbgen.IsNotificationTypeEnabledParams{
+		UserID: userID,
+		Type:   typ,
+	})
+}
+
+// ListPreferences implements NotificationRepository.
+func (n *notificationRepo) ListPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	rows, err := n.q.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ListNotificationPreferences: %w", err)
+	}
+
+	out := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		out[r.Type] = r.Enabled
+	}
+	return out, nil
+}
+
+// SetPreference implements NotificationRepository.
+func (n *notificationRepo) SetPreference(ctx context.Context, userID int64, typ string, enabled bool) error {
+	return n.q.UpsertNotificationPreference(ctx, dbgen.UpsertNotificationPreferenceParams{
+		UserID:  userID,
+		Type:    typ,
+		Enabled: enabled,
+	})
+}
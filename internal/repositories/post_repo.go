@@ -12,19 +12,42 @@ import (
 )
 
 var (
-	ErrPostNotFound  = errors.New("post not found")
-	ErrPostNotUpdate = errors.New("unable update post")
+	ErrPostNotFound       = errors.New("post not found")
+	ErrPostNotUpdate      = errors.New("unable update post")
+	ErrPostMediaNotFound  = errors.New("media not attached to post")
+	ErrMediaOrderMismatch = errors.New("media_ids must list every media of the post exactly once")
 )
 
 type PostRepository interface {
-	Create(ctx context.Context, title string, description string, userId int64) (models.Post, error)
-	SoftDelete(ctx context.Context, id int64) error
-	UpdatePartitial(ctx context.Context, id int64, title *string, description *string) (models.Post, error)
-	ListWithMediaPaginated(ctx context.Context, userId *int64, limit, offset int32) ([]models.PostMedia, error)
+	// Create stores a post with mediaIDs attached in that order, all in one
+	// transaction. Ownership of the media is the caller's to check.
+	Create(ctx context.Context, title string, description string, userId int64, visibility string, mediaIDs []int64) (models.Post, error)
+	// SoftDelete and UpdatePartitial only touch posts of ownerID; other
+	// posts give ErrPostNotFound.
+	SoftDelete(ctx context.Context, ownerID, id int64) error
+	UpdatePartitial(ctx context.Context, ownerID, id int64, title *string, description *string, visibility *string) (models.Post, error)
+	// GetWithMedia returns the post only when viewerID may see it; otherwise
+	// ErrPostNotFound, so hidden posts are indistinguishable from missing ones.
+	GetWithMedia(ctx context.Context, viewerID, id int64) (models.PostMedia, error)
+	// SetMentions replaces the users mentioned by a post.
+	SetMentions(ctx context.Context, postID int64, userIDs []int64) error
+	MentionIDs(ctx context.Context, postID int64) ([]int64, error)
+	// ListWithMediaPaginated hides authors blocked by or blocking viewerID and,
+	// outside a single user's listing, authors muted by viewerID. A zero
+	// viewerID is an anonymous caller.
+	ListWithMediaPaginated(ctx context.Context, viewerID int64, userId *int64, limit, offset int32) ([]models.PostMedia, error)
+
+	// DetachMedia removes media from a post of ownerID. A post of anyone
+	// else, or media not on it, gives ErrPostMediaNotFound.
+	DetachMedia(ctx context.Context, ownerID, postID, mediaID int64) error
+	// ReorderMedia sets the media order of a post of ownerID to mediaIDs,
+	// which must be a permutation of its media (ErrMediaOrderMismatch).
+	ReorderMedia(ctx context.Context, ownerID, postID int64, mediaIDs []int64) error
 }
 
 type postRepo struct {
-	q *dbgen.Queries
+	db *appdb.SQL
+	q  *dbgen.Queries
 }
 
 func toPostModelRow(p dbgen.Post) models.Post {
@@ -36,6 +59,7 @@ func toPostModelRow(p dbgen.Post) models.Post {
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
 		DeletedAt:   p.DeletedAt,
+		Visibility:  p.Visibility,
 	}
 }
 
@@ -45,11 +69,19 @@ func toMediaModelFromRow(r dbgen.ListPostsWithMediaPaginatedRow) (models.Media,
 	}
 
 	width := helpers.PtrFromNull(r.MediaWidth.Valid, r.MediaWidth.Int32)
-	height := helpers.PtrFromNull(r.MediaHeight.Valid, r.MediaWidth.Int32)
+	height := helpers.PtrFromNull(r.MediaHeight.Valid, r.MediaHeight.Int32)
 	duration := helpers.PtrFromNull(r.MediaDurationMs.Valid, r.MediaDurationMs.Int32)
 	kind := helpers.ValueOr(r.MediaKind.Valid, r.MediaKind.String, "")
 	mimeType := helpers.ValueOr(r.MediaMimeType.Valid, r.MediaMimeType.String, "")
 	storageKey := helpers.ValueOr(r.MediaStorageKey.Valid, r.MediaStorageKey.String, "")
+	status := helpers.ValueOr(r.MediaStatus.Valid, r.MediaStatus.String, "")
+	videoCodec := helpers.ValueOr(r.MediaVideoCodec.Valid, r.MediaVideoCodec.String, "")
+	audioCodec := helpers.ValueOr(r.MediaAudioCodec.Valid, r.MediaAudioCodec.String, "")
+	posterKey := helpers.ValueOr(r.MediaPosterKey.Valid, r.MediaPosterKey.String, "")
+	blurHash := helpers.ValueOr(r.MediaBlurhash.Valid, r.MediaBlurhash.String, "")
+	dominantColor := helpers.ValueOr(r.MediaDominantColor.Valid, r.MediaDominantColor.String, "")
+	contentHash := helpers.ValueOr(r.MediaContentHash.Valid, r.MediaContentHash.String, "")
+	altText := helpers.ValueOr(r.MediaAltText.Valid, r.MediaAltText.String, "")
 
 	m := models.Media{
 		ID:         r.MediaID.Int64,
@@ -60,25 +92,35 @@ func toMediaModelFromRow(r dbgen.ListPostsWithMediaPaginatedRow) (models.Media,
 		Width:      width,
 		Height:     height,
 		DurationMs: duration,
+		VideoCodec: videoCodec,
+		AudioCodec: audioCodec,
+		PosterKey:  posterKey,
+		Status:     status,
+		AltText:    altText,
+
+		BlurHash:      blurHash,
+		DominantColor: dominantColor,
+		ContentHash:   contentHash,
 	}
 	return m, true
 }
 
 func NewPostRepository(db *appdb.SQL) PostRepository {
-	return &postRepo{q: db.Q}
+	return &postRepo{db: db, q: db.Q}
 }
 
 // ListWithMedia implements PostRepository.
-func (p *postRepo) ListWithMediaPaginated(ctx context.Context, userId *int64, limit int32, offset int32) ([]models.PostMedia, error) {
+func (p *postRepo) ListWithMediaPaginated(ctx context.Context, viewerID int64, userId *int64, limit int32, offset int32) ([]models.PostMedia, error) {
 
 	uid := helpers.ToNull(userId, func(v int64) sql.NullInt64 {
 		return sql.NullInt64{Int64: v, Valid: true}
 	})
 
 	rows, err := p.q.ListPostsWithMediaPaginated(ctx, dbgen.ListPostsWithMediaPaginatedParams{
-		UserID: uid,
-		Limit:  limit,
-		Offset: offset,
+		UserID:   uid,
+		Limit:    limit,
+		Offset:   offset,
+		ViewerID: sql.NullInt64{Int64: viewerID, Valid: viewerID != 0},
 	})
 
 	if err != nil {
@@ -103,6 +145,7 @@ func (p *postRepo) ListWithMediaPaginated(ctx context.Context, userId *int64, li
 					CreatedAt:   pm.CreatedAt,
 					UpdatedAt:   pm.UpdatedAt,
 					DeletedAt:   pm.DeletedAt,
+					Visibility:  pm.Visibility,
 				},
 				Medias: make([]models.Media, 0, 2),
 			}
@@ -120,30 +163,131 @@ func (p *postRepo) ListWithMediaPaginated(ctx context.Context, userId *int64, li
 	for _, id := range order {
 		out = append(out, *byID[id])
 	}
+	if err := p.attachVariants(ctx, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
+func (p *postRepo) attachVariants(ctx context.Context, posts []models.PostMedia) error {
+	var ms []*models.Media
+	for i := range posts {
+		for j := range posts[i].Medias {
+			ms = append(ms, &posts[i].Medias[j])
+		}
+	}
+	return attachVariants(ctx, p.q, ms)
+}
+
+// GetWithMedia implements PostRepository.
+func (p *postRepo) GetWithMedia(ctx context.Context, viewerID int64, id int64) (models.PostMedia, error) {
+	rows, err := p.q.GetPostWithMedia(ctx, dbgen.GetPostWithMediaParams{
+		ID:       id,
+		ViewerID: sql.NullInt64{Int64: viewerID, Valid: viewerID != 0},
+	})
+	if err != nil {
+		return models.PostMedia{}, fmt.Errorf("GetPostWithMedia: %w", err)
+	}
+	if len(rows) == 0 {
+		return models.PostMedia{}, ErrPostNotFound
+	}
+
+	first := rows[0]
+	out := models.PostMedia{
+		Post: models.Post{
+			Id:          first.ID,
+			Title:       first.Title,
+			Description: first.Description,
+			UserId:      first.UserID,
+			CreatedAt:   first.CreatedAt,
+			UpdatedAt:   first.UpdatedAt,
+			DeletedAt:   first.DeletedAt,
+			Visibility:  first.Visibility,
+		},
+		Medias: make([]models.Media, 0, len(rows)),
+	}
+	for _, r := range rows {
+		if media, ok := toMediaModelFromRow(dbgen.ListPostsWithMediaPaginatedRow(r)); ok {
+			out.Medias = append(out.Medias, media)
+		}
+	}
+	if err := p.attachVariants(ctx, []models.PostMedia{out}); err != nil {
+		return models.PostMedia{}, err
+	}
+	return out, nil
+}
+
+// SetMentions implements PostRepository.
+func (p *postRepo) SetMentions(ctx context.Context, postID int64, userIDs []int64) error {
+	return p.db.InTx(ctx, func(q *dbgen.Queries) error {
+		if err := q.DeletePostMentions(ctx, postID); err != nil {
+			return fmt.Errorf("DeletePostMentions: %w", err)
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		if err := q.CreatePostMentions(ctx, dbgen.CreatePostMentionsParams{
+			PostID:  postID,
+			UserIds: userIDs,
+		}); err != nil {
+			return fmt.Errorf("CreatePostMentions: %w", err)
+		}
+		return nil
+	})
+}
+
+// MentionIDs implements PostRepository.
+func (p *postRepo) MentionIDs(ctx context.Context, postID int64) ([]int64, error) {
+	return p.q.ListPostMentionIDs(ctx, postID)
+}
+
 // Create implements PostRepository.
-func (p *postRepo) Create(ctx context.Context, title string, description string, userId int64) (models.Post, error) {
-	row, err := p.q.CreatePost(ctx, dbgen.CreatePostParams{
-		Title:       title,
-		Description: description,
-		UserID:      userId,
+func (p *postRepo) Create(ctx context.Context, title string, description string, userId int64, visibility string, mediaIDs []int64) (models.Post, error) {
+	var row dbgen.Post
+	err := p.db.InTx(ctx, func(q *dbgen.Queries) error {
+		var err error
+		row, err = q.CreatePost(ctx, dbgen.CreatePostParams{
+			Title:       title,
+			Description: description,
+			UserID:      userId,
+			Visibility:  visibility,
+		})
+		if err != nil {
+			return fmt.Errorf("CreatePost: %v", err)
+		}
+
+		for _, mid := range mediaIDs {
+			if _, err := q.AttachMediaToPost(ctx, dbgen.AttachMediaToPostParams{
+				MediaID: mid,
+				PostID:  row.ID,
+				UserID:  userId,
+			}); err != nil {
+				return fmt.Errorf("AttachMediaToPost: %w", err)
+			}
+		}
+		return nil
 	})
 	if err != nil {
-		return models.Post{}, fmt.Errorf("CreatePost: %v", err)
+		return models.Post{}, err
 	}
 
 	return toPostModelRow(row), nil
 }
 
 // SoftDelete implements PostRepository.
-func (p *postRepo) SoftDelete(ctx context.Context, id int64) error {
-	return p.q.SoftDeletePost(ctx, id)
+func (p *postRepo) SoftDelete(ctx context.Context, ownerID int64, id int64) error {
+	affected, err := p.q.SoftDeletePost(ctx, dbgen.SoftDeletePostParams{ID: id, UserID: ownerID})
+	if err != nil {
+		return fmt.Errorf("SoftDeletePost: %w", err)
+	}
+	if affected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
 }
 
 // UpdatePartitial implements PostRepository.
-func (p *postRepo) UpdatePartitial(ctx context.Context, id int64, title *string, description *string) (models.Post, error) {
+func (p *postRepo) UpdatePartitial(ctx context.Context, ownerID int64, id int64, title *string, description *string, visibility *string) (models.Post, error) {
 
 	tns := helpers.ToNull(title, func(v string) sql.NullString {
 		return sql.NullString{String: v, Valid: true}
@@ -153,14 +297,86 @@ func (p *postRepo) UpdatePartitial(ctx context.Context, id int64, title *string,
 		return sql.NullString{String: v, Valid: true}
 	})
 
+	vns := helpers.ToNull(visibility, func(v string) sql.NullString {
+		return sql.NullString{String: v, Valid: true}
+	})
+
 	row, err := p.q.UpdatePostPartial(ctx, dbgen.UpdatePostPartialParams{
 		ID:          id,
+		UserID:      ownerID,
 		Title:       tns,
 		Description: dns,
+		Visibility:  vns,
 	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Post{}, ErrPostNotFound
+		}
 		return models.Post{}, ErrPostNotUpdate
 	}
 
 	return toPostModelRow(row), nil
 }
+
+// DetachMedia implements PostRepository.
+func (p *postRepo) DetachMedia(ctx context.Context, ownerID int64, postID int64, mediaID int64) error {
+	affected, err := p.q.DetachPostMedia(ctx, dbgen.DetachPostMediaParams{
+		PostID:  postID,
+		MediaID: mediaID,
+		UserID:  ownerID,
+	})
+	if err != nil {
+		return fmt.Errorf("DetachPostMedia: %w", err)
+	}
+	if affected == 0 {
+		return ErrPostMediaNotFound
+	}
+	return nil
+}
+
+// ReorderMedia implements PostRepository. The post row is locked so that
+// concurrent attaches cannot slip in between the check and the update.
+func (p *postRepo) ReorderMedia(ctx context.Context, ownerID int64, postID int64, mediaIDs []int64) error {
+	return p.db.InTx(ctx, func(q *dbgen.Queries) error {
+		if _, err := q.LockOwnedPost(ctx, dbgen.LockOwnedPostParams{ID: postID, UserID: ownerID}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPostNotFound
+			}
+			return fmt.Errorf("LockOwnedPost: %w", err)
+		}
+
+		current, err := q.ListPostMediaIDs(ctx, postID)
+		if err != nil {
+			return fmt.Errorf("ListPostMediaIDs: %w", err)
+		}
+		if !samePermutation(current, mediaIDs) {
+			return ErrMediaOrderMismatch
+		}
+
+		if err := q.SetPostMediaPositions(ctx, dbgen.SetPostMediaPositionsParams{
+			MediaIds: mediaIDs,
+			PostID:   postID,
+		}); err != nil {
+			return fmt.Errorf("SetPostMediaPositions: %w", err)
+		}
+		return nil
+	})
+}
+
+// samePermutation reports whether b holds exactly the ids of a, in any order.
+func samePermutation(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[int64]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
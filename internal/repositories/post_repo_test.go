@@ -0,0 +1,26 @@
+package repositories
+
+import "testing"
+
+func TestSamePermutation(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []int64
+		want bool
+	}{
+		{"same order", []int64{1, 2, 3}, []int64{1, 2, 3}, true},
+		{"reordered", []int64{1, 2, 3}, []int64{3, 1, 2}, true},
+		{"both empty", nil, []int64{}, true},
+		{"missing id", []int64{1, 2, 3}, []int64{1, 2}, false},
+		{"extra id", []int64{1, 2}, []int64{1, 2, 3}, false},
+		{"foreign id", []int64{1, 2, 3}, []int64{1, 2, 4}, false},
+		{"duplicate id", []int64{1, 2, 3}, []int64{1, 1, 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samePermutation(tt.a, tt.b); got != tt.want {
+				t.Errorf("samePermutation(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
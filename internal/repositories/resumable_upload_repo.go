@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+	"time"
+)
+
+var (
+	ErrResumableUploadNotFound = errors.New("upload not found")
+	// ErrOffsetConflict means another request moved the upload first.
+	ErrOffsetConflict = errors.New("upload offset changed")
+	// ErrUploadFinishing means another request is finishing the upload.
+	ErrUploadFinishing = errors.New("upload is being finished")
+)
+
+type CreateResumableUploadParams struct {
+	ID        string
+	OwnerID   int64
+	Filename  string
+	MimeType  string
+	Length    int64
+	Metadata  string
+	AltText   string
+	ExpiresAt time.Time
+}
+
+type ResumableUploadRepository interface {
+	Create(ctx context.Context, p CreateResumableUploadParams) (models.ResumableUpload, error)
+	Get(ctx context.Context, ownerID int64, id string) (models.ResumableUpload, error)
+	// AppendPart records a stored chunk at offset and advances the upload,
+	// failing with ErrOffsetConflict when the offset is no longer current.
+	AppendPart(ctx context.Context, id string, offset int64, key string, size int64) error
+	Parts(ctx context.Context, id string) ([]models.ResumableUploadPart, error)
+	// Claim marks the upload as being finished, failing with
+	// ErrUploadFinishing while a claim made after staleBefore is held or
+	// the upload already has its media.
+	Claim(ctx context.Context, id string, staleBefore time.Time) error
+	// Release drops the claim of a finish that failed.
+	Release(ctx context.Context, id string) error
+	// Complete links the finished media and forgets the parts.
+	Complete(ctx context.Context, id string, mediaID int64) error
+	Delete(ctx context.Context, ownerID int64, id string) error
+}
+
+type resumableUploadRepo struct {
+	db *appdb.SQL
+	q  *dbgen.Queries
+}
+
+func NewResumableUploadRepository(db *appdb.SQL) ResumableUploadRepository {
+	return &resumableUploadRepo{db: db, q: db.Q}
+}
+
+func toResumableUploadModel(u dbgen.ResumableUpload) models.ResumableUpload {
+	return models.ResumableUpload{
+		ID:        u.ID,
+		OwnerID:   u.OwnerID,
+		Filename:  u.Filename,
+		MimeType:  u.MimeType,
+		Length:    u.UploadLength,
+		Offset:    u.UploadOffset,
+		Metadata:  u.Metadata,
+		AltText:   helpers.ValueOr(u.AltText.Valid, u.AltText.String, ""),
+		MediaID:   helpers.PtrFromNull(u.MediaID.Valid, u.MediaID.Int64),
+		ExpiresAt: u.ExpiresAt,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// Create implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Create(ctx context.Context, p CreateResumableUploadParams) (models.ResumableUpload, error) {
+	row, err := r.q.CreateResumableUpload(ctx, dbgen.CreateResumableUploadParams{
+		ID:           p.ID,
+		OwnerID:      p.OwnerID,
+		Filename:     p.Filename,
+		MimeType:     p.MimeType,
+		UploadLength: p.Length,
+		Metadata:     p.Metadata,
+		ExpiresAt:    p.ExpiresAt,
+		AltText:      nullString(p.AltText),
+	})
+	if err != nil {
+		return models.ResumableUpload{}, fmt.Errorf("CreateResumableUpload: %w", err)
+	}
+	return toResumableUploadModel(row), nil
+}
+
+// Get implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Get(ctx context.Context, ownerID int64, id string) (models.ResumableUpload, error) {
+	row, err := r.q.GetResumableUpload(ctx, dbgen.GetResumableUploadParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ResumableUpload{}, ErrResumableUploadNotFound
+		}
+		return models.ResumableUpload{}, fmt.Errorf("GetResumableUpload: %w", err)
+	}
+	return toResumableUploadModel(row), nil
+}
+
+// AppendPart implements ResumableUploadRepository.
+func (r *resumableUploadRepo) AppendPart(ctx context.Context, id string, offset int64, key string, size int64) error {
+	return r.db.InTx(ctx, func(q *dbgen.Queries) error {
+		affected, err := q.AdvanceResumableUpload(ctx, dbgen.AdvanceResumableUploadParams{
+			NewOffset: offset + size,
+			ID:        id,
+			OldOffset: offset,
+		})
+		if err != nil {
+			return fmt.Errorf("AdvanceResumableUpload: %w", err)
+		}
+		if affected == 0 {
+			return ErrOffsetConflict
+		}
+
+		if err := q.CreateResumableUploadPart(ctx, dbgen.CreateResumableUploadPartParams{
+			UploadID:   id,
+			PartOffset: offset,
+			StorageKey: key,
+			SizeBytes:  size,
+		}); err != nil {
+			return fmt.Errorf("CreateResumableUploadPart: %w", err)
+		}
+		return nil
+	})
+}
+
+// Parts implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Parts(ctx context.Context, id string) ([]models.ResumableUploadPart, error) {
+	rows, err := r.q.ListResumableUploadParts(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("ListResumableUploadParts: %w", err)
+	}
+
+	out := make([]models.ResumableUploadPart, 0, len(rows))
+	for _, p := range rows {
+		out = append(out, models.ResumableUploadPart{
+			Offset:     p.PartOffset,
+			StorageKey: p.StorageKey,
+			Size:       p.SizeBytes,
+		})
+	}
+	return out, nil
+}
+
+// Claim implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Claim(ctx context.Context, id string, staleBefore time.Time) error {
+	affected, err := r.q.ClaimResumableUpload(ctx, dbgen.ClaimResumableUploadParams{
+		ID:          id,
+		StaleBefore: &staleBefore,
+	})
+	if err != nil {
+		return fmt.Errorf("ClaimResumableUpload: %w", err)
+	}
+	if affected == 0 {
+		return ErrUploadFinishing
+	}
+	return nil
+}
+
+// Release implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Release(ctx context.Context, id string) error {
+	if err := r.q.ReleaseResumableUpload(ctx, id); err != nil {
+		return fmt.Errorf("ReleaseResumableUpload: %w", err)
+	}
+	return nil
+}
+
+// Complete implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Complete(ctx context.Context, id string, mediaID int64) error {
+	return r.db.InTx(ctx, func(q *dbgen.Queries) error {
+		affected, err := q.SetResumableUploadMedia(ctx, dbgen.SetResumableUploadMediaParams{
+			ID:      id,
+			MediaID: sql.NullInt64{Int64: mediaID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("SetResumableUploadMedia: %w", err)
+		}
+		if affected == 0 {
+			return ErrUploadCompleted
+		}
+
+		if err := q.DeleteResumableUploadParts(ctx, id); err != nil {
+			return fmt.Errorf("DeleteResumableUploadParts: %w", err)
+		}
+		return nil
+	})
+}
+
+// Delete implements ResumableUploadRepository.
+func (r *resumableUploadRepo) Delete(ctx context.Context, ownerID int64, id string) error {
+	if err := r.q.DeleteResumableUpload(ctx, dbgen.DeleteResumableUploadParams{
+		ID:      id,
+		OwnerID: ownerID,
+	}); err != nil {
+		return fmt.Errorf("DeleteResumableUpload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/dbgen"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+)
+
+// SearchCursor positions a relevance-ordered page after the given rank and id.
+type SearchCursor struct {
+	Rank float64
+	ID   int64
+}
+
+type SearchRepository interface {
+	// Posts runs a full-text search limited to posts viewerID may see in
+	// listings. A zero viewerID is an anonymous caller.
+	Posts(ctx context.Context, viewerID int64, query string, after *SearchCursor, limit int32) ([]models.PostSearchResult, error)
+	// Users matches usernames and display names by trigram similarity.
+	Users(ctx context.Context, viewerID int64, query string, after *SearchCursor, limit int32) ([]models.UserSearchResult, error)
+	// SyncLanguage indexes posts in the configured language, rebuilding
+	// every post's document when it changed. It returns the number rebuilt.
+	SyncLanguage(ctx context.Context) (int64, error)
+}
+
+type searchRepo struct {
+	db *appdb.SQL
+	q  *dbgen.Queries
+	// lang is the text search configuration posts are matched in.
+	lang string
+}
+
+func NewSearchRepository(db *appdb.SQL, lang string) SearchRepository {
+	return &searchRepo{db: db, q: db.Q, lang: lang}
+}
+
+func cursorParams(after *SearchCursor) (sql.NullFloat64, sql.NullInt64) {
+	if after == nil {
+		return sql.NullFloat64{}, sql.NullInt64{}
+	}
+	return sql.NullFloat64{Float64: after.Rank, Valid: true}, sql.NullInt64{Int64: after.ID, Valid: true}
+}
+
+// Posts implements SearchRepository.
+func (s *searchRepo) Posts(ctx context.Context, viewerID int64, query string, after *SearchCursor, limit int32) ([]models.PostSearchResult, error) {
+	beforeRank, beforeID := cursorParams(after)
+	rows, err := s.q.SearchPosts(ctx, dbgen.SearchPostsParams{
+		Lang:       s.lang,
+		Query:      query,
+		ViewerID:   sql.NullInt64{Int64: viewerID, Valid: viewerID != 0},
+		BeforeRank: beforeRank,
+		BeforeID:   beforeID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SearchPosts: %w", err)
+	}
+
+	out := make([]models.PostSearchResult, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.PostSearchResult{
+			ID:          r.ID,
+			Title:       r.Title,
+			Description: r.Description,
+			UserID:      r.UserID,
+			Username:    r.Username,
+			Visibility:  r.Visibility,
+			CreatedAt:   r.CreatedAt,
+			Snippet:     r.Snippet,
+			Rank:        r.Rank,
+		})
+	}
+	return out, nil
+}
+
+// Users implements SearchRepository.
+func (s *searchRepo) Users(ctx context.Context, viewerID int64, query string, after *SearchCursor, limit int32) ([]models.UserSearchResult, error) {
+	beforeRank, beforeID := cursorParams(after)
+	rows, err := s.q.SearchUsers(ctx, dbgen.SearchUsersParams{
+		Query:      query,
+		ViewerID:   sql.NullInt64{Int64: viewerID, Valid: viewerID != 0},
+		BeforeRank: beforeRank,
+		BeforeID:   beforeID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SearchUsers: %w", err)
+	}
+
+	out := make([]models.UserSearchResult, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.UserSearchResult{
+			ID:          r.ID,
+			Username:    r.Username,
+			DisplayName: helpers.PtrFromNull(r.DisplayName.Valid, r.DisplayName.String),
+			Rank:        r.Rank,
+		})
+	}
+	return out, nil
+}
+
+// SyncLanguage implements SearchRepository. Posts written by other
+// instances while it runs may keep the old language until they are edited.
+func (s *searchRepo) SyncLanguage(ctx context.Context) (int64, error) {
+	var rebuilt int64
+	err := s.db.InTx(ctx, func(q *dbgen.Queries) error {
+		changed, err := q.SetSearchConfig(ctx, s.lang)
+		if err != nil {
+			return fmt.Errorf("SetSearchConfig: %w", err)
+		}
+		if changed == 0 {
+			return nil
+		}
+		rebuilt, err = q.RebuildPostSearchDocuments(ctx, s.lang)
+		if err != nil {
+			return fmt.Errorf("RebuildPostSearchDocuments: %w", err)
+		}
+		return nil
+	})
+	return rebuilt, err
+}
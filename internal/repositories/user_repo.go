@@ -19,10 +19,13 @@ var (
 
 type UserRepository interface {
 	Create(ctx context.Context, email string, username string, hash string) (models.User, error)
+	GetByID(ctx context.Context, id int64) (models.User, error)
 	GetByEmail(ctx context.Context, email string) (models.User, error)
 	GetByUsername(ctx context.Context, username string) (models.User, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	UpdatePrivacy(ctx context.Context, id int64, isPrivate bool) (models.User, error)
+	UpdateDisplayName(ctx context.Context, id int64, displayName *string) (models.User, error)
 }
 
 type userRepo struct {
@@ -42,6 +45,8 @@ func toUserModel(u dbgen.User) models.User {
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 		DeletedAt:    u.DeletedAt,
+		IsPrivate:    u.IsPrivate,
+		DisplayName:  helpers.PtrFromNull(u.DisplayName.Valid, u.DisplayName.String),
 	}
 }
 
@@ -65,6 +70,20 @@ func (r *userRepo) Create(ctx context.Context, email string, username string, ha
 	return toUserModel(u), nil
 }
 
+func (r *userRepo) GetByID(ctx context.Context, id int64) (models.User, error) {
+	u, err := r.q.GetUserById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("GetUserById : %v", err)
+	}
+	if u.DeletedAt != nil {
+		return models.User{}, ErrUserNotFound
+	}
+	return toUserModel(u), nil
+}
+
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (models.User, error) {
 	u, err := r.q.GetUserByEmail(ctx, email)
 	if err != nil {
@@ -95,3 +114,35 @@ func (r *userRepo) ExistsByEmail(ctx context.Context, email string) (bool, error
 func (r *userRepo) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	return r.q.ExistsUserByUsername(ctx, username)
 }
+
+func (r *userRepo) UpdatePrivacy(ctx context.Context, id int64, isPrivate bool) (models.User, error) {
+	u, err := r.q.UpdateUserPrivacy(ctx, dbgen.UpdateUserPrivacyParams{
+		ID:        id,
+		IsPrivate: isPrivate,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("UpdateUserPrivacy: %w", err)
+	}
+	return toUserModel(u), nil
+}
+
+func (r *userRepo) UpdateDisplayName(ctx context.Context, id int64, displayName *string) (models.User, error) {
+	dn := helpers.ToNull(displayName, func(v string) sql.NullString {
+		return sql.NullString{String: v, Valid: true}
+	})
+
+	u, err := r.q.UpdateUserDisplayName(ctx, dbgen.UpdateUserDisplayNameParams{
+		ID:          id,
+		DisplayName: dn,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("UpdateUserDisplayName: %w", err)
+	}
+	return toUserModel(u), nil
+}
@@ -12,9 +12,10 @@ type Success struct {
 }
 
 type Err struct {
-	Status  string `json:"status"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Status  string      `json:"status"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 func JSON(w http.ResponseWriter, r *http.Request, data interface{}, status int) {
@@ -31,3 +32,9 @@ func Error(w http.ResponseWriter, r *http.Request, status int, code, msg string)
 	render.Status(r, status)
 	render.JSON(w, r, Err{Status: "error", Code: code, Message: msg})
 }
+
+// ErrorDetails is Error with machine-readable context for the client.
+func ErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, msg string, details interface{}) {
+	render.Status(r, status)
+	render.JSON(w, r, Err{Status: "error", Code: code, Message: msg, Details: details})
+}
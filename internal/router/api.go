@@ -4,14 +4,29 @@ import (
 	"go-rest-chi/internal/router/routes"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-func MountAPI(r *chi.Mux, d Deps) {
+func MountAPI(r *chi.Mux, d Deps, opts Options) {
 	r.Route("/api", func(api chi.Router) {
 		api.Route("/v1", func(v1 chi.Router) {
-			routes.MountAuth(v1, d.Services.JWT, d.Services.Users)
-			routes.MountPosts(v1, d.Services.JWT, d.Services.Posts)
-			routes.MountMedia(v1, d.Services.JWT, d.Services.Media)
+			// Long-lived stream, kept outside the request timeout.
+			routes.MountStream(v1, d.Services.JWT, d.Realtime, d.Services.Blocks, opts.StreamHeartbeat)
+			// Chunk uploads are bounded by size rather than time.
+			routes.MountTus(v1, d.Services.JWT, d.Services.Uploads)
+
+			v1.Group(func(v1 chi.Router) {
+				v1.Use(middleware.Timeout(requestTimeout))
+				routes.MountAuth(v1, d.Services.JWT, d.Services.Users)
+				routes.MountUsers(v1, d.Services.JWT, d.Services.Users)
+				routes.MountPosts(v1, d.Services.JWT, d.Services.Posts)
+				routes.MountMedia(v1, d.Services.JWT, d.Services.Media)
+				routes.MountNotifications(v1, d.Services.JWT, d.Services.Notifications)
+				routes.MountConversations(v1, d.Services.JWT, d.Services.Messages)
+				routes.MountBlocks(v1, d.Services.JWT, d.Services.Blocks)
+				routes.MountFollows(v1, d.Services.JWT, d.Services.Follows)
+				routes.MountSearch(v1, d.Services.JWT, d.Services.Search)
+			})
 		})
 	})
 }
@@ -3,17 +3,25 @@ package router
 import (
 	"go-rest-chi/internal/auth"
 	appdb "go-rest-chi/internal/db"
+	"go-rest-chi/internal/realtime"
 	"go-rest-chi/internal/services"
 )
 
 type Services struct {
-	Users services.UserService
-	Posts services.PostService
-	Media services.MediaService
-	JWT   *auth.Service
+	Users         services.UserService
+	Posts         services.PostService
+	Media         services.MediaService
+	Uploads       services.ResumableUploadService
+	Notifications services.NotificationService
+	Messages      services.MessageService
+	Blocks        services.BlockService
+	Follows       services.FollowService
+	Search        services.SearchService
+	JWT           *auth.Service
 }
 
 type Deps struct {
 	DB       *appdb.SQL
+	Realtime realtime.Broker
 	Services Services
 }
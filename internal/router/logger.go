@@ -0,0 +1,54 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// redactedParams are query parameters that carry credentials. Browsers
+// cannot set headers on an EventSource, so the stream takes its bearer
+// token as access_token.
+var redactedParams = []string{"access_token"}
+
+// redactingFormatter logs requests like middleware.Logger, with the values
+// of redactedParams replaced.
+type redactingFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f redactingFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	return f.LogFormatter.NewLogEntry(redactQuery(r))
+}
+
+// redactQuery returns r, or a shallow copy of it whose URL hides the
+// values of redactedParams.
+func redactQuery(r *http.Request) *http.Request {
+	q := r.URL.Query()
+	found := false
+	for _, name := range redactedParams {
+		if q.Has(name) {
+			q.Set(name, "REDACTED")
+			found = true
+		}
+	}
+	if !found {
+		return r
+	}
+
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	rc := r.WithContext(r.Context())
+	rc.URL = &u
+	rc.RequestURI = u.RequestURI()
+	return rc
+}
+
+// requestLogger is middleware.Logger without credentials in the logged URL.
+var requestLogger = middleware.RequestLogger(redactingFormatter{&middleware.DefaultLogFormatter{
+	Logger:  log.New(os.Stdout, "", log.LstdFlags),
+	NoColor: runtime.GOOS == "windows",
+}})
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestLoggerRedactsToken(t *testing.T) {
+	var buf bytes.Buffer
+	logger := middleware.RequestLogger(redactingFormatter{&middleware.DefaultLogFormatter{
+		Logger:  log.New(&buf, "", 0),
+		NoColor: true,
+	}})
+
+	var seen string
+	h := logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Query().Get("access_token")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream?access_token=secret.jwt&topics=a", nil))
+
+	if strings.Contains(buf.String(), "secret.jwt") {
+		t.Errorf("token logged: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "/stream?access_token=REDACTED&topics=a") {
+		t.Errorf("log line = %q", buf.String())
+	}
+	if seen != "secret.jwt" {
+		t.Errorf("handler saw access_token %q", seen)
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"go-rest-chi/internal/router/routes"
+	"go-rest-chi/internal/storage"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -19,19 +20,22 @@ type CORSOpts struct {
 }
 
 type Options struct {
-	CORS          CORSOpts
-	StorageDriver string
-	LocalDir      string
+	CORS CORSOpts
+	// LocalFS, when set, serves stored media at GET /media/* and signed
+	// direct uploads at PUT /uploads/*.
+	LocalFS         *storage.LocalFS
+	StreamHeartbeat time.Duration
 }
 
+const requestTimeout = 60 * time.Second
+
 func New(d Deps, opts Options) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   opts.CORS.AllowedOrigins,
@@ -42,10 +46,13 @@ func New(d Deps, opts Options) *chi.Mux {
 		MaxAge:           opts.CORS.MaxAge,
 	}))
 
-	MountAPI(r, d)
+	MountAPI(r, d, opts)
 
-	if opts.StorageDriver == "local" {
-		routes.MountLocalMediaStatic(r, opts.LocalDir)
+	if opts.LocalFS != nil {
+		// Downloads of large videos and uploads can both be slow; neither is
+		// held to the request timeout.
+		routes.MountLocalMediaStatic(r, opts.LocalFS, d.Services.Media)
+		routes.MountLocalUploads(r, opts.LocalFS, d.Services.Media)
 	}
 
 	return r
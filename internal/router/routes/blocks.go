@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountBlocks(r chi.Router, jwtSvc *auth.Service, blockSvc services.BlockService) {
+	h := handlers.NewBlockHandler(blockSvc)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.Middleware(jwtSvc))
+		priv.Get("/me/blocks", h.ListBlocks)
+		priv.Post("/me/blocks", h.Block)
+		priv.Delete("/me/blocks/{userId}", h.Unblock)
+		priv.Get("/me/mutes", h.ListMutes)
+		priv.Post("/me/mutes", h.Mute)
+		priv.Delete("/me/mutes/{userId}", h.Unmute)
+	})
+}
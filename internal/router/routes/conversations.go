@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountConversations(r chi.Router, jwtSvc *auth.Service, msgSvc services.MessageService) {
+	h := handlers.NewMessageHandler(msgSvc)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.Middleware(jwtSvc))
+		priv.Route("/conversations", func(rr chi.Router) {
+			rr.Get("/", h.ListConversations)
+			rr.Post("/", h.CreateConversation)
+			rr.Get("/{id}", h.GetConversation)
+			rr.Get("/{id}/messages", h.ListMessages)
+			rr.Post("/{id}/messages", h.Send)
+			rr.Post("/{id}/read", h.MarkRead)
+		})
+	})
+}
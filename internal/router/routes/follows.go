@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountFollows(r chi.Router, jwtSvc *auth.Service, followSvc services.FollowService) {
+	h := handlers.NewFollowHandler(followSvc)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.Middleware(jwtSvc))
+		priv.Post("/users/{userId}/follow", h.Follow)
+		priv.Delete("/users/{userId}/follow", h.Unfollow)
+		priv.Get("/me/follow-requests", h.ListRequests)
+		priv.Post("/me/follow-requests/{userId}/approve", h.Approve)
+		priv.Post("/me/follow-requests/{userId}/deny", h.Deny)
+		priv.Patch("/me/settings", h.UpdateSettings)
+	})
+}
@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"errors"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"go-rest-chi/internal/storage"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MountLocalUploads serves the signed PUT URLs handed out by
+// LocalFS.PresignPut, standing in for a bucket's upload endpoint. Unlike a
+// bucket it refuses PUTs once the upload was completed.
+func MountLocalUploads(r chi.Router, lfs *storage.LocalFS, svc services.MediaService) {
+	r.Put("/uploads/*", func(w http.ResponseWriter, r *http.Request) {
+		// LocalFS writes through a rooted handle; this only turns keys it
+		// would refuse anyway into a 404.
+		key := chi.URLParam(r, "*")
+		if !fs.ValidPath(key) || key == "." {
+			http.NotFound(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		mimeType := r.Header.Get("Content-Type")
+		if err := lfs.VerifyPut(key, mimeType, q.Get("expires"), q.Get("sig")); err != nil {
+			resp.Error(w, r, http.StatusForbidden, "BAD_SIGNATURE", err.Error())
+			return
+		}
+
+		size, err := svc.CheckUploadOpen(r.Context(), key)
+		switch {
+		case err == nil:
+		case errors.Is(err, services.ErrUploadNotFound):
+			resp.Error(w, r, http.StatusNotFound, "UPLOAD_NOT_FOUND", err.Error())
+			return
+		case errors.Is(err, services.ErrUploadCompleted):
+			resp.Error(w, r, http.StatusConflict, "UPLOAD_COMPLETED", err.Error())
+			return
+		case errors.Is(err, services.ErrUploadExpired):
+			resp.Error(w, r, http.StatusGone, "UPLOAD_EXPIRED", err.Error())
+			return
+		default:
+			resp.Error(w, r, http.StatusInternalServerError, "UPLOAD_FAIL", "cannot store upload")
+			return
+		}
+
+		// Large bodies may take longer than the server read timeout.
+		_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+		// Save leaves nothing behind when the body runs over the declared
+		// size, and an earlier PUT to key stays in place.
+		body := http.MaxBytesReader(w, r.Body, size)
+		if err := lfs.Save(r.Context(), key, body, r.ContentLength, mimeType); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				resp.Error(w, r, http.StatusRequestEntityTooLarge, "TOO_LARGE", "upload too large")
+				return
+			}
+			resp.Error(w, r, http.StatusInternalServerError, "UPLOAD_FAIL", "cannot store upload")
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"context"
+	"go-rest-chi/internal/services"
+	"go-rest-chi/internal/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// openUploads answers CheckUploadOpen with the declared size of each open
+// staging key.
+type openUploads struct {
+	services.MediaService
+	sizes map[string]int64
+}
+
+func (o openUploads) CheckUploadOpen(_ context.Context, key string) (int64, error) {
+	size, ok := o.sizes[key]
+	if !ok {
+		return 0, services.ErrUploadNotFound
+	}
+	return size, nil
+}
+
+const uploadKey = "staging/1/01K5A3Z6W7X8Y9Z0ABCDEFGHJK.txt"
+
+func newUploadServer(t *testing.T, size int64) (*httptest.Server, *storage.LocalFS) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+	lfs := storage.NewLocalFS(storage.LocalOptions{
+		Dir:           t.TempDir(),
+		PublicBaseURL: "http://" + srv.Listener.Addr().String(),
+		SigningSecret: "test-secret",
+		PresignTTL:    time.Minute,
+	})
+
+	r := chi.NewRouter()
+	MountLocalUploads(r, lfs, openUploads{sizes: map[string]int64{uploadKey: size}})
+	srv.Config.Handler = r
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, lfs
+}
+
+func put(t *testing.T, lfs *storage.LocalFS, body string) int {
+	t.Helper()
+
+	url, err := lfs.PresignPut(context.Background(), uploadKey, "text/plain", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func readObject(t *testing.T, lfs *storage.LocalFS) string {
+	t.Helper()
+
+	rc, err := lfs.Open(context.Background(), uploadKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLocalUploadsDeclaredSize(t *testing.T) {
+	_, lfs := newUploadServer(t, 5)
+
+	if code := put(t, lfs, "hello"); code != http.StatusOK {
+		t.Fatalf("PUT of the declared size = %d, want 200", code)
+	}
+	if code := put(t, lfs, "hello, world"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("PUT over the declared size = %d, want 413", code)
+	}
+	// The rejected PUT leaves the earlier one in place.
+	if got := readObject(t, lfs); got != "hello" {
+		t.Errorf("object = %q, want hello", got)
+	}
+}
+
+func TestLocalUploadsUnknownKey(t *testing.T) {
+	_, lfs := newUploadServer(t, 5)
+
+	url, err := lfs.PresignPut(context.Background(), "staging/1/other.txt", "text/plain", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("PUT to an unknown upload = %d, want 404", res.StatusCode)
+	}
+}
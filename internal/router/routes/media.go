@@ -13,8 +13,15 @@ func MountMedia(r chi.Router, jwt *auth.Service, mcv services.MediaService) {
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth.Middleware(jwt))
+		r.Get("/me/media", h.ListMine)
+		r.Get("/me/quota", h.Quota)
 		r.Route("/media", func(r chi.Router) {
+			r.Post("/", h.Upload)
+			r.Patch("/{id}", h.Update)
+			r.Delete("/{id}", h.Delete)
 			r.Post("/posts/{id}", h.UploadPostMedia)
+			r.Post("/uploads", h.CreateUpload)
+			r.Post("/uploads/{id}/complete", h.CompleteUpload)
 		})
 	})
 }
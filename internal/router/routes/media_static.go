@@ -1,35 +1,125 @@
 package routes
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/resp"
+	"go-rest-chi/internal/services"
+	"go-rest-chi/internal/storage"
+	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func MountLocalMediaStatic(r chi.Router, localDir string) {
-	fs := http.StripPrefix("/media/", http.FileServer(http.Dir(localDir)))
+// immutableMaxAge is how long public objects under immutable keys are
+// cached: a year, the longest caches honor.
+const immutableMaxAge = 365 * 24 * 60 * 60
 
-	r.Get("/media/*", func(w http.ResponseWriter, r *http.Request) {
-		p := chi.URLParam(r, "*")
-		clean := filepath.Clean(p)
+// MountLocalMediaStatic serves objects of the local driver at the URLs
+// handed out by LocalFS.URL and PresignGet. Unless the driver is public,
+// requests need a valid, unexpired signature. Only objects of live media
+// are served, with the type recorded for them; range requests and
+// conditional requests are answered from the file.
+func MountLocalMediaStatic(r chi.Router, lfs *storage.LocalFS, svc services.MediaService) {
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, "*")
+		if key == "" {
+			http.NotFound(w, r)
+			return
+		}
 
-		if clean == "." || clean == "/" || clean == ".." || clean == "" {
+		q := r.URL.Query()
+		if err := lfs.VerifyGet(key, q.Get("expires"), q.Get("sig")); err != nil {
+			resp.Error(w, r, http.StatusForbidden, "BAD_SIGNATURE", err.Error())
+			return
+		}
+
+		obj, err := svc.ServedObject(r.Context(), key)
+		if errors.Is(err, services.ErrMediaNotFound) {
 			http.NotFound(w, r)
 			return
 		}
+		if err != nil {
+			resp.Error(w, r, http.StatusInternalServerError, "MEDIA_FAIL", "cannot read media")
+			return
+		}
 
-		if len(clean) > 0 && clean[0] == '.' {
+		// Keys that escape the storage directory fail to open.
+		f, err := lfs.OpenFile(key)
+		if err != nil {
 			http.NotFound(w, r)
 			return
 		}
+		defer f.Close()
 
-		if _, err := os.Stat(filepath.Join(localDir, clean)); err != nil {
+		fi, err := f.Stat()
+		if err != nil || fi.IsDir() {
 			http.NotFound(w, r)
 			return
 		}
 
-		fs.ServeHTTP(w, r)
-	})
+		h := w.Header()
+		h.Set("Content-Type", obj.MimeType)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("ETag", objectETag(key, obj.ContentHash, fi.Size(), fi.ModTime()))
+		h.Set("Cache-Control", cacheControl(lfs.Public(), storage.IsImmutable(key), q.Get("expires")))
+		h.Set("Content-Disposition", contentDisposition(obj.MimeType, path.Base(key), q.Has("download")))
+
+		// Handles HEAD, Range, If-Range, If-None-Match and If-Modified-Since.
+		http.ServeContent(w, r, "", fi.ModTime(), f)
+	}
+
+	r.Get("/media/*", serve)
+	r.Head("/media/*", serve)
+}
+
+// objectETag is a strong validator: the hash of the bytes when recorded,
+// otherwise derived from the key, which immutable objects never outlive,
+// and the file's size and modification time.
+func objectETag(key, contentHash string, size int64, modTime time.Time) string {
+	if contentHash != "" {
+		return `"` + contentHash + `"`
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%d", key, size, modTime.UnixNano())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// cacheControl lets public objects be cached by anyone and signed ones by
+// the client only, no longer than the signature lasts. Objects under
+// immutable keys are never revalidated.
+func cacheControl(public, immutable bool, expires string) string {
+	if public {
+		if immutable {
+			return fmt.Sprintf("public, max-age=%d, immutable", immutableMaxAge)
+		}
+		return "public, no-cache"
+	}
+
+	exp, _ := strconv.ParseInt(expires, 10, 64)
+	cc := fmt.Sprintf("private, max-age=%d", max(0, exp-time.Now().Unix()))
+	if immutable {
+		cc += ", immutable"
+	}
+	return cc
+}
+
+// contentDisposition shows media inline unless a download is asked for;
+// anything that is not an image, video or audio is always downloaded.
+func contentDisposition(mimeType, filename string, download bool) string {
+	typ := "inline"
+	kind, _, _ := strings.Cut(mimeType, "/")
+	if download || (kind != "image" && kind != "video" && kind != "audio") {
+		typ = "attachment"
+	}
+	if d := mime.FormatMediaType(typ, map[string]string{"filename": filename}); d != "" {
+		return d
+	}
+	return typ
 }
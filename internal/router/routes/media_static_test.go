@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/services"
+	"go-rest-chi/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// servedMedia answers ServedObject from a map; the other methods are never
+// reached by the static route.
+type servedMedia struct {
+	services.MediaService
+	objects map[string]models.ServedObject
+}
+
+func (s servedMedia) ServedObject(_ context.Context, key string) (models.ServedObject, error) {
+	obj, ok := s.objects[key]
+	if !ok {
+		return models.ServedObject{}, services.ErrMediaNotFound
+	}
+	return obj, nil
+}
+
+const staticKey = "posts/2025/09/1/01K5A3Z6W7X8Y9Z0ABCDEFGHJK.png"
+
+func newStaticServer(t *testing.T, public bool) (*httptest.Server, *storage.LocalFS) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+	lfs := storage.NewLocalFS(storage.LocalOptions{
+		Dir:           t.TempDir(),
+		PublicBaseURL: "http://" + srv.Listener.Addr().String(),
+		SigningSecret: "test-secret",
+		Public:        public,
+		PresignTTL:    time.Minute,
+	})
+	body := bytes.Repeat([]byte("0123456789"), 100)
+	if err := lfs.Save(context.Background(), staticKey, bytes.NewReader(body), int64(len(body)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	MountLocalMediaStatic(r, lfs, servedMedia{objects: map[string]models.ServedObject{
+		staticKey: {MimeType: "image/png", ContentHash: "abc123"},
+	}})
+	srv.Config.Handler = r
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, lfs
+}
+
+func mediaURL(t *testing.T, lfs *storage.LocalFS) string {
+	t.Helper()
+
+	u, err := lfs.URL(context.Background(), staticKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func get(t *testing.T, url string, header http.Header) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestLocalMediaStaticRange(t *testing.T) {
+	_, lfs := newStaticServer(t, true)
+
+	res := get(t, mediaURL(t, lfs), http.Header{"Range": {"bytes=10-19"}})
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", res.StatusCode)
+	}
+	if got := res.Header.Get("Content-Range"); got != "bytes 10-19/1000" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if got := res.Header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := res.Header.Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("Cache-Control = %q, want immutable", got)
+	}
+}
+
+func TestLocalMediaStaticNotModified(t *testing.T) {
+	_, lfs := newStaticServer(t, true)
+
+	res := get(t, mediaURL(t, lfs), nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", res.StatusCode)
+	}
+	etag := res.Header.Get("ETag")
+	if etag != `"abc123"` {
+		t.Fatalf("ETag = %q", etag)
+	}
+
+	res = get(t, mediaURL(t, lfs), http.Header{"If-None-Match": {etag}})
+	if res.StatusCode != http.StatusNotModified {
+		t.Fatalf("status = %d, want 304", res.StatusCode)
+	}
+}
+
+func TestLocalMediaStaticSignature(t *testing.T) {
+	srv, lfs := newStaticServer(t, false)
+
+	if res := get(t, mediaURL(t, lfs), nil); res.StatusCode != http.StatusOK {
+		t.Errorf("signed: status = %d, want 200", res.StatusCode)
+	}
+	if res := get(t, srv.URL+"/media/"+staticKey, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("unsigned: status = %d, want 403", res.StatusCode)
+	}
+	if res := get(t, mediaURL(t, lfs)+"0", nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("tampered: status = %d, want 403", res.StatusCode)
+	}
+}
+
+func TestLocalMediaStaticUnknownKey(t *testing.T) {
+	srv, _ := newStaticServer(t, true)
+
+	if res := get(t, srv.URL+"/media/posts/2025/09/1/missing.png", nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	soon := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		public    bool
+		immutable bool
+		expires   string
+		want      []string
+	}{
+		{"public immutable", true, true, "", []string{fmt.Sprintf("public, max-age=%d, immutable", immutableMaxAge)}},
+		{"public mutable", true, false, "", []string{"public, no-cache"}},
+		// The signed URL may tick over a second while the test runs.
+		{"signed", false, false, soon, []string{"private, max-age=3600", "private, max-age=3599"}},
+		{"signed immutable", false, true, soon, []string{"private, max-age=3600, immutable", "private, max-age=3599, immutable"}},
+		{"signed expired", false, false, past, []string{"private, max-age=0"}},
+		{"signed without expiry", false, false, "", []string{"private, max-age=0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cacheControl(tt.public, tt.immutable, tt.expires)
+			for _, w := range tt.want {
+				if got == w {
+					return
+				}
+			}
+			t.Errorf("cacheControl = %q, want %q", got, tt.want[0])
+		})
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		filename string
+		download bool
+		want     string
+	}{
+		{"image", "image/jpeg", "a.jpg", false, `inline; filename=a.jpg`},
+		{"video", "video/mp4", "clip.mp4", false, `inline; filename=clip.mp4`},
+		{"audio", "audio/ogg", "song.ogg", false, `inline; filename=song.ogg`},
+		{"download", "image/jpeg", "a.jpg", true, `attachment; filename=a.jpg`},
+		{"html is downloaded", "text/html", "page.html", false, `attachment; filename=page.html`},
+		{"unknown is downloaded", "application/octet-stream", "blob", false, `attachment; filename=blob`},
+		{"quoted name", "image/png", "my photo.png", false, `inline; filename="my photo.png"`},
+		{"non-ascii name", "image/png", "café.png", false, `inline; filename*=utf-8''caf%C3%A9.png`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contentDisposition(tt.mimeType, tt.filename, tt.download); got != tt.want {
+				t.Errorf("contentDisposition = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
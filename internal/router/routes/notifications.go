@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountNotifications(r chi.Router, jwtSvc *auth.Service, notifSvc services.NotificationService) {
+	h := handlers.NewNotificationHandler(notifSvc)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.Middleware(jwtSvc))
+		priv.Get("/me/notifications", h.List)
+		priv.Post("/me/notifications/read", h.MarkAllRead)
+		priv.Post("/me/notifications/{id}/read", h.MarkRead)
+		priv.Get("/me/notifications/preferences", h.Preferences)
+		priv.Put("/me/notifications/preferences", h.UpdatePreferences)
+	})
+}
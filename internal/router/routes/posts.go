@@ -12,12 +12,15 @@ func MountPosts(r chi.Router, jwtSvc *auth.Service, posrSvc services.PostService
 	h := handlers.NewPostHandler(posrSvc)
 
 	r.Route("/posts", func(rr chi.Router) {
-		rr.Get("/", h.List)
+		rr.With(auth.OptionalMiddleware(jwtSvc)).Get("/", h.List)
+		rr.With(auth.OptionalMiddleware(jwtSvc)).Get("/{id}", h.Get)
 		rr.Group(func(priv chi.Router) {
 			priv.Use(auth.Middleware(jwtSvc))
 			priv.Post("/", h.Create)
 			priv.Patch("/{id}", h.UpdatePartial)
 			priv.Delete("/{id}", h.SoftDelete)
+			priv.Delete("/{id}/media/{mediaId}", h.DetachMedia)
+			priv.Put("/{id}/media/order", h.ReorderMedia)
 		})
 
 	})
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountSearch(r chi.Router, jwtSvc *auth.Service, searchSvc services.SearchService) {
+	h := handlers.NewSearchHandler(searchSvc)
+
+	r.With(auth.OptionalMiddleware(jwtSvc)).Get("/search", h.Search)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/realtime"
+	"go-rest-chi/internal/services"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountStream(r chi.Router, jwtSvc *auth.Service, broker realtime.Broker, blockSvc services.BlockService, heartbeat time.Duration) {
+	h := handlers.NewStreamHandler(broker, blockSvc, heartbeat)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.StreamMiddleware(jwtSvc))
+		priv.Get("/stream", h.Stream)
+	})
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountTus(r chi.Router, jwt *auth.Service, svc services.ResumableUploadService) {
+	h := handlers.NewTusHandler(svc)
+
+	r.Route("/media/tus", func(r chi.Router) {
+		r.Use(h.Protocol)
+		r.Options("/", h.Options)
+
+		r.Group(func(priv chi.Router) {
+			priv.Use(auth.Middleware(jwt))
+			priv.Post("/", h.Create)
+			priv.Head("/{id}", h.Head)
+			priv.Patch("/{id}", h.Patch)
+			priv.Delete("/{id}", h.Terminate)
+		})
+	})
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"go-rest-chi/internal/auth"
+	"go-rest-chi/internal/handlers"
+	"go-rest-chi/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func MountUsers(r chi.Router, jwtSvc *auth.Service, usersSvc services.UserService) {
+	h := handlers.NewUserHandler(usersSvc)
+
+	r.Group(func(priv chi.Router) {
+		priv.Use(auth.Middleware(jwtSvc))
+		priv.Patch("/me/profile", h.UpdateProfile)
+	})
+}
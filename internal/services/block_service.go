@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+)
+
+var (
+	ErrSelfTarget = errors.New("cannot target yourself")
+	ErrBlocked    = errors.New("interaction blocked between users")
+)
+
+type BlockService interface {
+	Block(ctx context.Context, userID, targetID int64) error
+	Unblock(ctx context.Context, userID, targetID int64) error
+	ListBlocks(ctx context.Context, userID int64, limit, offset int32) ([]models.RelatedUser, error)
+	Mute(ctx context.Context, userID, targetID int64) error
+	Unmute(ctx context.Context, userID, targetID int64) error
+	ListMutes(ctx context.Context, userID int64, limit, offset int32) ([]models.RelatedUser, error)
+	// HiddenAuthors returns the users whose content must not reach userID's
+	// feed: blocked in either direction or muted by userID.
+	HiddenAuthors(ctx context.Context, userID int64) (map[int64]struct{}, error)
+}
+
+type blockService struct {
+	repo    repositories.BlockRepository
+	users   repositories.UserRepository
+	follows repositories.FollowRepository
+}
+
+func NewBlockService(repo repositories.BlockRepository, users repositories.UserRepository, follows repositories.FollowRepository) BlockService {
+	return &blockService{repo: repo, users: users, follows: follows}
+}
+
+func (b *blockService) checkTarget(ctx context.Context, userID, targetID int64) error {
+	if userID == targetID {
+		return ErrSelfTarget
+	}
+	_, err := b.users.GetByID(ctx, targetID)
+	return err
+}
+
+// Block implements BlockService. Follows in both directions, accepted or
+// pending, are removed along with the block.
+func (b *blockService) Block(ctx context.Context, userID int64, targetID int64) error {
+	if err := b.checkTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+	if err := b.repo.Block(ctx, userID, targetID); err != nil {
+		return err
+	}
+	return b.follows.RemoveBetween(ctx, userID, targetID)
+}
+
+// Unblock implements BlockService.
+func (b *blockService) Unblock(ctx context.Context, userID int64, targetID int64) error {
+	return b.repo.Unblock(ctx, userID, targetID)
+}
+
+// ListBlocks implements BlockService.
+func (b *blockService) ListBlocks(ctx context.Context, userID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	return b.repo.ListBlocks(ctx, userID, limit, offset)
+}
+
+// Mute implements BlockService.
+func (b *blockService) Mute(ctx context.Context, userID int64, targetID int64) error {
+	if err := b.checkTarget(ctx, userID, targetID); err != nil {
+		return err
+	}
+	return b.repo.Mute(ctx, userID, targetID)
+}
+
+// Unmute implements BlockService.
+func (b *blockService) Unmute(ctx context.Context, userID int64, targetID int64) error {
+	return b.repo.Unmute(ctx, userID, targetID)
+}
+
+// ListMutes implements BlockService.
+func (b *blockService) ListMutes(ctx context.Context, userID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	return b.repo.ListMutes(ctx, userID, limit, offset)
+}
+
+// HiddenAuthors implements BlockService.
+func (b *blockService) HiddenAuthors(ctx context.Context, userID int64) (map[int64]struct{}, error) {
+	ids, err := b.repo.HiddenUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		out[id] = struct{}{}
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"log"
+)
+
+type FollowService interface {
+	// Follow follows targetID right away when the account is public and
+	// files a pending request when it is private.
+	Follow(ctx context.Context, userID, targetID int64) (models.FollowStatus, error)
+	Unfollow(ctx context.Context, userID, targetID int64) error
+	ListRequests(ctx context.Context, userID int64, limit, offset int32) ([]models.RelatedUser, error)
+	Approve(ctx context.Context, userID, requesterID int64) error
+	Deny(ctx context.Context, userID, requesterID int64) error
+	// SetPrivate toggles the account privacy. Going public accepts every
+	// pending request, since they no longer need approval.
+	SetPrivate(ctx context.Context, userID int64, isPrivate bool) (models.UserPublic, error)
+}
+
+type followService struct {
+	repo   repositories.FollowRepository
+	users  repositories.UserRepository
+	blocks repositories.BlockRepository
+	notifs NotificationService
+}
+
+func NewFollowService(repo repositories.FollowRepository, users repositories.UserRepository, blocks repositories.BlockRepository, notifs NotificationService) FollowService {
+	return &followService{repo: repo, users: users, blocks: blocks, notifs: notifs}
+}
+
+func (f *followService) notify(ctx context.Context, userID, actorID int64, typ string) {
+	if err := f.notifs.Notify(ctx, NotifyParams{UserID: userID, ActorID: actorID, Type: typ}); err != nil {
+		log.Printf("notify %s user=%d actor=%d: %v", typ, userID, actorID, err)
+	}
+}
+
+// Follow implements FollowService.
+func (f *followService) Follow(ctx context.Context, userID int64, targetID int64) (models.FollowStatus, error) {
+	if userID == targetID {
+		return models.FollowStatus{}, ErrSelfTarget
+	}
+
+	target, err := f.users.GetByID(ctx, targetID)
+	if err != nil {
+		return models.FollowStatus{}, err
+	}
+
+	blocked, err := f.blocks.IsBlockedEither(ctx, userID, targetID)
+	if err != nil {
+		return models.FollowStatus{}, err
+	}
+	if blocked {
+		return models.FollowStatus{}, ErrBlocked
+	}
+
+	status := models.FollowAccepted
+	if target.IsPrivate {
+		status = models.FollowPending
+	}
+
+	stored, created, err := f.repo.Follow(ctx, userID, targetID, status)
+	if err != nil {
+		return models.FollowStatus{}, err
+	}
+
+	// Following again changes nothing and notifies no one.
+	if created {
+		typ := models.NotificationFollow
+		if stored == models.FollowPending {
+			typ = models.NotificationFollowRequest
+		}
+		f.notify(ctx, targetID, userID, typ)
+	}
+
+	return models.FollowStatus{UserID: targetID, Status: stored}, nil
+}
+
+// Unfollow implements FollowService. It also withdraws a pending request.
+func (f *followService) Unfollow(ctx context.Context, userID int64, targetID int64) error {
+	return f.repo.Unfollow(ctx, userID, targetID)
+}
+
+// ListRequests implements FollowService.
+func (f *followService) ListRequests(ctx context.Context, userID int64, limit int32, offset int32) ([]models.RelatedUser, error) {
+	return f.repo.ListRequests(ctx, userID, limit, offset)
+}
+
+// Approve implements FollowService.
+func (f *followService) Approve(ctx context.Context, userID int64, requesterID int64) error {
+	if err := f.repo.Accept(ctx, requesterID, userID); err != nil {
+		return err
+	}
+
+	f.notify(ctx, requesterID, userID, models.NotificationFollowAccepted)
+	return nil
+}
+
+// Deny implements FollowService.
+func (f *followService) Deny(ctx context.Context, userID int64, requesterID int64) error {
+	return f.repo.Reject(ctx, requesterID, userID)
+}
+
+// SetPrivate implements FollowService.
+func (f *followService) SetPrivate(ctx context.Context, userID int64, isPrivate bool) (models.UserPublic, error) {
+	usr, err := f.users.UpdatePrivacy(ctx, userID, isPrivate)
+	if err != nil {
+		return models.UserPublic{}, err
+	}
+
+	if !isPrivate {
+		if err := f.repo.AcceptAll(ctx, userID); err != nil {
+			return models.UserPublic{}, err
+		}
+	}
+	return usr.Public(), nil
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+)
+
+type MediaGCOptions struct {
+	// Interval between runs; zero leaves running to the caller.
+	Interval time.Duration
+	// UnattachedAfter is how long media may sit on no post or message, and
+	// how old an object with no row must be, before either is removed. It
+	// must outlast the gap between storing an upload and recording it.
+	UnattachedAfter time.Duration
+	// Retention is how long deleted media, and the media of deleted posts
+	// and messages, is kept.
+	Retention time.Duration
+	// BatchSize caps the media and uploads one run removes of each sort, and
+	// the keys looked up at once while scanning storage.
+	BatchSize int
+	// DryRun logs what a run would remove and removes nothing.
+	DryRun bool
+}
+
+// MediaGCReport counts what one run removed, or would have in a dry run.
+// Bytes counts objects as stored; a dry run counts shared originals once
+// per media.
+type MediaGCReport struct {
+	DryRun          bool  `json:"dry_run"`
+	UnattachedMedia int   `json:"unattached_media"`
+	DeletedMedia    int   `json:"deleted_media"`
+	ExpiredUploads  int   `json:"expired_uploads"`
+	OrphanObjects   int   `json:"orphan_objects"`
+	Bytes           int64 `json:"bytes"`
+}
+
+func (r MediaGCReport) String() string {
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	return fmt.Sprintf("%s %d unattached media, %d deleted media, %d expired uploads, %d orphaned objects (%d bytes)",
+		verb, r.UnattachedMedia, r.DeletedMedia, r.ExpiredUploads, r.OrphanObjects, r.Bytes)
+}
+
+// MediaGC removes media nobody can reach any more along with their stored
+// objects: media never attached to a post or message, media deleted past
+// retention, uploads that expired unfinished, and objects no row refers to.
+type MediaGC interface {
+	// Run collects once. Errors of one sort of garbage do not stop the
+	// others; the report counts what was done either way.
+	Run(ctx context.Context) (MediaGCReport, error)
+	// Close stops the scheduled runs, waiting for one in progress.
+	Close() error
+}
+
+type mediaGC struct {
+	repo repositories.MediaGCRepository
+	st   storage.Storage
+	opts MediaGCOptions
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewMediaGC(repo repositories.MediaGCRepository, st storage.Storage, opts MediaGCOptions) MediaGC {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &mediaGC{repo: repo, st: st, opts: opts, cancel: cancel}
+	if opts.Interval > 0 {
+		g.wg.Add(1)
+		go g.schedule(ctx)
+	}
+	return g
+}
+
+// Close implements MediaGC.
+func (g *mediaGC) Close() error {
+	g.cancel()
+	g.wg.Wait()
+	return nil
+}
+
+func (g *mediaGC) schedule(ctx context.Context) {
+	defer g.wg.Done()
+
+	t := time.NewTicker(g.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		rep, err := g.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("media gc: %v", err)
+		}
+		if rep.DryRun || rep != (MediaGCReport{}) {
+			log.Printf("media gc: %s", rep)
+		}
+	}
+}
+
+// Run implements MediaGC.
+func (g *mediaGC) Run(ctx context.Context) (MediaGCReport, error) {
+	now := time.Now()
+	c := repositories.MediaGCCutoffs{
+		UnattachedBefore: now.Add(-g.opts.UnattachedAfter),
+		DeletedBefore:    now.Add(-g.opts.Retention),
+	}
+	rep := MediaGCReport{DryRun: g.opts.DryRun}
+
+	var errs []error
+	if err := g.collectMedia(ctx, c, false, &rep); err != nil {
+		errs = append(errs, fmt.Errorf("unattached media: %w", err))
+	}
+	if err := g.collectMedia(ctx, c, true, &rep); err != nil {
+		errs = append(errs, fmt.Errorf("deleted media: %w", err))
+	}
+	if err := g.collectUploads(ctx, &rep); err != nil {
+		errs = append(errs, fmt.Errorf("expired uploads: %w", err))
+	}
+	if err := g.collectOrphans(ctx, c.UnattachedBefore, &rep); err != nil {
+		errs = append(errs, fmt.Errorf("orphaned objects: %w", err))
+	}
+	return rep, errors.Join(errs...)
+}
+
+// deleteObjects removes stored objects; ones already gone are fine.
+func (g *mediaGC) deleteObjects(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		if err := g.st.Delete(ctx, k); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			// The object has no row any more; the orphan scan retries it.
+			log.Printf("media gc: delete %s: %v", k, err)
+		}
+	}
+}
+
+// mediaObjects lists the keys of everything stored for m and their size.
+func mediaObjects(m models.Media) ([]string, int64) {
+	keys, size := []string{m.StorageKey}, m.SizeBytes
+	for _, v := range m.Variants {
+		keys = append(keys, v.StorageKey)
+		size += v.SizeBytes
+	}
+	if m.PosterKey != "" {
+		keys = append(keys, m.PosterKey)
+	}
+	return keys, size
+}
+
+// collectMedia purges one batch of unattached media, or of deleted media.
+// Objects are removed with the last media row that shares them.
+func (g *mediaGC) collectMedia(ctx context.Context, c repositories.MediaGCCutoffs, deleted bool, rep *MediaGCReport) error {
+	list, count := g.repo.ListUnattached, &rep.UnattachedMedia
+	if deleted {
+		list, count = g.repo.ListDeleted, &rep.DeletedMedia
+	}
+
+	items, err := list(ctx, c, int32(g.opts.BatchSize))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range items {
+		keys, size := mediaObjects(m)
+		if g.opts.DryRun {
+			log.Printf("media gc (dry run): would purge media %d of user %d, %d objects", m.ID, m.OwnerID, len(keys))
+			*count++
+			rep.Bytes += size
+			continue
+		}
+
+		last, err := g.repo.Purge(ctx, m.ID, c)
+		if errors.Is(err, repositories.ErrMediaNotFound) {
+			// Attached or purged since it was listed.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		*count++
+		if last {
+			g.deleteObjects(ctx, keys...)
+			rep.Bytes += size
+		}
+	}
+	return nil
+}
+
+// collectUploads deletes one batch each of expired direct and resumable
+// uploads with whatever they stored.
+func (g *mediaGC) collectUploads(ctx context.Context, rep *MediaGCReport) error {
+	ups, err := g.repo.ListExpiredUploads(ctx, int32(g.opts.BatchSize))
+	if err != nil {
+		return err
+	}
+	for _, up := range ups {
+		if g.opts.DryRun {
+			log.Printf("media gc (dry run): would delete expired upload %d of user %d", up.ID, up.OwnerID)
+			rep.ExpiredUploads++
+			continue
+		}
+
+		ok, err := g.repo.DeleteExpiredUpload(ctx, up.ID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			g.deleteObjects(ctx, up.StorageKey)
+			rep.ExpiredUploads++
+		}
+	}
+
+	ids, err := g.repo.ListExpiredResumable(ctx, int32(g.opts.BatchSize))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if g.opts.DryRun {
+			log.Printf("media gc (dry run): would delete expired resumable upload %s", id)
+			rep.ExpiredUploads++
+			continue
+		}
+
+		keys, ok, err := g.repo.DeleteExpiredResumable(ctx, id)
+		if err != nil {
+			return err
+		}
+		if ok {
+			g.deleteObjects(ctx, keys...)
+			rep.ExpiredUploads++
+		}
+	}
+	return nil
+}
+
+// collectOrphans scans storage for objects stored before cutoff that no row
+// refers to. Younger objects may belong to an upload still being recorded.
+func (g *mediaGC) collectOrphans(ctx context.Context, cutoff time.Time, rep *MediaGCReport) error {
+	batch := make([]storage.ObjectInfo, 0, g.opts.BatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(batch))
+		for _, o := range batch {
+			keys = append(keys, o.Key)
+		}
+		known, err := g.repo.KnownKeys(ctx, keys)
+		if err != nil {
+			return err
+		}
+		isKnown := make(map[string]bool, len(known))
+		for _, k := range known {
+			isKnown[k] = true
+		}
+
+		for _, o := range batch {
+			if isKnown[o.Key] {
+				continue
+			}
+			if g.opts.DryRun {
+				log.Printf("media gc (dry run): would delete orphaned object %s", o.Key)
+			} else {
+				g.deleteObjects(ctx, o.Key)
+			}
+			rep.OrphanObjects++
+			rep.Bytes += o.Size
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, prefix := range storage.KeyPrefixes {
+		err := g.st.List(ctx, prefix, func(o storage.ObjectInfo) error {
+			// Drivers that cannot date an object never have it collected.
+			if o.ModTime.IsZero() || !o.ModTime.Before(cutoff) {
+				return nil
+			}
+			batch = append(batch, o)
+			if len(batch) < cap(batch) {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGCRepo lists unattached media and purges it like the database would:
+// media attached since it was listed is refused, and a shared original
+// reports its last reference with the last media row.
+type fakeGCRepo struct {
+	repositories.MediaGCRepository
+	unattached []models.Media
+	// attached is media put on a post between listing and purging.
+	attached map[int64]bool
+	refs     map[string]int
+	purged   []int64
+}
+
+func (f *fakeGCRepo) ListUnattached(context.Context, repositories.MediaGCCutoffs, int32) ([]models.Media, error) {
+	return f.unattached, nil
+}
+
+func (f *fakeGCRepo) ListDeleted(context.Context, repositories.MediaGCCutoffs, int32) ([]models.Media, error) {
+	return nil, nil
+}
+
+func (f *fakeGCRepo) ListExpiredUploads(context.Context, int32) ([]models.MediaUpload, error) {
+	return nil, nil
+}
+
+func (f *fakeGCRepo) ListExpiredResumable(context.Context, int32) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeGCRepo) KnownKeys(_ context.Context, keys []string) ([]string, error) {
+	return keys, nil
+}
+
+func (f *fakeGCRepo) Purge(_ context.Context, id int64, _ repositories.MediaGCCutoffs) (bool, error) {
+	if f.attached[id] {
+		return false, repositories.ErrMediaNotFound
+	}
+	for _, m := range f.unattached {
+		if m.ID == id {
+			f.purged = append(f.purged, id)
+			f.refs[m.StorageKey]--
+			return f.refs[m.StorageKey] == 0, nil
+		}
+	}
+	return false, repositories.ErrMediaNotFound
+}
+
+// countingStorage counts the deletes of each key.
+type countingStorage struct {
+	storage.Storage
+	deletes map[string]int
+}
+
+func (c *countingStorage) Delete(ctx context.Context, key string) error {
+	c.deletes[key]++
+	return c.Storage.Delete(ctx, key)
+}
+
+func TestMediaGCUnattached(t *testing.T) {
+	ctx := context.Background()
+	lfs := storage.NewLocalFS(storage.LocalOptions{Dir: t.TempDir()})
+	for _, key := range []string{"posts/1/a.jpg", "posts/1/b.jpg", "posts/1/c.jpg", "posts/1/c_480w.jpg"} {
+		if err := lfs.Save(ctx, key, strings.NewReader("data"), 4, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := &countingStorage{Storage: lfs, deletes: make(map[string]int)}
+
+	repo := &fakeGCRepo{
+		unattached: []models.Media{
+			{ID: 1, StorageKey: "posts/1/a.jpg", SizeBytes: 4},
+			{ID: 2, StorageKey: "posts/1/b.jpg", SizeBytes: 4},
+			{ID: 3, StorageKey: "posts/1/b.jpg", SizeBytes: 4},
+			{ID: 4, StorageKey: "posts/1/c.jpg", SizeBytes: 4, Variants: []models.MediaVariant{
+				{StorageKey: "posts/1/c_480w.jpg", SizeBytes: 4},
+			}},
+		},
+		attached: map[int64]bool{1: true},
+		refs:     map[string]int{"posts/1/a.jpg": 1, "posts/1/b.jpg": 2, "posts/1/c.jpg": 1},
+	}
+
+	gc := NewMediaGC(repo, st, MediaGCOptions{UnattachedAfter: time.Hour, Retention: time.Hour})
+	defer gc.Close()
+
+	rep, err := gc.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repo.purged, []int64{2, 3, 4}) {
+		t.Errorf("purged %v, want [2 3 4]", repo.purged)
+	}
+	if rep.UnattachedMedia != 3 || rep.Bytes != 12 {
+		t.Errorf("report = %+v, want 3 unattached media and 12 bytes", rep)
+	}
+
+	wantDeletes := map[string]int{"posts/1/b.jpg": 1, "posts/1/c.jpg": 1, "posts/1/c_480w.jpg": 1}
+	for key, want := range wantDeletes {
+		if got := st.deletes[key]; got != want {
+			t.Errorf("%s deleted %d times, want %d", key, got, want)
+		}
+	}
+	// Media attached since it was listed keeps its object.
+	if st.deletes["posts/1/a.jpg"] != 0 {
+		t.Error("object of media attached after listing was deleted")
+	}
+	if _, err := lfs.Stat(ctx, "posts/1/a.jpg"); err != nil {
+		t.Errorf("object of attached media: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/realtime"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MediaJob is one media item going through the pipeline. Processors update
+// Media in place; the pipeline stores it once every processor has run.
+type MediaJob struct {
+	Media models.Media
+
+	st   storage.Storage
+	data []byte
+	// file is the original opened by File, closed by cleanup; tmp is the
+	// temporary copy behind it for remote drivers, removed by cleanup.
+	file *os.File
+	tmp  string
+}
+
+// File opens the original as a seekable file for tools that need one, such
+// as ffprobe on MP4s whose index sits at the end. Local objects are opened
+// through the storage root, so symlinks leading out of it are refused;
+// objects of remote drivers are copied to a temporary file on first use.
+// The file stays open until the job ends and must not be closed.
+func (j *MediaJob) File(ctx context.Context) (*os.File, error) {
+	if j.file != nil {
+		return j.file, nil
+	}
+
+	if lfs, ok := j.st.(*storage.LocalFS); ok {
+		f, err := lfs.OpenFile(j.Media.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("storage open: %w", err)
+		}
+		j.file = f
+		return f, nil
+	}
+
+	rc, err := j.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	f, err := os.CreateTemp("", "media-*"+filepath.Ext(j.Media.StorageKey))
+	if err != nil {
+		return nil, fmt.Errorf("temp file: %w", err)
+	}
+	j.file, j.tmp = f, f.Name()
+
+	if _, err := io.Copy(f, rc); err != nil {
+		return nil, fmt.Errorf("copy original: %w", err)
+	}
+	return f, nil
+}
+
+func (j *MediaJob) cleanup() {
+	if j.file != nil {
+		j.file.Close()
+	}
+	if j.tmp != "" {
+		if err := os.Remove(j.tmp); err != nil {
+			log.Printf("media pipeline: remove %s: %v", j.tmp, err)
+		}
+	}
+}
+
+// Open streams the original object.
+func (j *MediaJob) Open(ctx context.Context) (io.ReadCloser, error) {
+	rc, err := j.st.Open(ctx, j.Media.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("storage open: %w", err)
+	}
+	return rc, nil
+}
+
+// Original reads the whole original object, once per job. Processors of
+// large media (videos) should stream it with Open instead.
+func (j *MediaJob) Original(ctx context.Context) ([]byte, error) {
+	if j.data != nil {
+		return j.data, nil
+	}
+
+	rc, err := j.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("storage read: %w", err)
+	}
+	j.data = data
+	return data, nil
+}
+
+// MediaProcessor is one step of the media pipeline: metadata extraction,
+// variants, placeholders, scanning. Processors run in the order they are
+// given to NewMediaPipeline and must be safe to run again on the same media,
+// since a worker that dies leaves its media to be processed from scratch.
+type MediaProcessor interface {
+	Name() string
+	// Accepts reports whether the processor applies to m.
+	Accepts(m models.Media) bool
+	// Process does the work; an error marks the media failed.
+	Process(ctx context.Context, job *MediaJob) error
+}
+
+// MediaPipeline processes uploaded media in the background. Media is stored
+// as processing, moves to ready or failed once its processors ran, and the
+// owner is told through a media.ready or media.failed event.
+type MediaPipeline interface {
+	// Enqueue hands media to the workers without blocking. Media that does
+	// not fit in the queue stays processing until the next sweep.
+	Enqueue(mediaID int64)
+	// Close stops the workers. Media they were working on is resumed by the
+	// next instance once its lock runs out.
+	Close() error
+}
+
+type PipelineOptions struct {
+	Workers   int
+	QueueSize int
+	// Timeout bounds the work on one media item, which stays locked to its
+	// worker for as long.
+	Timeout time.Duration
+	// SweepInterval is how often processing media that nobody holds, left
+	// behind by a full queue or a crashed instance, is queued again.
+	SweepInterval time.Duration
+}
+
+type mediaPipeline struct {
+	repo       repositories.MediaRepository
+	st         storage.Storage
+	broker     realtime.Broker
+	ttl        time.Duration
+	processors []MediaProcessor
+	opts       PipelineOptions
+
+	queue  chan int64
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewMediaPipeline(repo repositories.MediaRepository, st storage.Storage, broker realtime.Broker, presignTTL time.Duration, opts PipelineOptions, processors ...MediaProcessor) MediaPipeline {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.SweepInterval <= 0 {
+		opts.SweepInterval = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &mediaPipeline{
+		repo:       repo,
+		st:         st,
+		broker:     broker,
+		ttl:        presignTTL,
+		processors: processors,
+		opts:       opts,
+		queue:      make(chan int64, opts.QueueSize),
+		cancel:     cancel,
+	}
+
+	p.wg.Add(opts.Workers + 1)
+	for range opts.Workers {
+		go p.work(ctx)
+	}
+	go p.sweep(ctx)
+	return p
+}
+
+// Enqueue implements MediaPipeline.
+func (p *mediaPipeline) Enqueue(mediaID int64) {
+	select {
+	case p.queue <- mediaID:
+	default:
+		log.Printf("media pipeline: queue full, media %d waits for the next sweep", mediaID)
+	}
+}
+
+// Close implements MediaPipeline.
+func (p *mediaPipeline) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *mediaPipeline) work(ctx context.Context) {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-p.queue:
+			p.process(ctx, id)
+		}
+	}
+}
+
+// sweep queues processing media nobody holds, first right away to resume
+// what a previous run left unfinished and then every SweepInterval.
+func (p *mediaPipeline) sweep(ctx context.Context) {
+	defer p.wg.Done()
+
+	t := time.NewTicker(p.opts.SweepInterval)
+	defer t.Stop()
+	for {
+		if free := cap(p.queue) - len(p.queue); free > 0 {
+			ids, err := p.repo.ListPending(ctx, int32(free))
+			if err != nil && ctx.Err() == nil {
+				log.Printf("media pipeline sweep: %v", err)
+			}
+			for _, id := range ids {
+				select {
+				case p.queue <- id:
+				default:
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (p *mediaPipeline) process(ctx context.Context, id int64) {
+	jobCtx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	m, err := p.repo.Claim(jobCtx, id, time.Now().Add(p.opts.Timeout))
+	if err != nil {
+		// Already processed, deleted or held by another worker.
+		if !errors.Is(err, repositories.ErrMediaNotFound) {
+			log.Printf("media pipeline claim %d: %v", id, err)
+		}
+		return
+	}
+
+	job := &MediaJob{Media: m, st: p.st}
+	defer job.cleanup()
+	job.Media.Status = models.MediaStatusReady
+	for _, pr := range p.processors {
+		if !pr.Accepts(job.Media) {
+			continue
+		}
+		if err := pr.Process(jobCtx, job); err != nil {
+			// Shutting down: leave the media locked for the next run.
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("media pipeline %s %d: %v", pr.Name(), id, err)
+			job.Media.Status = models.MediaStatusFailed
+			break
+		}
+	}
+
+	saveCtx := context.WithoutCancel(jobCtx)
+	if err := p.repo.FinishProcessing(saveCtx, job.Media); err != nil {
+		log.Printf("media pipeline finish %d: %v", id, err)
+		return
+	}
+
+	typ := realtime.EventMediaReady
+	if job.Media.Status == models.MediaStatusFailed {
+		typ = realtime.EventMediaFailed
+	}
+	pub := job.Media.PublicWith(presignFor(saveCtx, p.st, p.ttl))
+	if err := p.broker.Publish(saveCtx, realtime.UserTopic(job.Media.OwnerID), typ, pub); err != nil {
+		log.Printf("media pipeline publish %d: %v", id, err)
+	}
+}
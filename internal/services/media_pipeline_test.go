@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMediaJobFileLocal(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "clip.mp4"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	st := storage.NewLocalFS(storage.LocalOptions{Dir: dir})
+
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"regular file", "clip.mp4", "video", false},
+		{"symlink out of the root", "link.mp4", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &MediaJob{Media: models.Media{StorageKey: tt.key}, st: st}
+			defer job.cleanup()
+
+			f, err := job.File(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("File() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("File() read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
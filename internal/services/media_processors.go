@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/imaging"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/probe"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"log"
+	"math"
+)
+
+// variantProcessor stores resized copies of images next to the original.
+type variantProcessor struct {
+	repo   repositories.MediaRepository
+	st     storage.Storage
+	widths []int
+}
+
+// NewVariantProcessor resizes images to each of widths, in pixels, skipping
+// widths the original does not exceed.
+func NewVariantProcessor(repo repositories.MediaRepository, st storage.Storage, widths []int) MediaProcessor {
+	return &variantProcessor{repo: repo, st: st, widths: widths}
+}
+
+func (p *variantProcessor) Name() string { return "variants" }
+
+func (p *variantProcessor) Accepts(m models.Media) bool {
+	return m.Kind == "image" && len(p.widths) > 0
+}
+
+func (p *variantProcessor) Process(ctx context.Context, job *MediaJob) error {
+	data, err := job.Original(ctx)
+	if err != nil {
+		return err
+	}
+
+	variants, err := imaging.Variants(data, p.widths)
+	if err != nil {
+		return err
+	}
+
+	out := make([]models.MediaVariant, 0, len(variants))
+	for _, v := range variants {
+		key := storage.BuildVariantKey(job.Media.StorageKey, v.Width, v.Ext)
+		if err := p.st.Save(ctx, key, bytes.NewReader(v.Data), int64(len(v.Data)), v.MimeType); err != nil {
+			return fmt.Errorf("save variant %s: %w", key, err)
+		}
+
+		row, err := p.repo.CreateVariant(ctx, job.Media.ID, models.MediaVariant{
+			Width:      int32(v.Width),
+			Height:     int32(v.Height),
+			StorageKey: key,
+			MimeType:   v.MimeType,
+			SizeBytes:  int64(len(v.Data)),
+		})
+		if err != nil {
+			return err
+		}
+		out = append(out, row)
+	}
+	job.Media.Variants = out
+	return nil
+}
+
+// probeProcessor fills duration, dimensions and codecs of videos and audio
+// and stores a poster frame for videos when the prober can decode one.
+type probeProcessor struct {
+	prober probe.Prober
+	st     storage.Storage
+}
+
+func NewProbeProcessor(prober probe.Prober, st storage.Storage) MediaProcessor {
+	return &probeProcessor{prober: prober, st: st}
+}
+
+func (p *probeProcessor) Name() string { return "probe" }
+
+func (p *probeProcessor) Accepts(m models.Media) bool {
+	return m.Kind == "video" || m.Kind == "audio"
+}
+
+func (p *probeProcessor) Process(ctx context.Context, job *MediaJob) error {
+	f, err := job.File(ctx)
+	if err != nil {
+		return err
+	}
+
+	info, err := p.prober.Probe(ctx, f)
+	if errors.Is(err, probe.ErrUnsupported) {
+		// Nothing to learn about this format, which is still playable.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if ms := info.Duration.Milliseconds(); ms > 0 {
+		d := int32(min(ms, math.MaxInt32))
+		job.Media.DurationMs = &d
+	}
+	if info.Width > 0 && info.Height > 0 {
+		w, h := int32(info.Width), int32(info.Height)
+		job.Media.Width, job.Media.Height = &w, &h
+	}
+	job.Media.VideoCodec = info.VideoCodec
+	job.Media.AudioCodec = info.AudioCodec
+
+	pe, ok := p.prober.(probe.PosterExtractor)
+	if !ok || job.Media.Kind != "video" {
+		return nil
+	}
+
+	frame, err := pe.Poster(ctx, f, probe.PosterOffset(info.Duration))
+	if err != nil || len(frame) == 0 {
+		// A video without a poster is still a good video.
+		if err != nil && !errors.Is(err, probe.ErrUnsupported) {
+			log.Printf("poster of media %d: %v", job.Media.ID, err)
+		}
+		return nil
+	}
+
+	key := storage.BuildPosterKey(job.Media.StorageKey)
+	if err := p.st.Save(ctx, key, bytes.NewReader(frame), int64(len(frame)), "image/jpeg"); err != nil {
+		return fmt.Errorf("save poster %s: %w", key, err)
+	}
+	job.Media.PosterKey = key
+	return nil
+}
+
+// placeholderProcessor computes the BlurHash and dominant color of images.
+type placeholderProcessor struct{}
+
+func NewPlaceholderProcessor() MediaProcessor {
+	return placeholderProcessor{}
+}
+
+func (placeholderProcessor) Name() string { return "placeholder" }
+
+func (placeholderProcessor) Accepts(m models.Media) bool {
+	return m.Kind == "image"
+}
+
+func (placeholderProcessor) Process(ctx context.Context, job *MediaJob) error {
+	data, err := job.Original(ctx)
+	if err != nil {
+		return err
+	}
+
+	ph, err := imaging.Placeholders(data)
+	if err != nil {
+		return err
+	}
+	job.Media.BlurHash = ph.BlurHash
+	job.Media.DominantColor = ph.Color
+	return nil
+}
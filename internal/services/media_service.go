@@ -1,48 +1,352 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go-rest-chi/internal/auth"
 	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/imaging"
 	"go-rest-chi/internal/models"
 	"go-rest-chi/internal/repositories"
 	"go-rest-chi/internal/storage"
 	"io"
+	"io/fs"
+	"log"
 	"mime"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var (
 	ErrNotImplemented  = fmt.Errorf("not implemented")
 	ErrUnsupportedMime = fmt.Errorf("unsupported content type")
+	ErrMimeMismatch    = errors.New("file content does not match its declared type")
+	ErrInvalidImage    = imaging.ErrInvalidImage
+	ErrInvalidSize     = errors.New("size must be positive")
+	ErrUploadNotFound  = repositories.ErrUploadNotFound
+	ErrUploadCompleted = repositories.ErrUploadCompleted
+	ErrUploadExpired   = errors.New("upload expired")
+	ErrUploadMissing   = errors.New("object has not been uploaded yet")
+	ErrUploadMismatch  = errors.New("uploaded object does not match the declared size or content type")
+	ErrMediaNotFound   = repositories.ErrMediaNotFound
+	ErrMixedPostMedia  = errors.New("a post cannot mix media kinds")
+	ErrAltTextRequired = errors.New("images need alt text")
 )
 
+// MediaLimits caps upload sizes per media kind, in bytes, and lists the
+// exact MIME types accepted for each kind.
+type MediaLimits struct {
+	Image int64
+	Video int64
+	Audio int64
+	Types map[string][]string
+	// Post caps the media attached to one post.
+	Post PostMediaLimits
+	// AltText governs the descriptions sent with media.
+	AltText AltTextPolicy
+	// Quota caps what each user stores.
+	Quota StorageQuotas
+}
+
+// Allows reports whether mimeType is on the allow-list of its kind.
+func (l MediaLimits) Allows(kind, mimeType string) bool {
+	return kind != "" && slices.Contains(l.Types[kind], mimeType)
+}
+
+// For returns the limit for kind, or the largest limit for an unknown kind.
+func (l MediaLimits) For(kind string) int64 {
+	switch kind {
+	case "image":
+		return l.Image
+	case "video":
+		return l.Video
+	case "audio":
+		return l.Audio
+	}
+	return max(l.Image, l.Video, l.Audio)
+}
+
+// FileTooLargeError reports an upload over its kind's limit.
+type FileTooLargeError struct {
+	Kind     string `json:"kind"`
+	MaxBytes int64  `json:"max_bytes"`
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("%s uploads are limited to %d bytes", e.Kind, e.MaxBytes)
+}
+
+// PostMediaLimits caps how many media of each kind one post holds. Unless
+// Mixed is set, all media on a post must be of the same kind.
+type PostMediaLimits struct {
+	Images int
+	Videos int
+	Audio  int
+	Mixed  bool
+}
+
+// For returns the limit for kind; unknown kinds are not allowed on posts.
+func (l PostMediaLimits) For(kind string) int {
+	switch kind {
+	case "image":
+		return l.Images
+	case "video":
+		return l.Videos
+	case "audio":
+		return l.Audio
+	}
+	return 0
+}
+
+// Check validates the kinds of every media a post would hold.
+func (l PostMediaLimits) Check(kinds []string) error {
+	counts := make(map[string]int, 3)
+	for _, k := range kinds {
+		counts[k]++
+	}
+	if !l.Mixed && len(counts) > 1 {
+		return ErrMixedPostMedia
+	}
+	for _, k := range kinds {
+		if limit := l.For(k); counts[k] > limit {
+			return &PostMediaLimitError{Kind: k, Max: limit}
+		}
+	}
+	return nil
+}
+
+// PostMediaLimitError reports a post over its limit for one media kind.
+type PostMediaLimitError struct {
+	Kind string `json:"kind"`
+	Max  int    `json:"max"`
+}
+
+func (e *PostMediaLimitError) Error() string {
+	return fmt.Sprintf("%s attachments are limited to %d per post", e.Kind, e.Max)
+}
+
+// Alt text policy modes.
+const (
+	AltTextOff     = "off"
+	AltTextWarn    = "warn"
+	AltTextRequire = "require"
+)
+
+// AltTextPolicy caps the length of alt text, in characters, and decides
+// what happens to images sent without any: nothing (off), a warning in the
+// response (warn) or a rejection (require).
+type AltTextPolicy struct {
+	Mode     string
+	MaxChars int
+}
+
+// Check trims alt and validates it for media of kind.
+func (p AltTextPolicy) Check(kind, alt string) (string, error) {
+	alt = strings.TrimSpace(alt)
+	if p.MaxChars > 0 && utf8.RuneCountInString(alt) > p.MaxChars {
+		return "", &AltTextTooLongError{MaxChars: p.MaxChars}
+	}
+	if alt == "" && kind == "image" && p.Mode == AltTextRequire {
+		return "", ErrAltTextRequired
+	}
+	return alt, nil
+}
+
+// Warnings returns the advice given to the owner of m.
+func (p AltTextPolicy) Warnings(m models.Media) []string {
+	if m.AltText == "" && m.Kind == "image" && p.Mode == AltTextWarn {
+		return []string{"images should have alt text"}
+	}
+	return nil
+}
+
+// AltTextTooLongError reports alt text over the configured length.
+type AltTextTooLongError struct {
+	MaxChars int `json:"max_chars"`
+}
+
+func (e *AltTextTooLongError) Error() string {
+	return fmt.Sprintf("alt text is limited to %d characters", e.MaxChars)
+}
+
+// StorageQuota caps the bytes of live media one user keeps and the files
+// they upload per UTC day, deleted since or not. Zero is unlimited.
+type StorageQuota struct {
+	MaxBytes       int64
+	MaxFilesPerDay int
+}
+
+// StorageQuotas is the default quota with overrides by role.
+type StorageQuotas struct {
+	Default StorageQuota
+	Roles   map[string]StorageQuota
+}
+
+// For returns the quota of users with role.
+func (q StorageQuotas) For(role string) StorageQuota {
+	if rq, ok := q.Roles[role]; ok {
+		return rq
+	}
+	return q.Default
+}
+
+// StorageQuotaError reports an upload that would take its owner over their
+// storage quota.
+type StorageQuotaError struct {
+	MaxBytes  int64 `json:"max_bytes"`
+	UsedBytes int64 `json:"used_bytes"`
+}
+
+func (e *StorageQuotaError) Error() string {
+	return fmt.Sprintf("storage is limited to %d bytes per user", e.MaxBytes)
+}
+
+// UploadRateError reports an owner out of uploads for the day.
+type UploadRateError struct {
+	MaxFiles int       `json:"max_files_per_day"`
+	ResetAt  time.Time `json:"reset_at"`
+}
+
+func (e *UploadRateError) Error() string {
+	return fmt.Sprintf("uploads are limited to %d files per day", e.MaxFiles)
+}
+
+// quotaRoom is what is left of a user's storage quota.
+type quotaRoom struct {
+	max, used int64
+}
+
+// fits reports whether n more bytes fit.
+func (r quotaRoom) fits(n int64) bool {
+	return r.max <= 0 || r.used+n <= r.max
+}
+
+// left is how many more bytes fit, or -1 for no limit.
+func (r quotaRoom) left() int64 {
+	if r.max <= 0 {
+		return -1
+	}
+	return max(0, r.max-r.used)
+}
+
+func (r quotaRoom) err() error {
+	return &StorageQuotaError{MaxBytes: r.max, UsedBytes: r.used}
+}
+
+// dayStart is the start of the UTC day of t, when daily file counts reset.
+func dayStart(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+var errOverLimit = errors.New("upload over size limit")
+
+// uploadReader counts the bytes of an upload and fails once they pass max.
+// It keeps the first read error because storage drivers do not always wrap
+// it.
+type uploadReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+	err error
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	if u.n > u.max {
+		err = errOverLimit
+	}
+	if err != nil && err != io.EOF && u.err == nil {
+		u.err = err
+	}
+	return n, err
+}
+
 type MediaService interface {
-	SavePostMedia(ctx context.Context, userID, postID int64, filename string, r io.Reader, size int64, mimeType string) (models.MediaPublic, error)
+	// MaxSize is the upload limit in bytes for kind; an empty kind gives the
+	// largest limit of any kind.
+	MaxSize(kind string) int64
+	// CheckType validates a client-declared type against the allow-list and
+	// returns its kind. The content itself is sniffed when it is stored.
+	CheckType(mimeType string) (string, error)
+	// CheckAltText trims alt text and validates it for media of kind.
+	CheckAltText(kind, alt string) (string, error)
+	Save(ctx context.Context, userID int64, filename string, r io.Reader, size int64, mimeType, altText string) (models.MediaPublic, error)
+	SavePostMedia(ctx context.Context, userID, postID int64, filename string, r io.Reader, size int64, mimeType, altText string) (models.MediaPublic, error)
 
 	UploadUserAvatar(ctx context.Context, userID int64, filename string, r io.Reader, size int64, mimeType string) (models.MediaPublic, error)
+
+	// CreateUpload reserves a staging key and returns a presigned PUT the
+	// client sends the bytes to directly.
+	CreateUpload(ctx context.Context, userID int64, filename string, size int64, mimeType, altText string) (models.MediaUploadPublic, error)
+	// CompleteUpload checks the stored object against the declared size and
+	// type and records the media row.
+	CompleteUpload(ctx context.Context, userID, id int64) (models.MediaPublic, error)
+	// CheckUploadOpen fails unless key is the staging key of an upload that
+	// may still be PUT to: not completed and not expired. It returns the
+	// declared size, the most the PUT may write.
+	CheckUploadOpen(ctx context.Context, key string) (int64, error)
+
+	// ListOwned pages through the media of userID, newest first, optionally
+	// of one kind only.
+	ListOwned(ctx context.Context, userID int64, kind string, limit, offset int32) ([]models.MediaPublic, error)
+	// Delete hides media of userID from every post and message it is on.
+	// The stored objects are removed later by garbage collection.
+	Delete(ctx context.Context, userID, id int64) error
+	// UpdateAltText replaces the alt text of media of userID; an empty alt
+	// clears it where the policy allows.
+	UpdateAltText(ctx context.Context, userID, id int64, alt string) (models.MediaPublic, error)
+
+	// CheckQuota fails when userID may not upload another file of size
+	// bytes, or of any size when size is -1. The quota follows the role in
+	// ctx.
+	CheckQuota(ctx context.Context, userID, size int64) error
+	// Quota reports the storage use of userID against their quota.
+	Quota(ctx context.Context, userID int64) (models.QuotaUsage, error)
+
+	// ServedObject describes the object stored under key for serving it.
+	// Objects of no live media, such as unfinished uploads, give
+	// ErrMediaNotFound.
+	ServedObject(ctx context.Context, key string) (models.ServedObject, error)
 }
 
 type mediaService struct {
-	repo repositories.MediaRepository
-	st   storage.Storage
-	ttl  time.Duration
+	repo   repositories.MediaRepository
+	st     storage.Storage
+	ttl    time.Duration
+	limits MediaLimits
+	// pipeline finishes stored media in the background.
+	pipeline MediaPipeline
 }
 
-func NewMediaService(repo repositories.MediaRepository, st storage.Storage, presignTTL time.Duration) MediaService {
-	return &mediaService{repo: repo, st: st, ttl: presignTTL}
+func NewMediaService(repo repositories.MediaRepository, st storage.Storage, presignTTL time.Duration, limits MediaLimits, pipeline MediaPipeline) MediaService {
+	return &mediaService{repo: repo, st: st, ttl: presignTTL, limits: limits, pipeline: pipeline}
 }
 
-// SavePostImage implements MediaService.
-func (med *mediaService) SavePostMedia(ctx context.Context, userID int64, postID int64, filename string, r io.Reader, size int64, mimeType string) (models.MediaPublic, error) {
-	kind := helpers.InferKind(mimeType)
-
-	if kind == "" {
-		return models.MediaPublic{}, ErrUnsupportedMime
+// presignFor resolves storage keys to presigned URLs valid for ttl.
+func presignFor(ctx context.Context, st storage.Storage, ttl time.Duration) func(string) string {
+	return func(key string) string {
+		url, _ := st.PresignGet(ctx, key, ttl)
+		return url
 	}
+}
 
+// buildKey sanitizes filename, adds an extension from mimeType when it has
+// none and returns a fresh storage key for userID.
+func buildKey(userID int64, filename string, mimeType string) string {
+	return storage.BuildPostKey(userID, keyFilename(filename, mimeType), time.Now())
+}
+
+// keyFilename sanitizes filename, giving it the extension of mimeType when
+// it has none, for keys to end in.
+func keyFilename(filename string, mimeType string) string {
 	filename = helpers.SanitizeFilename(filename)
 
 	if ext := strings.ToLower(filepath.Ext(filename)); ext == "" {
@@ -50,36 +354,576 @@ func (med *mediaService) SavePostMedia(ctx context.Context, userID int64, postID
 			filename += exts[0]
 		}
 	}
+	return filename
+}
+
+// resolveType checks the sniffed type against the allow-list and against
+// what the client declared, returning the type to store and its kind. A
+// missing or generic declaration defers to the content.
+func (med *mediaService) resolveType(declared, detected string) (string, string, error) {
+	kind := helpers.InferKind(detected)
+	if !med.limits.Allows(kind, detected) {
+		return "", "", ErrUnsupportedMime
+	}
+
+	if d := helpers.NormalizeMime(declared); d != "" && d != "application/octet-stream" && d != detected {
+		return "", "", ErrMimeMismatch
+	}
+	return detected, kind, nil
+}
+
+// sniffObject detects the type of an object already in storage.
+func (med *mediaService) sniffObject(ctx context.Context, key string) (string, error) {
+	rc, err := med.st.Open(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("storage open: %w", err)
+	}
+	defer rc.Close()
+
+	head := make([]byte, helpers.SniffLen)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("storage read: %w", err)
+	}
+	return helpers.DetectMime(head[:n]), nil
+}
+
+// cleanImage reads a whole image, strips its metadata and bakes in its
+// orientation.
+func cleanImage(r io.Reader, mimeType string) ([]byte, imaging.Info, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, imaging.Info{}, fmt.Errorf("read upload: %w", err)
+	}
+	return imaging.Clean(mimeType, data)
+}
+
+func dimensions(info imaging.Info) (*int32, *int32) {
+	w, h := int32(info.Width), int32(info.Height)
+	return &w, &h
+}
+
+// cleanStoredImage cleans an image that reached storage directly and writes
+// the result back over it, with the type in the returned info.
+func (med *mediaService) cleanStoredImage(ctx context.Context, key, mimeType string) (int64, imaging.Info, error) {
+	rc, err := med.st.Open(ctx, key)
+	if err != nil {
+		return 0, imaging.Info{}, fmt.Errorf("storage open: %w", err)
+	}
+	clean, info, err := cleanImage(rc, mimeType)
+	rc.Close()
+	if err != nil {
+		return 0, imaging.Info{}, err
+	}
+
+	if err := med.st.Save(ctx, key, bytes.NewReader(clean), int64(len(clean)), info.MimeType); err != nil {
+		return 0, imaging.Info{}, fmt.Errorf("storage save: %w", err)
+	}
+	return int64(len(clean)), info, nil
+}
+
+// hashObject streams an object already in storage through SHA-256.
+func (med *mediaService) hashObject(ctx context.Context, key string) (string, error) {
+	rc, err := med.st.Open(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("storage open: %w", err)
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("storage read: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// deleteObject removes an object stored for an upload that did not make it
+// into a media row.
+func (med *mediaService) deleteObject(ctx context.Context, key string) {
+	if err := med.st.Delete(context.WithoutCancel(ctx), key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("delete unused upload %s: %v", key, err)
+	}
+}
+
+// dropDuplicate deletes the object stored under key when media was
+// deduplicated onto an object its owner had already uploaded.
+func (med *mediaService) dropDuplicate(ctx context.Context, media models.Media, key string) {
+	if media.StorageKey == key {
+		return
+	}
+	if err := med.st.Delete(context.WithoutCancel(ctx), key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("delete duplicate upload %s: %v", key, err)
+	}
+}
+
+// store writes the upload to storage, records the media row without
+// attaching it anywhere and queues it for processing. Content the owner
+// already uploaded is stored once: the new row shares the existing object.
+// size may be -1 when r is a stream of unknown length; the row records the
+// bytes actually stored. The stored type is the one sniffed from the
+// content, never the declared one.
+func (med *mediaService) store(ctx context.Context, userID int64, filename string, r io.Reader, size int64, declared, altText string) (models.Media, error) {
+	room, err := med.quotaRoom(ctx, userID, size)
+	if err != nil {
+		return models.Media{}, err
+	}
+
+	ur := &uploadReader{r: r, max: med.limits.For("")}
+
+	head := make([]byte, helpers.SniffLen)
+	n, err := io.ReadFull(ur, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return models.Media{}, fmt.Errorf("read upload: %w", err)
+	}
+	head = head[:n]
+
+	mimeType, kind, err := med.resolveType(declared, helpers.DetectMime(head))
+	if err != nil {
+		return models.Media{}, err
+	}
+	altText, err = med.limits.AltText.Check(kind, altText)
+	if err != nil {
+		return models.Media{}, err
+	}
+
+	limit := med.limits.For(kind)
+	if size > limit {
+		return models.Media{}, &FileTooLargeError{Kind: kind, MaxBytes: limit}
+	}
+	ur.max = limit
+	if left := room.left(); left >= 0 {
+		ur.max = min(limit, left)
+	}
+	// overLimit is the error for an upload cut off by ur.max.
+	overLimit := func() error {
+		if ur.n > limit {
+			return &FileTooLargeError{Kind: kind, MaxBytes: limit}
+		}
+		if !room.fits(ur.n) {
+			return room.err()
+		}
+		return nil
+	}
 
-	key := storage.BuildPostKey(userID, filename, time.Now())
+	body := io.Reader(io.MultiReader(bytes.NewReader(head), ur))
+	stored := int64(-1)
+	var width, height *int32
 
-	if err := med.st.Save(ctx, key, r, size, mimeType); err != nil {
-		return models.MediaPublic{}, fmt.Errorf("storage save: %w", err)
+	// Images are cleaned in memory (they are capped well below videos) so
+	// storage only ever sees the stripped bytes. A rotated WebP comes back
+	// in another format.
+	if kind == "image" {
+		clean, info, err := cleanImage(body, mimeType)
+		if err != nil {
+			if over := overLimit(); over != nil {
+				return models.Media{}, over
+			}
+			return models.Media{}, err
+		}
+		body, size, stored = bytes.NewReader(clean), int64(len(clean)), int64(len(clean))
+		width, height = dimensions(info)
+		mimeType = info.MimeType
+	}
+
+	key := buildKey(userID, filename, mimeType)
+
+	// The hash covers what storage receives, so cleaned bytes for images.
+	h := sha256.New()
+	if err := med.st.Save(ctx, key, io.TeeReader(body, h), size, mimeType); err != nil {
+		if delErr := med.st.Delete(context.WithoutCancel(ctx), key); delErr != nil && !errors.Is(delErr, fs.ErrNotExist) {
+			log.Printf("delete failed upload %s: %v", key, delErr)
+		}
+		if over := overLimit(); over != nil {
+			return models.Media{}, over
+		}
+		if ur.err != nil {
+			return models.Media{}, fmt.Errorf("read upload: %w", ur.err)
+		}
+		return models.Media{}, fmt.Errorf("storage save: %w", err)
+	}
+	if stored < 0 {
+		stored = ur.n
 	}
 
 	media, err := med.repo.Create(ctx, repositories.CreateMediaParams{
+		OwnerID:     userID,
+		Kind:        kind,
+		StorageKey:  key,
+		MimeType:    mimeType,
+		SizeBytes:   stored,
+		Width:       width,
+		Height:      height,
+		Status:      models.MediaStatusProcessing,
+		ContentHash: hex.EncodeToString(h.Sum(nil)),
+		AltText:     altText,
+	})
+
+	if err != nil {
+		return models.Media{}, fmt.Errorf("media create: %w", err)
+	}
+	med.dropDuplicate(ctx, media, key)
+	med.pipeline.Enqueue(media.ID)
+
+	return media, nil
+}
+
+// MaxSize implements MediaService.
+func (med *mediaService) MaxSize(kind string) int64 {
+	return med.limits.For(kind)
+}
+
+// CheckType implements MediaService.
+func (med *mediaService) CheckType(mimeType string) (string, error) {
+	mimeType = helpers.NormalizeMime(mimeType)
+	kind := helpers.InferKind(mimeType)
+	if !med.limits.Allows(kind, mimeType) {
+		return "", ErrUnsupportedMime
+	}
+	return kind, nil
+}
+
+// CheckAltText implements MediaService.
+func (med *mediaService) CheckAltText(kind, alt string) (string, error) {
+	return med.limits.AltText.Check(kind, alt)
+}
+
+// publicForOwner is the public form of media returned to its owner, with
+// the policy's warnings.
+func (med *mediaService) publicForOwner(ctx context.Context, m models.Media) models.MediaPublic {
+	pub := m.PublicWith(presignFor(ctx, med.st, med.ttl))
+	pub.Warnings = med.limits.AltText.Warnings(m)
+	return pub
+}
+
+// Save implements MediaService.
+func (med *mediaService) Save(ctx context.Context, userID int64, filename string, r io.Reader, size int64, mimeType, altText string) (models.MediaPublic, error) {
+	media, err := med.store(ctx, userID, filename, r, size, mimeType, altText)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+
+	return med.publicForOwner(ctx, media), nil
+}
+
+// SavePostImage implements MediaService.
+func (med *mediaService) SavePostMedia(ctx context.Context, userID int64, postID int64, filename string, r io.Reader, size int64, mimeType, altText string) (models.MediaPublic, error) {
+	media, err := med.store(ctx, userID, filename, r, size, mimeType, altText)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+
+	if err := med.repo.AttachToPost(ctx, userID, postID, media.ID, med.limits.Post.Check); err != nil {
+		// Nothing else references the new media.
+		if delErr := med.repo.SoftDelete(context.WithoutCancel(ctx), userID, media.ID); delErr != nil {
+			log.Printf("delete unattached media %d: %v", media.ID, delErr)
+		}
+		return models.MediaPublic{}, fmt.Errorf("attach to post: %w", err)
+	}
+
+	return med.publicForOwner(ctx, media), nil
+
+}
+
+// UploadUserAvatar implements MediaService.
+func (med *mediaService) UploadUserAvatar(ctx context.Context, userID int64, filename string, r io.Reader, size int64, mimeType string) (models.MediaPublic, error) {
+	panic("unimplemented")
+}
+
+// CreateUpload implements MediaService.
+func (med *mediaService) CreateUpload(ctx context.Context, userID int64, filename string, size int64, mimeType, altText string) (models.MediaUploadPublic, error) {
+	kind, err := med.CheckType(mimeType)
+	if err != nil {
+		return models.MediaUploadPublic{}, err
+	}
+	altText, err = med.CheckAltText(kind, altText)
+	if err != nil {
+		return models.MediaUploadPublic{}, err
+	}
+	// The signed Content-Type and the later sniff compare against this form.
+	mimeType = helpers.NormalizeMime(mimeType)
+	if size <= 0 {
+		return models.MediaUploadPublic{}, ErrInvalidSize
+	}
+	if limit := med.limits.For(kind); size > limit {
+		return models.MediaUploadPublic{}, &FileTooLargeError{Kind: kind, MaxBytes: limit}
+	}
+	if err := med.CheckQuota(ctx, userID, size); err != nil {
+		return models.MediaUploadPublic{}, err
+	}
+
+	key := storage.BuildStagingKey(userID, keyFilename(filename, mimeType))
+	expiresAt := time.Now().Add(med.ttl)
+
+	url, err := med.st.PresignPut(ctx, key, mimeType, med.ttl)
+	if err != nil {
+		return models.MediaUploadPublic{}, fmt.Errorf("presign put: %w", err)
+	}
+
+	up, err := med.repo.CreateUpload(ctx, repositories.CreateUploadParams{
 		OwnerID:    userID,
 		Kind:       kind,
 		StorageKey: key,
 		MimeType:   mimeType,
 		SizeBytes:  size,
+		ExpiresAt:  expiresAt,
+		AltText:    altText,
 	})
+	if err != nil {
+		return models.MediaUploadPublic{}, err
+	}
+
+	return models.MediaUploadPublic{
+		ID:        up.ID,
+		Method:    "PUT",
+		UploadURL: url,
+		Headers:   map[string]string{"Content-Type": mimeType},
+		ExpiresAt: up.ExpiresAt,
+	}, nil
+}
 
+// discardUpload deletes the staged object of an upload once it was
+// completed or failed verification.
+func (med *mediaService) discardUpload(ctx context.Context, up models.MediaUpload) {
+	if err := med.st.Delete(context.WithoutCancel(ctx), up.StorageKey); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("delete staged upload %d: %v", up.ID, err)
+	}
+}
+
+// copyUpload copies the staged object of up to key, failing with
+// ErrUploadMismatch when it is not of the declared size.
+func (med *mediaService) copyUpload(ctx context.Context, up models.MediaUpload, key string) error {
+	rc, err := med.st.Open(ctx, up.StorageKey)
 	if err != nil {
-		return models.MediaPublic{}, fmt.Errorf("media create: %w", err)
+		return fmt.Errorf("storage open: %w", err)
 	}
+	defer rc.Close()
 
-	if err := med.repo.AttachToPost(ctx, postID, media.ID, 0); err != nil {
-		return models.MediaPublic{}, fmt.Errorf("attach to post: %w", err)
+	ur := &uploadReader{r: rc, max: up.SizeBytes}
+	if err := med.st.Save(ctx, key, ur, up.SizeBytes, up.MimeType); err != nil {
+		if errors.Is(ur.err, errOverLimit) {
+			return ErrUploadMismatch
+		}
+		return fmt.Errorf("storage save: %w", err)
+	}
+	if ur.n != up.SizeBytes {
+		return ErrUploadMismatch
+	}
+	return nil
+}
+
+// CheckUploadOpen implements MediaService.
+func (med *mediaService) CheckUploadOpen(ctx context.Context, key string) (int64, error) {
+	up, err := med.repo.GetUploadByKey(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if up.CompletedAt != nil {
+		return 0, ErrUploadCompleted
+	}
+	if time.Now().After(up.ExpiresAt) {
+		return 0, ErrUploadExpired
+	}
+	return up.SizeBytes, nil
+}
+
+// CompleteUpload implements MediaService.
+func (med *mediaService) CompleteUpload(ctx context.Context, userID int64, id int64) (models.MediaPublic, error) {
+	up, err := med.repo.GetUpload(ctx, userID, id)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+	if up.CompletedAt != nil {
+		return models.MediaPublic{}, ErrUploadCompleted
+	}
 
+	info, err := med.st.Stat(ctx, up.StorageKey)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Nothing was stored, so an expired upload is simply dead.
+			if time.Now().After(up.ExpiresAt) {
+				return models.MediaPublic{}, ErrUploadExpired
+			}
+			return models.MediaPublic{}, ErrUploadMissing
+		}
+		return models.MediaPublic{}, fmt.Errorf("storage stat: %w", err)
 	}
 
-	url, _ := med.st.PresignGet(ctx, media.StorageKey, med.ttl)
-	return media.PublicWithURL(url), nil
+	// Drivers that do not keep a content type (local) verify it at PUT time.
+	typeOK := info.ContentType == "" || strings.EqualFold(info.ContentType, up.MimeType)
+	if info.Size != up.SizeBytes || !typeOK {
+		med.discardUpload(ctx, up)
+		return models.MediaPublic{}, ErrUploadMismatch
+	}
 
+	// Everything below checks a copy under a fresh key: the client can PUT
+	// to the staging key until it expires, but holds no URL for this one.
+	key := buildKey(userID, up.StorageKey, up.MimeType)
+	if err := med.copyUpload(ctx, up, key); err != nil {
+		med.deleteObject(ctx, key)
+		if errors.Is(err, ErrUploadMismatch) {
+			med.discardUpload(ctx, up)
+		}
+		return models.MediaPublic{}, err
+	}
+	done := false
+	defer func() {
+		if !done {
+			med.deleteObject(ctx, key)
+		}
+	}()
+
+	detected, err := med.sniffObject(ctx, key)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+	if detected != up.MimeType {
+		med.discardUpload(ctx, up)
+		return models.MediaPublic{}, ErrMimeMismatch
+	}
+
+	size := info.Size
+	mimeType := up.MimeType
+	var width, height *int32
+	if up.Kind == "image" {
+		cleaned, imgInfo, err := med.cleanStoredImage(ctx, key, up.MimeType)
+		if err != nil {
+			if errors.Is(err, ErrInvalidImage) {
+				med.discardUpload(ctx, up)
+			}
+			return models.MediaPublic{}, err
+		}
+		size = cleaned
+		width, height = dimensions(imgInfo)
+		mimeType = imgInfo.MimeType
+	}
+
+	// Checked again on what was stored, as other uploads may have finished
+	// since this one was created. The staged object stays for a later
+	// attempt once the owner has freed space.
+	if err := med.CheckQuota(ctx, userID, size); err != nil {
+		return models.MediaPublic{}, err
+	}
+
+	hash, err := med.hashObject(ctx, key)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+
+	media, err := med.repo.CompleteUpload(ctx, up.ID, repositories.CreateMediaParams{
+		OwnerID:     userID,
+		Kind:        up.Kind,
+		StorageKey:  key,
+		MimeType:    mimeType,
+		SizeBytes:   size,
+		Width:       width,
+		Height:      height,
+		Status:      models.MediaStatusProcessing,
+		ContentHash: hash,
+		AltText:     up.AltText,
+	})
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+	done = true
+	med.discardUpload(ctx, up)
+	med.dropDuplicate(ctx, media, key)
+	med.pipeline.Enqueue(media.ID)
+
+	return med.publicForOwner(ctx, media), nil
 }
 
-// UploadUserAvatar implements MediaService.
-func (med *mediaService) UploadUserAvatar(ctx context.Context, userID int64, filename string, r io.Reader, size int64, mimeType string) (models.MediaPublic, error) {
-	panic("unimplemented")
+// ListOwned implements MediaService.
+func (med *mediaService) ListOwned(ctx context.Context, userID int64, kind string, limit int32, offset int32) ([]models.MediaPublic, error) {
+	items, err := med.repo.ListOwned(ctx, userID, kind, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	urlFor := presignFor(ctx, med.st, med.ttl)
+	out := make([]models.MediaPublic, 0, len(items))
+	for _, m := range items {
+		out = append(out, m.PublicWith(urlFor))
+	}
+	return out, nil
+}
+
+// Delete implements MediaService.
+func (med *mediaService) Delete(ctx context.Context, userID int64, id int64) error {
+	return med.repo.SoftDelete(ctx, userID, id)
+}
+
+// UpdateAltText implements MediaService.
+func (med *mediaService) UpdateAltText(ctx context.Context, userID int64, id int64, alt string) (models.MediaPublic, error) {
+	owned, err := med.repo.ListOwnedByIDs(ctx, userID, []int64{id})
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+	if len(owned) == 0 {
+		return models.MediaPublic{}, ErrMediaNotFound
+	}
+
+	alt, err = med.CheckAltText(owned[0].Kind, alt)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+
+	media, err := med.repo.UpdateAltText(ctx, userID, id, alt)
+	if err != nil {
+		return models.MediaPublic{}, err
+	}
+	return med.publicForOwner(ctx, media), nil
+}
+
+// quotaRoom checks the quota of userID ahead of an upload of size bytes, -1
+// when unknown, and returns the room left for it.
+func (med *mediaService) quotaRoom(ctx context.Context, userID, size int64) (quotaRoom, error) {
+	q := med.limits.Quota.For(auth.RoleFromCtx(ctx))
+	if q.MaxBytes <= 0 && q.MaxFilesPerDay <= 0 {
+		return quotaRoom{}, nil
+	}
+
+	day := dayStart(time.Now())
+	usage, err := med.repo.Usage(ctx, userID, day)
+	if err != nil {
+		return quotaRoom{}, err
+	}
+	if q.MaxFilesPerDay > 0 && usage.Files >= q.MaxFilesPerDay {
+		return quotaRoom{}, &UploadRateError{MaxFiles: q.MaxFilesPerDay, ResetAt: day.Add(24 * time.Hour)}
+	}
+
+	room := quotaRoom{max: q.MaxBytes, used: usage.Bytes}
+	// An upload of unknown size needs room for at least one byte.
+	if !room.fits(max(size, 1)) {
+		return quotaRoom{}, room.err()
+	}
+	return room, nil
+}
+
+// CheckQuota implements MediaService.
+func (med *mediaService) CheckQuota(ctx context.Context, userID int64, size int64) error {
+	_, err := med.quotaRoom(ctx, userID, size)
+	return err
+}
+
+// Quota implements MediaService.
+func (med *mediaService) Quota(ctx context.Context, userID int64) (models.QuotaUsage, error) {
+	q := med.limits.Quota.For(auth.RoleFromCtx(ctx))
+	day := dayStart(time.Now())
+	usage, err := med.repo.Usage(ctx, userID, day)
+	if err != nil {
+		return models.QuotaUsage{}, err
+	}
+
+	return models.QuotaUsage{
+		UsedBytes:      usage.Bytes,
+		MaxBytes:       q.MaxBytes,
+		FilesToday:     usage.Files,
+		MaxFilesPerDay: q.MaxFilesPerDay,
+		FilesResetAt:   day.Add(24 * time.Hour),
+	}, nil
+}
+
+// ServedObject implements MediaService.
+func (med *mediaService) ServedObject(ctx context.Context, key string) (models.ServedObject, error) {
+	return med.repo.GetServedObject(ctx, key)
 }
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPostMediaLimitsCheck(t *testing.T) {
+	single := PostMediaLimits{Images: 4, Videos: 1, Audio: 1}
+	mixed := PostMediaLimits{Images: 4, Videos: 1, Audio: 1, Mixed: true}
+
+	tests := []struct {
+		name    string
+		limits  PostMediaLimits
+		kinds   []string
+		wantErr error
+		wantMax *PostMediaLimitError
+	}{
+		{"no media", single, nil, nil, nil},
+		{"four images", single, []string{"image", "image", "image", "image"}, nil, nil},
+		{"one video", single, []string{"video"}, nil, nil},
+		{"five images", single, []string{"image", "image", "image", "image", "image"}, nil, &PostMediaLimitError{Kind: "image", Max: 4}},
+		{"two videos", single, []string{"video", "video"}, nil, &PostMediaLimitError{Kind: "video", Max: 1}},
+		{"mixed kinds", single, []string{"image", "video"}, ErrMixedPostMedia, nil},
+		{"mixed kinds allowed", mixed, []string{"image", "video", "audio"}, nil, nil},
+		{"mixed over a limit", mixed, []string{"image", "video", "video"}, nil, &PostMediaLimitError{Kind: "video", Max: 1}},
+		{"unknown kind", single, []string{"document"}, nil, &PostMediaLimitError{Kind: "document", Max: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(tt.kinds)
+
+			var limitErr *PostMediaLimitError
+			switch {
+			case tt.wantMax != nil:
+				if !errors.As(err, &limitErr) || *limitErr != *tt.wantMax {
+					t.Errorf("err = %v, want %v", err, tt.wantMax)
+				}
+			case err != tt.wantErr:
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/realtime"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxGroupParticipants = 10
+	maxMessageLength     = 4000
+	maxMessageMedia      = 10
+)
+
+var (
+	ErrInvalidParticipants  = errors.New("invalid participants")
+	ErrTooManyParticipants  = fmt.Errorf("a conversation can have at most %d participants", maxGroupParticipants)
+	ErrEmptyMessage         = errors.New("message must have a body or attachments")
+	ErrMessageTooLong       = fmt.Errorf("message body is limited to %d characters", maxMessageLength)
+	ErrTooManyAttachments   = fmt.Errorf("a message can have at most %d attachments", maxMessageMedia)
+	ErrMediaNotOwned        = errors.New("media not found or not owned by user")
+	ErrNotFollower          = errors.New("private accounts only accept new conversations from approved followers")
+	ErrConversationNotFound = repositories.ErrConversationNotFound
+)
+
+type MessageService interface {
+	StartConversation(ctx context.Context, userID int64, participantIDs []int64, title *string) (models.ConversationPublic, error)
+	GetConversation(ctx context.Context, userID, id int64) (models.ConversationPublic, error)
+	ListConversations(ctx context.Context, userID int64, cursor string, limit int32) ([]models.ConversationPublic, string, error)
+	Send(ctx context.Context, userID, conversationID int64, body string, mediaIDs []int64) (models.MessagePublic, error)
+	ListMessages(ctx context.Context, userID, conversationID int64, cursor string, limit int32) ([]models.MessagePublic, string, error)
+	MarkRead(ctx context.Context, userID, conversationID, messageID int64) error
+}
+
+type messageService struct {
+	repo    repositories.ConversationRepository
+	users   repositories.UserRepository
+	blocks  repositories.BlockRepository
+	follows repositories.FollowRepository
+	media   repositories.MediaRepository
+	broker  realtime.Broker
+	st      storage.Storage
+	ttl     time.Duration
+}
+
+func NewMessageService(repo repositories.ConversationRepository, users repositories.UserRepository, blocks repositories.BlockRepository, follows repositories.FollowRepository, media repositories.MediaRepository, broker realtime.Broker, st storage.Storage, presignTTL time.Duration) MessageService {
+	return &messageService{repo: repo, users: users, blocks: blocks, follows: follows, media: media, broker: broker, st: st, ttl: presignTTL}
+}
+
+// checkBlocks fails when userID and any of others block each other.
+func (s *messageService) checkBlocks(ctx context.Context, userID int64, others []int64) error {
+	for _, id := range others {
+		blocked, err := s.blocks.IsBlockedEither(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+	}
+	return nil
+}
+
+// uniqueIDs drops duplicates while keeping the caller's order.
+func uniqueIDs(ids []int64) []int64 {
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (s *messageService) publicMessage(ctx context.Context, m models.Message) models.MessagePublic {
+	pub := models.MessagePublic{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+		Medias:         make([]models.MediaPublic, 0, len(m.Medias)),
+	}
+	urlFor := presignFor(ctx, s.st, s.ttl)
+	for _, md := range m.Medias {
+		pub.Medias = append(pub.Medias, md.PublicWith(urlFor))
+	}
+	return pub
+}
+
+func (s *messageService) publish(ctx context.Context, conv models.Conversation, typ string, data any) {
+	for _, p := range conv.Participants {
+		if err := s.broker.Publish(ctx, realtime.UserTopic(p.UserID), typ, data); err != nil {
+			log.Printf("publish %s conversation=%d: %v", typ, conv.ID, err)
+		}
+	}
+}
+
+// StartConversation implements MessageService. A 1:1 conversation with the
+// same user is reused instead of creating a duplicate.
+func (s *messageService) StartConversation(ctx context.Context, userID int64, participantIDs []int64, title *string) (models.ConversationPublic, error) {
+	others := make([]int64, 0, len(participantIDs))
+	for _, id := range uniqueIDs(participantIDs) {
+		if id > 0 && id != userID {
+			others = append(others, id)
+		}
+	}
+
+	if len(others) == 0 {
+		return models.ConversationPublic{}, ErrInvalidParticipants
+	}
+	if len(others)+1 > maxGroupParticipants {
+		return models.ConversationPublic{}, ErrTooManyParticipants
+	}
+
+	for _, id := range others {
+		usr, err := s.users.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				return models.ConversationPublic{}, ErrInvalidParticipants
+			}
+			return models.ConversationPublic{}, err
+		}
+		if usr.IsPrivate {
+			ok, err := s.follows.IsFollowing(ctx, userID, id)
+			if err != nil {
+				return models.ConversationPublic{}, err
+			}
+			if !ok {
+				return models.ConversationPublic{}, ErrNotFollower
+			}
+		}
+	}
+
+	if err := s.checkBlocks(ctx, userID, others); err != nil {
+		return models.ConversationPublic{}, err
+	}
+
+	if len(others) == 1 {
+		conv, err := s.repo.CreateDirect(ctx, userID, others[0])
+		if err != nil {
+			return models.ConversationPublic{}, err
+		}
+		return conv.Public(), nil
+	}
+
+	if title != nil {
+		t := strings.TrimSpace(*title)
+		title = &t
+		if t == "" {
+			title = nil
+		}
+	}
+
+	conv, err := s.repo.Create(ctx, models.ConversationGroup, title, userID, append([]int64{userID}, others...))
+	if err != nil {
+		return models.ConversationPublic{}, err
+	}
+	return conv.Public(), nil
+}
+
+// GetConversation implements MessageService.
+func (s *messageService) GetConversation(ctx context.Context, userID int64, id int64) (models.ConversationPublic, error) {
+	conv, err := s.repo.GetForUser(ctx, id, userID)
+	if err != nil {
+		return models.ConversationPublic{}, err
+	}
+	return conv.Public(), nil
+}
+
+// ListConversations implements MessageService.
+func (s *messageService) ListConversations(ctx context.Context, userID int64, cursor string, limit int32) ([]models.ConversationPublic, string, error) {
+	var beforeAt *time.Time
+	var beforeID *int64
+	if cursor != "" {
+		at, id, err := helpers.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		beforeAt, beforeID = &at, &id
+	}
+
+	items, err := s.repo.ListByUser(ctx, userID, beforeAt, beforeID, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out := make([]models.ConversationPublic, 0, len(items))
+	for _, it := range items {
+		out = append(out, it.Public())
+	}
+
+	next := ""
+	if len(items) == int(limit) && len(items) > 0 {
+		last := items[len(items)-1]
+		next = helpers.EncodeCursor(last.LastActivityAt, last.ID)
+	}
+	return out, next, nil
+}
+
+// Send implements MessageService.
+func (s *messageService) Send(ctx context.Context, userID int64, conversationID int64, body string, mediaIDs []int64) (models.MessagePublic, error) {
+	body = strings.TrimSpace(body)
+	mediaIDs = uniqueIDs(mediaIDs)
+	if body == "" && len(mediaIDs) == 0 {
+		return models.MessagePublic{}, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return models.MessagePublic{}, ErrMessageTooLong
+	}
+	if len(mediaIDs) > maxMessageMedia {
+		return models.MessagePublic{}, ErrTooManyAttachments
+	}
+
+	conv, err := s.repo.GetForUser(ctx, conversationID, userID)
+	if err != nil {
+		return models.MessagePublic{}, err
+	}
+
+	// Blocking ends a 1:1 conversation; groups stay usable for everyone else.
+	if conv.Kind == models.ConversationDirect {
+		others := make([]int64, 0, 1)
+		for _, p := range conv.Participants {
+			if p.UserID != userID {
+				others = append(others, p.UserID)
+			}
+		}
+		if err := s.checkBlocks(ctx, userID, others); err != nil {
+			return models.MessagePublic{}, err
+		}
+	}
+
+	if len(mediaIDs) > 0 {
+		owned, err := s.media.ListOwnedByIDs(ctx, userID, mediaIDs)
+		if err != nil {
+			return models.MessagePublic{}, err
+		}
+		if len(owned) != len(mediaIDs) {
+			return models.MessagePublic{}, ErrMediaNotOwned
+		}
+	}
+
+	msg, err := s.repo.CreateMessage(ctx, conversationID, userID, body, mediaIDs)
+	if err != nil {
+		return models.MessagePublic{}, err
+	}
+
+	pub := s.publicMessage(ctx, msg)
+	s.publish(ctx, conv, realtime.EventMessageCreated, pub)
+	return pub, nil
+}
+
+// ListMessages implements MessageService. The cursor is the id of the oldest
+// message already seen; pages go backwards in time.
+func (s *messageService) ListMessages(ctx context.Context, userID int64, conversationID int64, cursor string, limit int32) ([]models.MessagePublic, string, error) {
+	var beforeID *int64
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, "", helpers.ErrBadCursor
+		}
+		beforeID = &id
+	}
+
+	if _, err := s.repo.GetForUser(ctx, conversationID, userID); err != nil {
+		return nil, "", err
+	}
+
+	items, err := s.repo.ListMessages(ctx, conversationID, beforeID, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out := make([]models.MessagePublic, 0, len(items))
+	for _, it := range items {
+		out = append(out, s.publicMessage(ctx, it))
+	}
+
+	next := ""
+	if len(items) == int(limit) && len(items) > 0 {
+		next = strconv.FormatInt(items[len(items)-1].ID, 10)
+	}
+	return out, next, nil
+}
+
+// MarkRead implements MessageService.
+func (s *messageService) MarkRead(ctx context.Context, userID int64, conversationID int64, messageID int64) error {
+	conv, err := s.repo.GetForUser(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.MarkRead(ctx, conversationID, userID, messageID); err != nil {
+		return err
+	}
+
+	s.publish(ctx, conv, realtime.EventConversationRead, map[string]int64{
+		"conversation_id": conversationID,
+		"user_id":         userID,
+		"message_id":      messageID,
+	})
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/realtime"
+	"go-rest-chi/internal/repositories"
+	"log"
+)
+
+var ErrUnknownNotificationType = errors.New("unknown notification type")
+
+type NotifyParams struct {
+	UserID   int64
+	ActorID  int64
+	Type     string
+	EntityID *int64
+}
+
+type NotificationService interface {
+	Notify(ctx context.Context, p NotifyParams) error
+	List(ctx context.Context, userID int64, limit, offset int32) ([]models.NotificationPublic, int64, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+	Preferences(ctx context.Context, userID int64) (map[string]bool, error)
+	UpdatePreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error)
+}
+
+type notificationService struct {
+	repo   repositories.NotificationRepository
+	blocks repositories.BlockRepository
+	broker realtime.Broker
+}
+
+func NewNotificationService(repo repositories.NotificationRepository, blocks repositories.BlockRepository, broker realtime.Broker) NotificationService {
+	return &notificationService{repo: repo, blocks: blocks, broker: broker}
+}
+
+// groupKey decides which events collapse into one unread notification:
+// all new followers (and follow requests) share one entry, everything else
+// is grouped per entity.
+func groupKey(typ string, entityID *int64) string {
+	if typ == models.NotificationFollow || typ == models.NotificationFollowRequest || entityID == nil {
+		return typ
+	}
+	return fmt.Sprintf("%s:%d", typ, *entityID)
+}
+
+// Notify implements NotificationService.
+func (n *notificationService) Notify(ctx context.Context, p NotifyParams) error {
+	if !models.IsNotificationType(p.Type) {
+		return ErrUnknownNotificationType
+	}
+
+	if p.UserID == p.ActorID {
+		return nil
+	}
+
+	blocked, err := n.blocks.IsBlockedEither(ctx, p.UserID, p.ActorID)
+	if err != nil {
+		return fmt.Errorf("IsBlockedEither: %w", err)
+	}
+	if blocked {
+		return nil
+	}
+
+	enabled, err := n.repo.IsTypeEnabled(ctx, p.UserID, p.Type)
+	if err != nil {
+		return fmt.Errorf("IsTypeEnabled: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	notif, err := n.repo.Upsert(ctx, repositories.CreateNotificationParams{
+		UserID:   p.UserID,
+		ActorID:  p.ActorID,
+		Type:     p.Type,
+		EntityID: p.EntityID,
+		GroupKey: groupKey(p.Type, p.EntityID),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := n.broker.Publish(ctx, realtime.UserTopic(p.UserID), realtime.EventNotification, notif.Public()); err != nil {
+		log.Printf("publish notification %d: %v", notif.ID, err)
+	}
+	return nil
+}
+
+// List implements NotificationService.
+func (n *notificationService) List(ctx context.Context, userID int64, limit int32, offset int32) ([]models.NotificationPublic, int64, error) {
+	items, err := n.repo.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := n.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CountUnread: %w", err)
+	}
+
+	out := make([]models.NotificationPublic, 0, len(items))
+	for _, it := range items {
+		out = append(out, it.Public())
+	}
+	return out, unread, nil
+}
+
+// MarkRead implements NotificationService.
+func (n *notificationService) MarkRead(ctx context.Context, userID int64, id int64) error {
+	return n.repo.MarkRead(ctx, userID, id)
+}
+
+// MarkAllRead implements NotificationService.
+func (n *notificationService) MarkAllRead(ctx context.Context, userID int64) error {
+	return n.repo.MarkAllRead(ctx, userID)
+}
+
+// Preferences implements NotificationService.
+func (n *notificationService) Preferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	stored, err := n.repo.ListPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		enabled, ok := stored[t]
+		out[t] = !ok || enabled
+	}
+	return out, nil
+}
+
+// UpdatePreferences implements NotificationService.
+func (n *notificationService) UpdatePreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error) {
+	for t := range prefs {
+		if !models.IsNotificationType(t) {
+			return nil, ErrUnknownNotificationType
+		}
+	}
+
+	for t, enabled := range prefs {
+		if err := n.repo.SetPreference(ctx, userID, t, enabled); err != nil {
+			return nil, fmt.Errorf("SetPreference: %w", err)
+		}
+	}
+
+	return n.Preferences(ctx, userID)
+}
@@ -3,80 +3,255 @@ package services
 import (
 	"context"
 	"fmt"
+	"go-rest-chi/internal/helpers"
 	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/realtime"
 	"go-rest-chi/internal/repositories"
 	"go-rest-chi/internal/storage"
+	"log"
+	"strings"
+)
+
+var (
+	ErrInvalidVisibility  = fmt.Errorf("visibility must be one of: %s", strings.Join(models.PostVisibilities, ", "))
+	ErrPostNotFound       = repositories.ErrPostNotFound
+	ErrPostMediaNotFound  = repositories.ErrPostMediaNotFound
+	ErrMediaOrderMismatch = repositories.ErrMediaOrderMismatch
 )
 
 type PostService interface {
-	Create(ctx context.Context, title string, description string, userId int64) (models.PostPublic, error)
-	SoftDelete(ctx context.Context, id int64) error
-	UpdatePartitial(ctx context.Context, id int64, title *string, description *string) (models.PostPublic, error)
-	ListPaginated(ctx context.Context, userId *int64, limit, offset int32) ([]models.PostMediaPublic, error)
+	// Create stores a post with the uploaded media of mediaIDs attached in
+	// that order; an empty visibility defaults to public.
+	Create(ctx context.Context, title string, description string, userId int64, visibility string, mediaIDs []int64) (models.PostPublic, error)
+	// SoftDelete and UpdatePartitial act on posts of userID only; other
+	// posts give ErrPostNotFound.
+	SoftDelete(ctx context.Context, userID, id int64) error
+	UpdatePartitial(ctx context.Context, userID, id int64, title *string, description *string, visibility *string) (models.PostPublic, error)
+	Get(ctx context.Context, viewerID, id int64) (models.PostMediaPublic, error)
+	ListPaginated(ctx context.Context, viewerID int64, userId *int64, limit, offset int32) ([]models.PostMediaPublic, error)
+
+	// DetachMedia removes media from a post of userID. The media itself
+	// stays in the owner's library.
+	DetachMedia(ctx context.Context, userID, postID, mediaID int64) error
+	// ReorderMedia sets the order of the media on a post of userID and
+	// returns the updated post.
+	ReorderMedia(ctx context.Context, userID, postID int64, mediaIDs []int64) (models.PostMediaPublic, error)
 }
 
 type postService struct {
-	repo repositories.PostRepository
-	st   storage.Storage
+	repo    repositories.PostRepository
+	media   repositories.MediaRepository
+	limits  PostMediaLimits
+	users   repositories.UserRepository
+	follows repositories.FollowRepository
+	notifs  NotificationService
+	broker  realtime.Broker
+	st      storage.Storage
+}
+
+func NewPostService(r repositories.PostRepository, media repositories.MediaRepository, limits PostMediaLimits, users repositories.UserRepository, follows repositories.FollowRepository, notifs NotificationService, broker realtime.Broker, st storage.Storage) PostService {
+	return &postService{repo: r, media: media, limits: limits, users: users, follows: follows, notifs: notifs, broker: broker, st: st}
 }
 
-func NewPostService(r repositories.PostRepository, st storage.Storage) PostService {
-	return &postService{repo: r, st: st}
+func (p *postService) publish(ctx context.Context, typ string, data any) {
+	if err := p.broker.Publish(ctx, realtime.TopicFeed, typ, data); err != nil {
+		log.Printf("publish %s: %v", typ, err)
+	}
+}
+
+// publishPost sends a post event to the public feed when anyone may see it.
+// Otherwise it goes to the author's audience only: mentioned users for
+// mentioned-only posts, accepted followers for everything else.
+func (p *postService) publishPost(ctx context.Context, typ string, post models.Post, mentionIDs []int64) {
+	author, err := p.users.GetByID(ctx, post.UserId)
+	if err != nil {
+		log.Printf("publish %s: author %d: %v", typ, post.UserId, err)
+		return
+	}
+	if post.Visibility == models.VisibilityPublic && !author.IsPrivate {
+		p.publish(ctx, typ, post.Public())
+		return
+	}
+
+	audience := []int64{post.UserId}
+	if post.Visibility == models.VisibilityMentioned {
+		audience = append(audience, mentionIDs...)
+	} else {
+		followers, err := p.follows.FollowerIDs(ctx, post.UserId)
+		if err != nil {
+			log.Printf("publish %s: followers of %d: %v", typ, post.UserId, err)
+			return
+		}
+		audience = append(audience, followers...)
+	}
+
+	for _, id := range audience {
+		if err := p.broker.Publish(ctx, realtime.UserTopic(id), typ, post.Public()); err != nil {
+			log.Printf("publish %s to user %d: %v", typ, id, err)
+		}
+	}
 }
 
 // Create implements PostService.
-func (p *postService) Create(ctx context.Context, title string, description string, userId int64) (models.PostPublic, error) {
-	post, err := p.repo.Create(ctx, title, description, userId)
+func (p *postService) Create(ctx context.Context, title string, description string, userId int64, visibility string, mediaIDs []int64) (models.PostPublic, error) {
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+	if !models.IsPostVisibility(visibility) {
+		return models.PostPublic{}, ErrInvalidVisibility
+	}
+
+	if len(mediaIDs) > 0 {
+		owned, err := p.media.ListOwnedByIDs(ctx, userId, mediaIDs)
+		if err != nil {
+			return models.PostPublic{}, err
+		}
+		if len(owned) != len(mediaIDs) {
+			return models.PostPublic{}, ErrMediaNotOwned
+		}
+
+		kinds := make([]string, 0, len(owned))
+		for _, m := range owned {
+			kinds = append(kinds, m.Kind)
+		}
+		if err := p.limits.Check(kinds); err != nil {
+			return models.PostPublic{}, err
+		}
+	}
+
+	post, err := p.repo.Create(ctx, title, description, userId, visibility, mediaIDs)
 	if err != nil {
 		return models.PostPublic{}, fmt.Errorf("CreatePost : %v", err)
 	}
 
+	mentionIDs := p.saveMentions(ctx, post)
+	p.notifyMentions(ctx, post, mentionIDs)
+	p.publishPost(ctx, realtime.EventPostCreated, post, mentionIDs)
+
 	return post.Public(), nil
 }
 
+// saveMentions records every existing user referenced as @username in the
+// post and returns their ids. Failures are logged and yield no mentions.
+func (p *postService) saveMentions(ctx context.Context, post models.Post) []int64 {
+	ids := make([]int64, 0)
+	for _, username := range helpers.ExtractMentions(post.Title, post.Description) {
+		usr, err := p.users.GetByUsername(ctx, username)
+		if err != nil || usr.Id == post.UserId {
+			continue
+		}
+		ids = append(ids, usr.Id)
+	}
+
+	if err := p.repo.SetMentions(ctx, post.Id, ids); err != nil {
+		log.Printf("save mentions post=%d: %v", post.Id, err)
+		return nil
+	}
+	return ids
+}
+
+// notifyMentions emits a mention notification to every mentioned user who
+// can actually see the post. Failures never fail the post itself.
+func (p *postService) notifyMentions(ctx context.Context, post models.Post, mentionIDs []int64) {
+	for _, uid := range mentionIDs {
+		if _, err := p.repo.GetWithMedia(ctx, uid, post.Id); err != nil {
+			continue
+		}
+
+		postID := post.Id
+		if err := p.notifs.Notify(ctx, NotifyParams{
+			UserID:   uid,
+			ActorID:  post.UserId,
+			Type:     models.NotificationMention,
+			EntityID: &postID,
+		}); err != nil {
+			log.Printf("notify mention post=%d user=%d: %v", post.Id, uid, err)
+		}
+	}
+}
+
+func (p *postService) toPublic(ctx context.Context, it models.PostMedia) models.PostMediaPublic {
+	pub := models.PostMediaPublic{
+		Post:   it.Post.Public(),
+		Medias: make([]models.MediaPublic, 0, len(it.Medias)),
+	}
+
+	urlFor := func(key string) string {
+		url, _ := p.st.URL(ctx, key)
+		return url
+	}
+	for _, m := range it.Medias {
+		pub.Medias = append(pub.Medias, m.PublicWith(urlFor))
+	}
+	return pub
+}
+
+// Get implements PostService.
+func (p *postService) Get(ctx context.Context, viewerID int64, id int64) (models.PostMediaPublic, error) {
+	item, err := p.repo.GetWithMedia(ctx, viewerID, id)
+	if err != nil {
+		return models.PostMediaPublic{}, err
+	}
+	return p.toPublic(ctx, item), nil
+}
+
 // ListPaginated implements PostService.
-func (p *postService) ListPaginated(ctx context.Context, userId *int64, limit int32, offset int32) ([]models.PostMediaPublic, error) {
-	items, err := p.repo.ListWithMediaPaginated(ctx, userId, limit, offset)
+func (p *postService) ListPaginated(ctx context.Context, viewerID int64, userId *int64, limit int32, offset int32) ([]models.PostMediaPublic, error) {
+	items, err := p.repo.ListWithMediaPaginated(ctx, viewerID, userId, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("ListWithMediaPaginated: %w", err)
 	}
 
 	out := make([]models.PostMediaPublic, 0, len(items))
 	for _, it := range items {
-		pub := models.PostMediaPublic{
-			Post:   it.Post.Public(),
-			Medias: make([]models.MediaPublic, 0, len(it.Medias)),
-		}
-
-		for _, m := range it.Medias {
-			url, _ := p.st.URL(ctx, m.StorageKey)
-			pub.Medias = append(pub.Medias, models.MediaPublic{
-				ID:         m.ID,
-				Kind:       m.Kind,
-				MimeType:   m.MimeType,
-				URL:        url,
-				Width:      m.Width,
-				Height:     m.Height,
-				DurationMs: m.DurationMs,
-			})
-		}
-
-		out = append(out, pub)
+		out = append(out, p.toPublic(ctx, it))
 	}
 	return out, nil
 }
 
 // SoftDelete implements PostService.
-func (p *postService) SoftDelete(ctx context.Context, id int64) error {
-	return p.repo.SoftDelete(ctx, id)
+func (p *postService) SoftDelete(ctx context.Context, userID int64, id int64) error {
+	if err := p.repo.SoftDelete(ctx, userID, id); err != nil {
+		return err
+	}
+
+	p.publish(ctx, realtime.EventPostDeleted, map[string]int64{"id": id})
+	return nil
 }
 
-// UpdatePartitial implements PostService.
-func (p *postService) UpdatePartitial(ctx context.Context, id int64, title *string, description *string) (models.PostPublic, error) {
-	post, err := p.repo.UpdatePartitial(ctx, id, title, description)
+// UpdatePartitial implements PostService. Editing the text refreshes the
+// stored mentions; no new mention notifications are sent.
+func (p *postService) UpdatePartitial(ctx context.Context, userID int64, id int64, title *string, description *string, visibility *string) (models.PostPublic, error) {
+	if visibility != nil && !models.IsPostVisibility(*visibility) {
+		return models.PostPublic{}, ErrInvalidVisibility
+	}
+
+	post, err := p.repo.UpdatePartitial(ctx, userID, id, title, description, visibility)
 	if err != nil {
-		return models.PostPublic{}, fmt.Errorf("UpdatePost[%d] : %v", id, err)
+		return models.PostPublic{}, fmt.Errorf("UpdatePost[%d] : %w", id, err)
 	}
 
+	var mentionIDs []int64
+	if title != nil || description != nil {
+		mentionIDs = p.saveMentions(ctx, post)
+	} else if mentionIDs, err = p.repo.MentionIDs(ctx, post.Id); err != nil {
+		log.Printf("mentions of post %d: %v", post.Id, err)
+	}
+
+	p.publishPost(ctx, realtime.EventPostUpdated, post, mentionIDs)
+
 	return post.Public(), nil
 }
+
+// DetachMedia implements PostService.
+func (p *postService) DetachMedia(ctx context.Context, userID int64, postID int64, mediaID int64) error {
+	return p.repo.DetachMedia(ctx, userID, postID, mediaID)
+}
+
+// ReorderMedia implements PostService.
+func (p *postService) ReorderMedia(ctx context.Context, userID int64, postID int64, mediaIDs []int64) (models.PostMediaPublic, error) {
+	if err := p.repo.ReorderMedia(ctx, userID, postID, mediaIDs); err != nil {
+		return models.PostMediaPublic{}, err
+	}
+	return p.Get(ctx, userID, postID)
+}
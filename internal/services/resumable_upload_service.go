@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"hash"
+	"io"
+	"log"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// ChecksumAlgorithms lists the Upload-Checksum algorithms WriteChunk accepts.
+var ChecksumAlgorithms = []string{"sha1", "sha256", "md5"}
+
+var (
+	ErrResumableUploadNotFound = repositories.ErrResumableUploadNotFound
+	ErrUploadFinishing         = repositories.ErrUploadFinishing
+	ErrUploadTooLarge          = errors.New("upload exceeds the maximum size")
+	ErrOffsetMismatch          = errors.New("offset does not match the upload offset")
+	ErrChunkTooLarge           = errors.New("chunk exceeds the declared upload length")
+	ErrChecksumMismatch        = errors.New("checksum mismatch")
+	ErrUnsupportedChecksum     = errors.New("unsupported checksum algorithm")
+)
+
+type ResumableUploadService interface {
+	MaxSize() int64
+	// Create starts an upload; altText is validated now and stored with
+	// the media once the last chunk arrives.
+	Create(ctx context.Context, userID, length int64, filename, mimeType, metadata, altText string) (models.ResumableUpload, error)
+	Get(ctx context.Context, userID int64, id string) (models.ResumableUpload, error)
+	// WriteChunk stores r at offset. When algo is set the chunk is only kept
+	// if its digest equals sum. The upload that reaches its length is handed
+	// to MediaService and comes back with MediaID set.
+	WriteChunk(ctx context.Context, userID int64, id string, offset int64, r io.Reader, algo string, sum []byte) (models.ResumableUpload, error)
+	Terminate(ctx context.Context, userID int64, id string) error
+}
+
+type resumableUploadService struct {
+	repo    repositories.ResumableUploadRepository
+	media   MediaService
+	st      storage.Storage
+	maxSize int64
+	expiry  time.Duration
+}
+
+func NewResumableUploadService(repo repositories.ResumableUploadRepository, media MediaService, st storage.Storage, maxSize int64, expiry time.Duration) ResumableUploadService {
+	return &resumableUploadService{repo: repo, media: media, st: st, maxSize: maxSize, expiry: expiry}
+}
+
+func newChecksum(algo string) hash.Hash {
+	switch algo {
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
+
+// chunkReader counts and hashes a PATCH body, failing once it goes past max.
+type chunkReader struct {
+	r   io.Reader
+	h   hash.Hash
+	n   int64
+	max int64
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.h != nil {
+		c.h.Write(p[:n])
+	}
+	if c.n > c.max {
+		return n, ErrChunkTooLarge
+	}
+	return n, err
+}
+
+// partsReader streams the stored parts back as one file, opening each part
+// only when the previous one is exhausted.
+type partsReader struct {
+	ctx   context.Context
+	st    storage.Storage
+	parts []models.ResumableUploadPart
+	cur   io.ReadCloser
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := p.st.Open(p.ctx, p.parts[0].StorageKey)
+			if err != nil {
+				return 0, fmt.Errorf("open part: %w", err)
+			}
+			p.cur, p.parts = rc, p.parts[1:]
+		}
+
+		n, err := p.cur.Read(b)
+		if err == io.EOF {
+			p.cur.Close()
+			p.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.cur != nil {
+		return p.cur.Close()
+	}
+	return nil
+}
+
+func (s *resumableUploadService) deleteObjects(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		if err := s.st.Delete(ctx, k); err != nil {
+			log.Printf("delete upload part %s: %v", k, err)
+		}
+	}
+}
+
+// MaxSize implements ResumableUploadService.
+func (s *resumableUploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create implements ResumableUploadService.
+func (s *resumableUploadService) Create(ctx context.Context, userID int64, length int64, filename string, mimeType string, metadata string, altText string) (models.ResumableUpload, error) {
+	if length <= 0 {
+		return models.ResumableUpload{}, ErrInvalidSize
+	}
+	if length > s.maxSize {
+		return models.ResumableUpload{}, ErrUploadTooLarge
+	}
+	kind, err := s.media.CheckType(mimeType)
+	if err != nil {
+		return models.ResumableUpload{}, err
+	}
+	if limit := s.media.MaxSize(kind); length > limit {
+		return models.ResumableUpload{}, &FileTooLargeError{Kind: kind, MaxBytes: limit}
+	}
+	if altText, err = s.media.CheckAltText(kind, altText); err != nil {
+		return models.ResumableUpload{}, err
+	}
+	if err := s.media.CheckQuota(ctx, userID, length); err != nil {
+		return models.ResumableUpload{}, err
+	}
+
+	return s.repo.Create(ctx, repositories.CreateResumableUploadParams{
+		ID:        ulid.Make().String(),
+		OwnerID:   userID,
+		Filename:  filename,
+		MimeType:  mimeType,
+		Length:    length,
+		Metadata:  metadata,
+		AltText:   altText,
+		ExpiresAt: time.Now().Add(s.expiry),
+	})
+}
+
+// Get implements ResumableUploadService.
+func (s *resumableUploadService) Get(ctx context.Context, userID int64, id string) (models.ResumableUpload, error) {
+	up, err := s.repo.Get(ctx, userID, id)
+	if err != nil {
+		return models.ResumableUpload{}, err
+	}
+	if up.MediaID == nil && time.Now().After(up.ExpiresAt) {
+		return models.ResumableUpload{}, ErrUploadExpired
+	}
+	return up, nil
+}
+
+// WriteChunk implements ResumableUploadService.
+func (s *resumableUploadService) WriteChunk(ctx context.Context, userID int64, id string, offset int64, r io.Reader, algo string, sum []byte) (models.ResumableUpload, error) {
+	var h hash.Hash
+	if algo != "" {
+		if h = newChecksum(algo); h == nil {
+			return models.ResumableUpload{}, ErrUnsupportedChecksum
+		}
+	}
+
+	up, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return models.ResumableUpload{}, err
+	}
+	if offset != up.Offset {
+		return models.ResumableUpload{}, ErrOffsetMismatch
+	}
+	// A retry after a failed hand-off only needs the final step again.
+	if up.Complete() {
+		if up.MediaID != nil {
+			return up, nil
+		}
+		return s.finish(ctx, up)
+	}
+
+	key := storage.BuildResumablePartKey(up.ID, offset)
+	cr := &chunkReader{r: r, h: h, max: up.Length - up.Offset}
+	saveErr := s.st.Save(ctx, key, cr, -1, "application/octet-stream")
+
+	// The client may be gone by now; what was received is still recorded.
+	ctx = context.WithoutCancel(ctx)
+
+	written := cr.n
+	switch {
+	case errors.Is(saveErr, ErrChunkTooLarge):
+		s.deleteObjects(ctx, key)
+		return models.ResumableUpload{}, ErrChunkTooLarge
+	case saveErr != nil && h != nil:
+		// A partial chunk cannot be checked against the client's digest.
+		s.deleteObjects(ctx, key)
+		return models.ResumableUpload{}, fmt.Errorf("storage save: %w", saveErr)
+	case saveErr != nil:
+		// Keep whatever reached storage so the client resumes from there
+		// instead of starting over.
+		info, err := s.st.Stat(ctx, key)
+		if err != nil || info.Size > written {
+			s.deleteObjects(ctx, key)
+			return models.ResumableUpload{}, fmt.Errorf("storage save: %w", saveErr)
+		}
+		written = info.Size
+	case h != nil && !bytes.Equal(h.Sum(nil), sum):
+		s.deleteObjects(ctx, key)
+		return models.ResumableUpload{}, ErrChecksumMismatch
+	}
+
+	if written == 0 {
+		s.deleteObjects(ctx, key)
+		if saveErr != nil {
+			return models.ResumableUpload{}, fmt.Errorf("storage save: %w", saveErr)
+		}
+		return up, nil
+	}
+
+	if err := s.repo.AppendPart(ctx, up.ID, offset, key, written); err != nil {
+		s.deleteObjects(ctx, key)
+		if errors.Is(err, repositories.ErrOffsetConflict) {
+			return models.ResumableUpload{}, ErrOffsetMismatch
+		}
+		return models.ResumableUpload{}, err
+	}
+	up.Offset += written
+
+	if saveErr != nil {
+		return up, fmt.Errorf("storage save: %w", saveErr)
+	}
+	if up.Complete() {
+		return s.finish(ctx, up)
+	}
+	return up, nil
+}
+
+// finishClaimTTL is how long a claim to finish an upload holds before a
+// retry may take over from a request that died midway.
+const finishClaimTTL = 15 * time.Minute
+
+// finish stitches the parts into one file and runs it through the regular
+// media pipeline. The upload is claimed first, so only one of several
+// requests racing to finish it creates the media.
+func (s *resumableUploadService) finish(ctx context.Context, up models.ResumableUpload) (_ models.ResumableUpload, err error) {
+	if err := s.repo.Claim(ctx, up.ID, time.Now().Add(-finishClaimTTL)); err != nil {
+		return models.ResumableUpload{}, err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Let the client retry the final step.
+		if rerr := s.repo.Release(context.WithoutCancel(ctx), up.ID); rerr != nil {
+			log.Printf("release upload %s: %v", up.ID, rerr)
+		}
+	}()
+
+	parts, err := s.repo.Parts(ctx, up.ID)
+	if err != nil {
+		return models.ResumableUpload{}, err
+	}
+
+	pr := &partsReader{ctx: ctx, st: s.st, parts: parts}
+	defer pr.Close()
+
+	pub, err := s.media.Save(ctx, up.OwnerID, up.Filename, pr, up.Length, up.MimeType, up.AltText)
+	if err != nil {
+		return models.ResumableUpload{}, fmt.Errorf("media save: %w", err)
+	}
+
+	if err := s.repo.Complete(ctx, up.ID, pub.ID); err != nil {
+		return models.ResumableUpload{}, err
+	}
+	up.MediaID = &pub.ID
+
+	keys := make([]string, 0, len(parts))
+	for _, p := range parts {
+		keys = append(keys, p.StorageKey)
+	}
+	s.deleteObjects(ctx, keys...)
+
+	return up, nil
+}
+
+// Terminate implements ResumableUploadService.
+func (s *resumableUploadService) Terminate(ctx context.Context, userID int64, id string) error {
+	up, err := s.repo.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	parts, err := s.repo.Parts(ctx, up.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, userID, up.ID); err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		s.deleteObjects(ctx, p.StorageKey)
+	}
+	return nil
+}
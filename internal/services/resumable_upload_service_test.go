@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"go-rest-chi/internal/storage"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResumableRepo holds one upload and the parts appended to it.
+type fakeResumableRepo struct {
+	repositories.ResumableUploadRepository
+	up    models.ResumableUpload
+	parts []models.ResumableUploadPart
+	// conflict makes AppendPart lose a race with another write.
+	conflict bool
+}
+
+func (f *fakeResumableRepo) Get(_ context.Context, ownerID int64, id string) (models.ResumableUpload, error) {
+	if ownerID != f.up.OwnerID || id != f.up.ID {
+		return models.ResumableUpload{}, repositories.ErrResumableUploadNotFound
+	}
+	return f.up, nil
+}
+
+func (f *fakeResumableRepo) AppendPart(_ context.Context, id string, offset int64, key string, size int64) error {
+	if f.conflict || offset != f.up.Offset {
+		return repositories.ErrOffsetConflict
+	}
+	f.parts = append(f.parts, models.ResumableUploadPart{Offset: offset, StorageKey: key, Size: size})
+	f.up.Offset += size
+	return nil
+}
+
+// countObjects counts the files stored under dir.
+func countObjects(t *testing.T, dir string) int {
+	t.Helper()
+
+	n := 0
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestResumableWriteChunk(t *testing.T) {
+	good := sha256.Sum256([]byte("hello"))
+
+	tests := []struct {
+		name       string
+		offset     int64
+		conflict   bool
+		algo       string
+		sum        []byte
+		wantErr    error
+		wantOffset int64
+		wantParts  int
+	}{
+		{"first chunk", 0, false, "", nil, nil, 5, 1},
+		{"checksum matches", 0, false, "sha256", good[:], nil, 5, 1},
+		{"stale offset", 3, false, "", nil, ErrOffsetMismatch, 0, 0},
+		{"offset taken by a racing write", 0, true, "", nil, ErrOffsetMismatch, 0, 0},
+		{"checksum mismatch", 0, false, "sha256", []byte("not the digest"), ErrChecksumMismatch, 0, 0},
+		{"unsupported algorithm", 0, false, "crc32", nil, ErrUnsupportedChecksum, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			repo := &fakeResumableRepo{
+				up: models.ResumableUpload{
+					ID:        "01K5A3Z6W7X8Y9Z0ABCDEFGHJK",
+					OwnerID:   1,
+					Length:    10,
+					ExpiresAt: time.Now().Add(time.Hour),
+				},
+				conflict: tt.conflict,
+			}
+			svc := NewResumableUploadService(repo, nil, storage.NewLocalFS(storage.LocalOptions{Dir: dir}), 10, time.Hour)
+
+			up, err := svc.WriteChunk(context.Background(), 1, repo.up.ID, tt.offset, strings.NewReader("hello"), tt.algo, tt.sum)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteChunk() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && up.Offset != tt.wantOffset {
+				t.Errorf("returned offset = %d, want %d", up.Offset, tt.wantOffset)
+			}
+			if repo.up.Offset != tt.wantOffset {
+				t.Errorf("stored offset = %d, want %d", repo.up.Offset, tt.wantOffset)
+			}
+			if len(repo.parts) != tt.wantParts {
+				t.Errorf("recorded %d parts, want %d", len(repo.parts), tt.wantParts)
+			}
+			// A rejected chunk leaves nothing behind in storage.
+			if n := countObjects(t, dir); n != tt.wantParts {
+				t.Errorf("stored %d objects, want %d", n, tt.wantParts)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-chi/internal/helpers"
+	"go-rest-chi/internal/models"
+	"go-rest-chi/internal/repositories"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxSearchQueryLength = 200
+
+var (
+	ErrEmptyQuery   = errors.New("search query is required")
+	ErrQueryTooLong = fmt.Errorf("search query is limited to %d characters", maxSearchQueryLength)
+)
+
+type SearchService interface {
+	// SearchPosts returns ranked posts with highlighted snippets and the
+	// cursor of the next page, empty when there is none.
+	SearchPosts(ctx context.Context, viewerID int64, query, cursor string, limit int32) ([]models.PostSearchResult, string, error)
+	SearchUsers(ctx context.Context, viewerID int64, query, cursor string, limit int32) ([]models.UserSearchResult, string, error)
+}
+
+type searchService struct {
+	repo repositories.SearchRepository
+}
+
+func NewSearchService(repo repositories.SearchRepository) SearchService {
+	return &searchService{repo: repo}
+}
+
+func prepareSearch(query, cursor string) (string, *repositories.SearchCursor, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", nil, ErrEmptyQuery
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return "", nil, ErrQueryTooLong
+	}
+
+	if cursor == "" {
+		return query, nil, nil
+	}
+	rank, id, err := helpers.DecodeRankCursor(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, &repositories.SearchCursor{Rank: rank, ID: id}, nil
+}
+
+// SearchPosts implements SearchService.
+func (s *searchService) SearchPosts(ctx context.Context, viewerID int64, query string, cursor string, limit int32) ([]models.PostSearchResult, string, error) {
+	query, after, err := prepareSearch(query, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, err := s.repo.Posts(ctx, viewerID, query, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(items) == int(limit) && len(items) > 0 {
+		last := items[len(items)-1]
+		next = helpers.EncodeRankCursor(last.Rank, last.ID)
+	}
+	return items, next, nil
+}
+
+// SearchUsers implements SearchService.
+func (s *searchService) SearchUsers(ctx context.Context, viewerID int64, query string, cursor string, limit int32) ([]models.UserSearchResult, string, error) {
+	query, after, err := prepareSearch(query, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, err := s.repo.Users(ctx, viewerID, query, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(items) == int(limit) && len(items) > 0 {
+		last := items[len(items)-1]
+		next = helpers.EncodeRankCursor(last.Rank, last.ID)
+	}
+	return items, next, nil
+}
@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go-rest-chi/internal/models"
 	"go-rest-chi/internal/repositories"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -14,11 +16,16 @@ var (
 	ErrInvalidEmail    = errors.New("invalid email")
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrBadCredentials  = errors.New("invalid credentials")
+	ErrDisplayNameLong = fmt.Errorf("display name is limited to %d characters", maxDisplayNameLength)
 )
 
+const maxDisplayNameLength = 100
+
 type UserService interface {
 	Register(ctx context.Context, email string, username string, password string) (models.UserPublic, error)
 	Login(ctx context.Context, identifier, password string) (models.UserPublic, error)
+	// UpdateProfile sets the display name; an empty name clears it.
+	UpdateProfile(ctx context.Context, userID int64, displayName string) (models.UserPublic, error)
 }
 
 type userService struct {
@@ -74,3 +81,22 @@ func (u *userService) Login(ctx context.Context, identifier, password string) (m
 
 	return usr.Public(), nil
 }
+
+// UpdateProfile implements UserService.
+func (u *userService) UpdateProfile(ctx context.Context, userID int64, displayName string) (models.UserPublic, error) {
+	displayName = strings.TrimSpace(displayName)
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return models.UserPublic{}, ErrDisplayNameLong
+	}
+
+	var dn *string
+	if displayName != "" {
+		dn = &displayName
+	}
+
+	usr, err := u.repo.UpdateDisplayName(ctx, userID, dn)
+	if err != nil {
+		return models.UserPublic{}, err
+	}
+	return usr.Public(), nil
+}
@@ -2,17 +2,64 @@ package storage
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 )
 
-func BuildPostKey(userID int64, filename string, now time.Time) string {
-	ext := strings.ToLower(filename)
-	if ext == "" {
-		ext = ".bin"
+// KeyPrefixes lists the prefixes of every key the app stores objects under.
+var KeyPrefixes = []string{"posts/", "staging/", "tus/"}
+
+// IsImmutable reports whether the object under key never changes once
+// served. Only upload originals qualify: each is written once under a fresh
+// key. Variants and posters are derived again when media is reprocessed and
+// may come out different, so their keys are excluded.
+func IsImmutable(key string) bool {
+	if !strings.HasPrefix(key, "posts/") {
+		return false
+	}
+	base := path.Base(key)
+	_, err := ulid.ParseStrict(strings.TrimSuffix(base, path.Ext(base)))
+	return err == nil
+}
+
+func keyExt(filename string) string {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		return ext
 	}
+	return ".bin"
+}
+
+func BuildPostKey(userID int64, filename string, now time.Time) string {
 	id := ulid.Make().String()
-	return fmt.Sprintf("posts/%04d/%02d/%d/%s%s", now.Year(), int(now.Month()), userID, id, ext)
+	return fmt.Sprintf("posts/%04d/%02d/%d/%s%s", now.Year(), int(now.Month()), userID, id, keyExt(filename))
+}
+
+// BuildStagingKey names the object a direct upload is PUT to. Once verified
+// it is copied to a fresh post key, so no client ever holds a URL that
+// writes to media being served.
+func BuildStagingKey(userID int64, filename string) string {
+	return fmt.Sprintf("staging/%d/%s%s", userID, ulid.Make().String(), keyExt(filename))
+}
+
+// BuildResumablePartKey names one stored chunk of a resumable upload. The
+// random suffix keeps racing writes at the same offset from clobbering each
+// other.
+func BuildResumablePartKey(uploadID string, offset int64) string {
+	return fmt.Sprintf("tus/%s/%020d-%s", uploadID, offset, ulid.Make().String())
+}
+
+// BuildVariantKey derives the key of a resized rendition from its original,
+// so variants sit next to it: posts/.../ID.jpg -> posts/.../ID_480w.jpg.
+func BuildVariantKey(key string, width int, ext string) string {
+	return fmt.Sprintf("%s_%dw%s", strings.TrimSuffix(key, filepath.Ext(key)), width, ext)
+}
+
+// BuildPosterKey derives the key of a video's poster frame from the video's
+// key: posts/.../ID.mp4 -> posts/.../ID_poster.jpg.
+func BuildPosterKey(key string) string {
+	return strings.TrimSuffix(key, filepath.Ext(key)) + "_poster.jpg"
 }
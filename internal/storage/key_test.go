@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsImmutable(t *testing.T) {
+	original := BuildPostKey(1, "photo.JPG", time.Now())
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{original, true},
+		{BuildVariantKey(original, 480, ".webp"), false},
+		{BuildPosterKey(original), false},
+		{BuildStagingKey(1, "photo.jpg"), false},
+		{BuildResumablePartKey("01K5A3Z6W7X8Y9Z0ABCDEFGHJK", 0), false},
+		{"posts/2025/09/1/photo.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := IsImmutable(tt.key); got != tt.want {
+			t.Errorf("IsImmutable(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -2,62 +2,252 @@ package storage
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/oklog/ulid/v2"
 )
 
+var ErrBadSignature = errors.New("invalid or expired signature")
+
+type LocalOptions struct {
+	Dir string
+	// PublicBaseURL is where the app serving /media/* and /uploads/* is
+	// reached.
+	PublicBaseURL string
+	// SigningSecret signs upload URLs, and download URLs unless Public.
+	SigningSecret string
+	// Public serves every object to anyone at a permanent URL. Otherwise
+	// objects are only served through signed URLs that expire.
+	Public     bool
+	PresignTTL time.Duration
+}
+
 type LocalFS struct {
 	baseDir    string
 	publicBase string
+	secret     []byte
+	public     bool
+	presignTTL time.Duration
 }
 
-func NewLocalFS(baseDir, publicBase string) *LocalFS {
+func NewLocalFS(opts LocalOptions) *LocalFS {
 	return &LocalFS{
-		baseDir:    filepath.Clean(baseDir),
-		publicBase: strings.TrimRight(publicBase, "/"),
+		baseDir:    filepath.Clean(opts.Dir),
+		publicBase: strings.TrimRight(opts.PublicBaseURL, "/"),
+		secret:     []byte(opts.SigningSecret),
+		public:     opts.Public,
+		presignTTL: opts.PresignTTL,
 	}
 }
 
+// Public reports whether objects are served without signatures.
+func (l *LocalFS) Public() bool {
+	return l.public
+}
+
 func (l *LocalFS) FullPath(key string) string {
 	clean := filepath.Clean(key)
 	return filepath.Join(l.baseDir, clean)
 }
 
+// root opens the storage directory. Objects are only ever reached through
+// it, so no key, symlink or ".." can lead out of the directory.
+func (l *LocalFS) root() (*os.Root, error) {
+	return os.OpenRoot(l.baseDir)
+}
+
+// Save writes the object to a temporary file next to it and renames it into
+// place, so a reader opening key sees either the old bytes or all of the
+// new ones, never a partial write.
 func (l *LocalFS) Save(ctx context.Context, key string, r io.Reader, _ int64, _ string) error {
-	path := l.FullPath(key)
+	if err := os.MkdirAll(l.baseDir, 0o755); err != nil {
+		return err
+	}
+	root, err := l.root()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
 
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+	name := filepath.FromSlash(key)
+	if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	tmp := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp-"+ulid.Make().String())
+	f, err := root.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
-
 	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = root.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = root.Remove(tmp)
+	}
 	return err
 }
 
+// OpenFile opens the object under key for serving.
+func (l *LocalFS) OpenFile(key string) (*os.File, error) {
+	root, err := l.root()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.Open(filepath.FromSlash(key))
+}
+
 func (l *LocalFS) Open(ctx context.Context, key string) (io.ReadCloser, error) {
-	return os.Open(l.FullPath(key))
+	return l.OpenFile(key)
+}
+
+func (l *LocalFS) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	root, err := l.root()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer root.Close()
+
+	fi, err := root.Stat(filepath.FromSlash(key))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: key, Size: fi.Size(), ModTime: fi.ModTime()}, nil
 }
 
+// URL is the permanent URL of public objects and a URL signed for the
+// default TTL otherwise.
 func (l *LocalFS) URL(ctx context.Context, key string) (string, error) {
-	u := fmt.Sprintf("%s/media/%s", l.publicBase, strings.ReplaceAll(key, "\\", "/"))
-	return u, nil
+	if !l.public {
+		return l.PresignGet(ctx, key, l.presignTTL)
+	}
+	return fmt.Sprintf("%s/media/%s", l.publicBase, strings.ReplaceAll(key, "\\", "/")), nil
+}
+
+// PresignGet returns a URL for the static media route, signed over the key
+// and expiry. Public objects need no signature and get their permanent URL.
+func (l *LocalFS) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if l.public {
+		return fmt.Sprintf("%s/media/%s", l.publicBase, key), nil
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sig", l.sign("GET", key, expires))
+	return fmt.Sprintf("%s/media/%s?%s", l.publicBase, key, q.Encode()), nil
 }
 
-func (l *LocalFS) PresignGet(ctx context.Context, key string, _ time.Duration) (string, error) {
-	return l.URL(ctx, key)
+// VerifyGet checks a request made to a URL from PresignGet.
+func (l *LocalFS) VerifyGet(key, expires, sig string) error {
+	if l.public {
+		return nil
+	}
+	return l.verify(expires, sig, "GET", key, expires)
+}
+
+// PresignPut returns a URL for the local upload endpoint, signed over the
+// key, content type and expiry.
+func (l *LocalFS) PresignPut(ctx context.Context, key string, mime string, ttl time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sig", l.sign("PUT", key, mime, expires))
+	return fmt.Sprintf("%s/uploads/%s?%s", l.publicBase, strings.ReplaceAll(key, "\\", "/"), q.Encode()), nil
+}
+
+// VerifyPut checks a request made to a URL from PresignPut.
+func (l *LocalFS) VerifyPut(key, mime, expires, sig string) error {
+	return l.verify(expires, sig, "PUT", key, mime, expires)
+}
+
+// verify checks sig against the signature of parts and that expires, in
+// Unix seconds, has not passed.
+func (l *LocalFS) verify(expires, sig string, parts ...string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrBadSignature
+	}
+	want := l.sign(parts...)
+	if !hmac.Equal([]byte(want), []byte(sig)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func (l *LocalFS) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 func (l *LocalFS) Delete(ctx context.Context, key string) error {
-	return os.Remove(l.FullPath(key))
+	root, err := l.root()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.Remove(filepath.FromSlash(key))
+}
+
+// List walks the directory holding prefix; keys use forward slashes on every
+// platform.
+func (l *LocalFS) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	dir := l.baseDir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = l.FullPath(prefix[:i])
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Nothing stored under prefix yet, or deleted while walking.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.baseDir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: key, Size: fi.Size(), ModTime: fi.ModTime()})
+	})
 }
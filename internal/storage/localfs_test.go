@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalFS(t *testing.T, public bool) *LocalFS {
+	t.Helper()
+
+	return NewLocalFS(LocalOptions{
+		Dir:           t.TempDir(),
+		PublicBaseURL: "http://media.test",
+		SigningSecret: "secret",
+		Public:        public,
+		PresignTTL:    time.Minute,
+	})
+}
+
+// signedQuery returns the expires and sig parameters of a presigned URL.
+func signedQuery(t *testing.T, rawURL string) (string, string) {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("expires"), u.Query().Get("sig")
+}
+
+func TestLocalFSSave(t *testing.T) {
+	l := newTestLocalFS(t, false)
+	ctx := context.Background()
+
+	for _, body := range []string{"first", "second"} {
+		if err := l.Save(ctx, "posts/2025/09/1/a.txt", strings.NewReader(body), -1, "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := os.ReadFile(filepath.Join(l.baseDir, "posts", "2025", "09", "1", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "second" {
+		t.Errorf("object = %q, want second", got)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(l.baseDir, "posts", "2025", "09", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want no temporary files left", len(entries))
+	}
+}
+
+func TestLocalFSSaveStaysInDir(t *testing.T) {
+	l := newTestLocalFS(t, false)
+	ctx := context.Background()
+
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(l.baseDir, "link")); err != nil {
+		t.Skipf("cannot create symlink: %v", err)
+	}
+
+	for _, key := range []string{"../escape.txt", "posts/../../escape.txt", "link/escape.txt"} {
+		t.Run(key, func(t *testing.T) {
+			if err := l.Save(ctx, key, strings.NewReader("x"), -1, "text/plain"); err == nil {
+				t.Error("Save outside the directory succeeded")
+			}
+		})
+	}
+
+	for _, dir := range []string{outside, filepath.Dir(l.baseDir)} {
+		if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("escape.txt written to %s", dir)
+		}
+	}
+}
+
+func TestLocalFSOpenStaysInDir(t *testing.T) {
+	l := newTestLocalFS(t, false)
+
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(l.baseDir, "link")); err != nil {
+		t.Skipf("cannot create symlink: %v", err)
+	}
+
+	if f, err := l.OpenFile("link/secret.txt"); err == nil {
+		f.Close()
+		t.Error("OpenFile followed a symlink out of the directory")
+	}
+}
+
+func TestLocalFSVerifyPut(t *testing.T) {
+	l := newTestLocalFS(t, false)
+	const key = "staging/1/a.jpg"
+
+	raw, err := l.PresignPut(context.Background(), key, "image/jpeg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, sig := signedQuery(t, raw)
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name               string
+		key, mime, expires string
+		sig                string
+		wantErr            bool
+	}{
+		{"valid", key, "image/jpeg", expires, sig, false},
+		{"other key", "staging/1/b.jpg", "image/jpeg", expires, sig, true},
+		{"other type", key, "text/html", expires, sig, true},
+		{"extended expiry", key, "image/jpeg", expires + "0", sig, true},
+		{"expired", key, "image/jpeg", past, l.sign("PUT", key, "image/jpeg", past), true},
+		{"bad expiry", key, "image/jpeg", "soon", sig, true},
+		{"no signature", key, "image/jpeg", expires, "", true},
+		{"get signature", key, "image/jpeg", expires, l.sign("GET", key, expires), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := l.VerifyPut(tt.key, tt.mime, tt.expires, tt.sig)
+			if tt.wantErr && !errors.Is(err, ErrBadSignature) {
+				t.Errorf("err = %v, want ErrBadSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalFSPresignPutURL(t *testing.T) {
+	l := newTestLocalFS(t, false)
+
+	raw, err := l.PresignPut(context.Background(), "staging/1/a.jpg", "image/jpeg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, "http://media.test/uploads/staging/1/a.jpg?") {
+		t.Errorf("PresignPut = %q", raw)
+	}
+}
+
+func TestLocalFSVerifyGet(t *testing.T) {
+	l := newTestLocalFS(t, false)
+	const key = "posts/2025/09/1/a.jpg"
+
+	raw, err := l.PresignGet(context.Background(), key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, sig := signedQuery(t, raw)
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name         string
+		key, expires string
+		sig          string
+		wantErr      bool
+	}{
+		{"valid", key, expires, sig, false},
+		{"other key", "posts/2025/09/1/b.jpg", expires, sig, true},
+		{"extended expiry", key, expires + "0", sig, true},
+		{"tampered signature", key, expires, strings.Repeat("0", len(sig)), true},
+		{"expired", key, past, l.sign("GET", key, past), true},
+		{"no signature", key, expires, "", true},
+		{"put signature", key, expires, l.sign("PUT", key, "image/jpeg", expires), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := l.VerifyGet(tt.key, tt.expires, tt.sig)
+			if tt.wantErr && !errors.Is(err, ErrBadSignature) {
+				t.Errorf("err = %v, want ErrBadSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalFSVerifyGetPublic(t *testing.T) {
+	l := newTestLocalFS(t, true)
+
+	raw, err := l.PresignGet(context.Background(), "posts/2025/09/1/a.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != "http://media.test/media/posts/2025/09/1/a.jpg" {
+		t.Errorf("PresignGet = %q, want the permanent URL", raw)
+	}
+	if err := l.VerifyGet("posts/2025/09/1/a.jpg", "", ""); err != nil {
+		t.Errorf("VerifyGet without a signature = %v", err)
+	}
+}
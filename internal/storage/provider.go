@@ -8,9 +8,25 @@ import (
 func NewFromConfig(cfg config.StorageConfig) (Storage, error) {
 	switch cfg.Driver {
 	case "local":
-		return NewLocalFS(cfg.LocalDir, cfg.PublicBaseURL), nil
+		return NewLocalFS(LocalOptions{
+			Dir:           cfg.LocalDir,
+			PublicBaseURL: cfg.PublicBaseURL,
+			SigningSecret: cfg.SigningSecret,
+			Public:        cfg.LocalPublic,
+			PresignTTL:    cfg.PresignTTL,
+		}), nil
 	case "s3":
-		return nil, fmt.Errorf("s3 driver not implemented yet")
+		return NewS3(S3Options{
+			Endpoint:      cfg.S3Endpoint,
+			Region:        cfg.S3Region,
+			Bucket:        cfg.S3Bucket,
+			AccessKey:     cfg.S3AccessKey,
+			SecretKey:     cfg.S3SecretKey,
+			UsePath:       cfg.S3UsePath,
+			PublicBaseURL: cfg.S3PublicBaseURL,
+			PresignTTL:    cfg.PresignTTL,
+			PartSize:      uint64(cfg.S3PartSizeMB) << 20,
+		})
 	default:
 		return nil, fmt.Errorf("unknown storage driver %v", cfg.Driver)
 	}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// defaultS3PartSize is the multipart chunk used for streamed uploads. Objects
+// up to this size go up in a single PUT.
+const defaultS3PartSize = 16 << 20
+
+type S3Options struct {
+	// Endpoint is host[:port] or a full URL; the scheme decides TLS. Empty
+	// means AWS S3.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UsePath   bool
+	// PublicBaseURL, when set, serves objects from a public bucket or CDN
+	// instead of presigned URLs.
+	PublicBaseURL string
+	PresignTTL    time.Duration
+	PartSize      uint64
+}
+
+type S3 struct {
+	client     *minio.Client
+	bucket     string
+	publicBase string
+	presignTTL time.Duration
+	partSize   uint64
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+
+	host, secure, err := parseS3Endpoint(opts.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var creds *credentials.Credentials
+	if opts.AccessKey != "" {
+		creds = credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, "")
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		})
+	}
+
+	lookup := minio.BucketLookupDNS
+	if opts.UsePath {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(host, &minio.Options{
+		Creds:        creds,
+		Secure:       secure,
+		Region:       opts.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = defaultS3PartSize
+	}
+
+	return &S3{
+		client:     client,
+		bucket:     opts.Bucket,
+		publicBase: strings.TrimRight(opts.PublicBaseURL, "/"),
+		presignTTL: opts.PresignTTL,
+		partSize:   partSize,
+	}, nil
+}
+
+func parseS3Endpoint(endpoint string) (string, bool, error) {
+	if endpoint == "" {
+		return "s3.amazonaws.com", true, nil
+	}
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, true, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", false, fmt.Errorf("s3: invalid endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "http":
+		return u.Host, false, nil
+	case "https":
+		return u.Host, true, nil
+	default:
+		return "", false, fmt.Errorf("s3: unsupported endpoint scheme %q", u.Scheme)
+	}
+}
+
+// notFound maps missing objects to fs.ErrNotExist so callers can treat every
+// driver alike.
+func notFound(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fmt.Errorf("%w: %v", fs.ErrNotExist, err)
+	}
+	return err
+}
+
+// Save streams r to the bucket. An unknown size (<= 0) is uploaded as a
+// multipart stream, as is anything larger than the part size.
+func (s *S3) Save(ctx context.Context, key string, r io.Reader, size int64, mime string) error {
+	if size <= 0 {
+		size = -1
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: mime,
+		PartSize:    s.partSize,
+	})
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	// GetObject is lazy; Stat surfaces a missing key before the first read.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, notFound(err)
+	}
+	return obj, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, notFound(err)
+	}
+	return ObjectInfo{Key: key, Size: info.Size, ContentType: info.ContentType, ModTime: info.LastModified}, nil
+}
+
+func (s *S3) URL(ctx context.Context, key string) (string, error) {
+	if s.publicBase != "" {
+		return s.publicBase + "/" + key, nil
+	}
+	return s.PresignGet(ctx, key, s.presignTTL)
+}
+
+func (s *S3) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// PresignPut signs the Content-Type header too, so the upload must declare
+// the same type it was requested with.
+func (s *S3) PresignPut(ctx context.Context, key string, mime string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignHeader(ctx, http.MethodPut, s.bucket, key, ttl, nil, http.Header{
+		"Content-Type": []string{mime},
+	})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	// Cancelling stops the listing goroutine when fn bails out early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(ObjectInfo{Key: obj.Key, Size: obj.Size, ContentType: obj.ContentType, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
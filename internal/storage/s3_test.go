@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/oklog/ulid/v2"
+)
+
+// newTestS3 connects to the S3 compatible server named by S3_TEST_ENDPOINT,
+// e.g. http://localhost:9000 for the MinIO of docker-compose.yml, and skips
+// the test when it is unset. Objects go under a prefix of their own, removed
+// when the test ends.
+func newTestS3(t *testing.T) (*S3, string) {
+	t.Helper()
+
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+	env := func(key, def string) string {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		return def
+	}
+
+	s, err := NewS3(S3Options{
+		Endpoint:   endpoint,
+		Region:     env("S3_TEST_REGION", "us-east-1"),
+		Bucket:     env("S3_TEST_BUCKET", "media"),
+		AccessKey:  env("S3_TEST_ACCESS_KEY", "minioadmin"),
+		SecretKey:  env("S3_TEST_SECRET_KEY", "minioadmin"),
+		UsePath:    true,
+		PresignTTL: time.Minute,
+		PartSize:   5 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		if err := s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prefix := "test/" + ulid.Make().String() + "/"
+	t.Cleanup(func() {
+		_ = s.List(context.Background(), prefix, func(obj ObjectInfo) error {
+			return s.Delete(context.Background(), obj.Key)
+		})
+	})
+	return s, prefix
+}
+
+func readObject(t *testing.T, s *S3, key string) []byte {
+	t.Helper()
+
+	rc, err := s.Open(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestS3SaveOpenStat(t *testing.T) {
+	s, prefix := newTestS3(t)
+	ctx := context.Background()
+
+	body := []byte("hello, object")
+	key := prefix + "known.txt"
+	if err := s.Save(ctx, key, bytes.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, s, key); !bytes.Equal(got, body) {
+		t.Errorf("Open = %q, want %q", got, body)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != key || info.Size != int64(len(body)) || info.ContentType != "text/plain" {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	// Streams of unknown length go up as multipart uploads.
+	big := bytes.Repeat([]byte("0123456789abcdef"), (6<<20)/16)
+	key = prefix + "streamed.bin"
+	if err := s.Save(ctx, key, bytes.NewReader(big), -1, "application/octet-stream"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, s, key); !bytes.Equal(got, big) {
+		t.Errorf("streamed object differs: %d bytes, want %d", len(got), len(big))
+	}
+}
+
+func TestS3Missing(t *testing.T) {
+	s, prefix := newTestS3(t)
+	ctx := context.Background()
+
+	if _, err := s.Stat(ctx, prefix+"missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat: err = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := s.Open(ctx, prefix+"missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open: err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestS3PresignPut(t *testing.T) {
+	s, prefix := newTestS3(t)
+	ctx := context.Background()
+
+	key := prefix + "direct.png"
+	u, err := s.PresignPut(ctx, key, "image/png", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(contentType string) int {
+		req, err := http.NewRequest(http.MethodPut, u, strings.NewReader("png bytes"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := put("text/html"); code != http.StatusForbidden {
+		t.Errorf("PUT with another type: status = %d, want 403", code)
+	}
+	if code := put("image/png"); code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, want 200", code)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "image/png" || info.Size != int64(len("png bytes")) {
+		t.Errorf("Stat = %+v", info)
+	}
+}
+
+func TestS3List(t *testing.T) {
+	s, prefix := newTestS3(t)
+	ctx := context.Background()
+
+	want := []string{prefix + "a/1", prefix + "a/2", prefix + "b/3"}
+	for _, key := range want {
+		if err := s.Save(ctx, key, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	if err := s.List(ctx, prefix, func(obj ObjectInfo) error {
+		got = append(got, obj.Key)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+
+	// Returning an error stops the listing and is passed through.
+	stop := errors.New("stop")
+	calls := 0
+	err := s.List(ctx, prefix, func(ObjectInfo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("List: err = %v after %d calls, want stop after 1", err, calls)
+	}
+}
@@ -6,10 +6,26 @@ import (
 	"time"
 )
 
+// ObjectInfo describes a stored object. ContentType is empty when the driver
+// does not record it.
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
 type Storage interface {
 	Save(ctx context.Context, key string, r io.Reader, size int64, mime string) error
 	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
 	URL(ctx context.Context, key string) (string, error)
 	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// PresignPut returns a URL accepting a single PUT of the object body with
+	// the given Content-Type header until ttl elapses.
+	PresignPut(ctx context.Context, key string, mime string, ttl time.Duration) (string, error)
 	Delete(ctx context.Context, key string) error
+	// List calls fn for every object whose key starts with prefix, in no
+	// particular order, and stops at the first error fn returns.
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
 }